@@ -1,10 +1,20 @@
 package cmd
 
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/kubeslice/kubeslice-cli/util"
+	"github.com/spf13/cobra"
+)
+
 var (
 	profile      string
+	profilesDir  string
 	skipSteps    = []string{}
 	outputFormat string
-	Config       string
+	Config       []string
 )
 
 func mapFromSlice(slice []string) map[string]string {
@@ -14,3 +24,39 @@ func mapFromSlice(slice []string) map[string]string {
 	}
 	return resultantMap
 }
+
+// exitOnError prints err, if any, and exits with the exit code of its class,
+// see util.ExitCode.
+func exitOnError(err error) {
+	if err == nil {
+		return
+	}
+	util.Printf("\n %v %v", util.Cross, err)
+	util.PrintProgressSummary(err)
+	os.Exit(util.ExitCode(err))
+}
+
+// controllerCommands are the commands --kubeconfig and --context select the
+// controller of, the others read their clusters from the topology.
+var controllerCommands = []string{"get", "create", "delete", "edit", "describe", "register"}
+
+// checkControllerFlags refuses --kubeconfig and --context on the commands
+// which would ignore them. config init has a --kubeconfig of its own, which
+// does not set kubeconfig.
+func checkControllerFlags(cmd *cobra.Command) error {
+	if kubeconfig == "" && kubeContext == "" {
+		return nil
+	}
+	for _, name := range controllerCommands {
+		if cmd.HasParent() && !cmd.Parent().HasParent() && cmd.Name() == name {
+			return nil
+		}
+	}
+	return util.ValidationErrorf("--kubeconfig and --context are only supported by the %s commands, %s reads the clusters from --config", strings.Join(controllerCommands, ", "), cmd.CommandPath())
+}
+
+// runStep runs the resource command cmd on objectType as a step of the
+// progress, e.g. "get project", and exits on its error.
+func runStep(cmd *cobra.Command, objectType string, f func(ctx context.Context) error) {
+	exitOnError(util.RunStep(cmd.Context(), cmd.Name()+" "+objectType, f))
+}
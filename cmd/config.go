@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kubeslice/kubeslice-cli/pkg"
+	"github.com/kubeslice/kubeslice-cli/util"
+	"github.com/spf13/cobra"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Work with topology configuration files.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validates a topology configuration file.",
+	Long: `Validates a topology configuration file against the topology schema without
+	contacting any cluster. Unknown or misspelled keys, values of the wrong type and
+	inconsistent settings are reported with their file, line and YAML path.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(Config) == 0 {
+			cmd.Help()
+			exitOnError(util.ValidationErrorf("Please pass the --config option"))
+		}
+		errors := pkg.ValidateConfigurationFiles(Config)
+		if len(errors) > 0 {
+			for _, e := range errors {
+				util.Printf("%s %s", util.Cross, e)
+			}
+			exitOnError(util.ValidationErrorf("%s is not a valid topology configuration, found %d error(s)", strings.Join(Config, ", "), len(errors)))
+		}
+		util.Printf("%s %s is a valid topology configuration", util.Tick, strings.Join(Config, ", "))
+	},
+}
+
+var configRenderCmd = &cobra.Command{
+	Use:   "render",
+	Short: "Prints the effective topology configuration.",
+	Long: `Prints the effective topology configuration after merging every file passed
+	with --config, and the files they name under base:, in order. Lists of named
+	entries such as workers are merged by name. Secret references are printed as written.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(Config) == 0 {
+			cmd.Help()
+			exitOnError(util.ValidationErrorf("Please pass the --config option"))
+		}
+		rendered, errors := pkg.RenderConfiguration(Config)
+		if len(errors) > 0 {
+			for _, e := range errors {
+				util.Printf("%s %s", util.Cross, e)
+			}
+			exitOnError(util.ValidationErrorf("Failed to render topology configuration"))
+		}
+		fmt.Print(string(rendered))
+	},
+}
+
+var configMigrateOutput string
+
+var configMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Migrates topology configuration files to the current version.",
+	Long: `Rewrites topology configuration files written in an older version of the
+	topology format to the current one, keeping comments where possible. Files are
+	rewritten in place unless --output is passed.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(Config) == 0 {
+			cmd.Help()
+			exitOnError(util.ValidationErrorf("Please pass the --config option"))
+		}
+		exitOnError(pkg.MigrateConfiguration(Config, configMigrateOutput))
+	},
+}
+
+var configInitOptions = pkg.ConfigInitParams{}
+
+var configInitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Generates a topology configuration file from existing kubeconfig contexts.",
+	Long: `Generates a topology configuration file from the contexts in your kubeconfig.
+	The wizard lets you pick the controller and worker clusters, looks up their
+	control plane address and node IP, and asks for the project name and chart versions.
+	Use --non-interactive with the flags below to generate the same file from scripts.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if configInitOptions.NonInteractive && (configInitOptions.ControllerContext == "" || len(configInitOptions.WorkerContexts) == 0) {
+			cmd.Help()
+			exitOnError(util.ValidationErrorf("Please pass --controller-context and --worker-contexts with --non-interactive"))
+		}
+		exitOnError(pkg.InitConfiguration(cmd.Context(), configInitOptions))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configValidateCmd)
+	configCmd.AddCommand(configInitCmd)
+	configCmd.AddCommand(configRenderCmd)
+	configCmd.AddCommand(configMigrateCmd)
+	configMigrateCmd.Flags().StringVarP(&configMigrateOutput, "output", "o", "", "File to write the migrated topology to, - for stdout. Defaults to rewriting the file in place")
+	configInitCmd.Flags().StringSliceVar(&configInitOptions.KubeConfigPaths, "kubeconfig", nil, "Kubeconfig files to read contexts from. Defaults to $KUBECONFIG or ~/.kube/config")
+	configInitCmd.Flags().StringVar(&configInitOptions.ControllerContext, "controller-context", "", "Context of the controller cluster")
+	configInitCmd.Flags().StringSliceVar(&configInitOptions.WorkerContexts, "worker-contexts", nil, "Contexts of the worker clusters (comma-seperated)")
+	configInitCmd.Flags().StringVar(&configInitOptions.ProjectName, "project", "", "Name of the KubeSlice project (default \"demo\")")
+	configInitCmd.Flags().StringVar(&configInitOptions.RepoUrl, "repo-url", "", "URL of the KubeSlice helm repository (default \"https://kubeslice.github.io/kubeslice/\")")
+	configInitCmd.Flags().StringVar(&configInitOptions.ControllerVersion, "controller-version", "", "Version of the controller chart. Leave blank for latest version")
+	configInitCmd.Flags().StringVar(&configInitOptions.WorkerVersion, "worker-version", "", "Version of the worker chart. Leave blank for latest version")
+	configInitCmd.Flags().StringVarP(&configInitOptions.OutputFile, "output", "o", "", "File to write the topology configuration to (default \"topology.yaml\")")
+	configInitCmd.Flags().BoolVar(&configInitOptions.NonInteractive, "non-interactive", false, "Do not prompt, use flags and defaults only")
+	configInitCmd.Flags().BoolVar(&configInitOptions.SkipNetworkLookup, "skip-network-lookup", false, "Do not contact the clusters to look up control plane addresses and node IPs")
+}
@@ -1,6 +1,8 @@
 package cmd
 
 import (
+	"context"
+
 	"github.com/kubeslice/kubeslice-cli/pkg"
 	"github.com/kubeslice/kubeslice-cli/util"
 	"github.com/spf13/cobra"
@@ -15,24 +17,27 @@ var deleteCmd = &cobra.Command{
 		var objectName string
 		ns, _ := cmd.Flags().GetString("namespace")
 		if ns == "" {
-			util.Fatalf("Namespace is required")
+			exitOnError(util.ValidationErrorf("Namespace is required"))
 		}
 
 		objectName = args[1]
 
-		pkg.SetCliOptions(pkg.CliParams{Config: Config, Namespace: ns, ObjectName: objectName, ObjectType: args[0]})
-		switch args[0] {
-		case "project":
-			pkg.DeleteProject()
-		case "sliceConfig":
-			pkg.DeleteSliceConfig()
-		case "serviceExportConfig":
-			pkg.DeleteServiceExportConfig()
-		case "worker":
-			pkg.RemoveWorker()
-		default:
-			util.Fatalf("Invalid object type")
-		}
+		exitOnError(pkg.SetCliOptions(pkg.CliParams{Config: Config, KubeConfigPath: kubeconfig, ContextName: kubeContext, Namespace: ns, ObjectName: objectName, ObjectType: args[0]}))
+		pkg.PrintControllerContext()
+		runStep(cmd, args[0], func(ctx context.Context) error {
+			switch args[0] {
+			case "project":
+				return pkg.DeleteProject(ctx)
+			case "sliceConfig":
+				return pkg.DeleteSliceConfig(ctx)
+			case "serviceExportConfig":
+				return pkg.DeleteServiceExportConfig(ctx)
+			case "worker":
+				return pkg.RemoveWorker(ctx)
+			default:
+				return util.ValidationErrorf("Invalid object type")
+			}
+		})
 	},
 }
 
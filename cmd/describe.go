@@ -1,6 +1,8 @@
 package cmd
 
 import (
+	"context"
+
 	"github.com/kubeslice/kubeslice-cli/pkg"
 	"github.com/kubeslice/kubeslice-cli/util"
 	"github.com/spf13/cobra"
@@ -15,26 +17,28 @@ var describeCmd = &cobra.Command{
 		var objectName string
 		ns, _ := cmd.Flags().GetString("namespace")
 		if ns == "" {
-			util.Fatalf("Namespace is required")
+			exitOnError(util.ValidationErrorf("Namespace is required"))
 		}
 
 		if len(args) > 1 {
 			objectName = args[1]
 		}
 
-		pkg.SetCliOptions(pkg.CliParams{Config: Config, Namespace: ns, ObjectName: objectName, ObjectType: args[0]})
-		switch args[0] {
-		case "project":
-			pkg.DescribeProject()
-		case "sliceConfig":
-			pkg.DescribeSliceConfig()
-		case "serviceExportConfig":
-			pkg.DescribeServiceExportConfig()
-		case "worker":
-			pkg.DescribeWorker()
-		default:
-			util.Fatalf("Invalid object type")
-		}
+		exitOnError(pkg.SetCliOptions(pkg.CliParams{Config: Config, KubeConfigPath: kubeconfig, ContextName: kubeContext, Namespace: ns, ObjectName: objectName, ObjectType: args[0]}))
+		runStep(cmd, args[0], func(ctx context.Context) error {
+			switch args[0] {
+			case "project":
+				return pkg.DescribeProject(ctx)
+			case "sliceConfig":
+				return pkg.DescribeSliceConfig(ctx)
+			case "serviceExportConfig":
+				return pkg.DescribeServiceExportConfig(ctx)
+			case "worker":
+				return pkg.DescribeWorker(ctx)
+			default:
+				return util.ValidationErrorf("Invalid object type")
+			}
+		})
 	},
 }
 
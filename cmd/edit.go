@@ -1,6 +1,8 @@
 package cmd
 
 import (
+	"context"
+
 	"github.com/kubeslice/kubeslice-cli/pkg"
 	"github.com/kubeslice/kubeslice-cli/util"
 	"github.com/spf13/cobra"
@@ -18,7 +20,7 @@ var editCmd = &cobra.Command{
 		var objectName string
 		ns, _ := cmd.Flags().GetString("namespace")
 		if ns == "" {
-			util.Fatalf("Namespace is required")
+			exitOnError(util.ValidationErrorf("Namespace is required"))
 		}
 		filename, _ := cmd.Flags().GetString("filename")
 
@@ -26,19 +28,22 @@ var editCmd = &cobra.Command{
 			objectName = args[1]
 		}
 
-		pkg.SetCliOptions(pkg.CliParams{Config: Config, Namespace: ns, ObjectName: objectName, ObjectType: args[0], FileName: filename})
-		switch args[0] {
-		case "project":
-			pkg.EditProject()
-		case "sliceConfig":
-			pkg.EditSliceConfig()
-		case "serviceExportConfig":
-			pkg.EditServiceExportConfig()
-		case "worker":
-			pkg.EditWorker()
-		default:
-			util.Fatalf("Invalid object type")
-		}
+		exitOnError(pkg.SetCliOptions(pkg.CliParams{Config: Config, KubeConfigPath: kubeconfig, ContextName: kubeContext, Namespace: ns, ObjectName: objectName, ObjectType: args[0], FileName: filename}))
+		pkg.PrintControllerContext()
+		runStep(cmd, args[0], func(ctx context.Context) error {
+			switch args[0] {
+			case "project":
+				return pkg.EditProject(ctx)
+			case "sliceConfig":
+				return pkg.EditSliceConfig(ctx)
+			case "serviceExportConfig":
+				return pkg.EditServiceExportConfig(ctx)
+			case "worker":
+				return pkg.EditWorker(ctx)
+			default:
+				return util.ValidationErrorf("Invalid object type")
+			}
+		})
 	},
 }
 
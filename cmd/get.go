@@ -1,6 +1,8 @@
 package cmd
 
 import (
+	"context"
+
 	"github.com/kubeslice/kubeslice-cli/pkg"
 	"github.com/kubeslice/kubeslice-cli/util"
 	"github.com/spf13/cobra"
@@ -15,30 +17,33 @@ var getCmd = &cobra.Command{
 		var objectName string
 		ns, _ := cmd.Flags().GetString("namespace")
 		if ns == "" && args[0] != "ui-endpoint" {
-			util.Fatalf("Namespace is required")
+			exitOnError(util.ValidationErrorf("Namespace is required"))
 		}
 		worker, _ := cmd.Flags().GetString("worker")
 		if len(args) > 1 {
 			objectName = args[1]
 		}
 
-		pkg.SetCliOptions(pkg.CliParams{Config: Config, Namespace: ns, ObjectName: objectName, ObjectType: args[0], OutputFormat: outputFormat})
-		switch args[0] {
-		case "project":
-			pkg.GetProject()
-		case "sliceConfig":
-			pkg.GetSliceConfig()
-		case "serviceExportConfig":
-			pkg.GetServiceExportConfig()
-		case "secrets":
-			pkg.GetSecrets(worker)
-		case "worker":
-			pkg.GetWorker()
-		case "ui-endpoint":
-			pkg.GetUIEndpoint()
-		default:
-			util.Fatalf("Invalid object type")
-		}
+		exitOnError(pkg.SetCliOptions(pkg.CliParams{Config: Config, KubeConfigPath: kubeconfig, ContextName: kubeContext, Namespace: ns, ObjectName: objectName, ObjectType: args[0], OutputFormat: outputFormat}))
+		runStep(cmd, args[0], func(ctx context.Context) error {
+			switch args[0] {
+			case "project":
+				return pkg.GetProject(ctx)
+			case "sliceConfig":
+				return pkg.GetSliceConfig(ctx)
+			case "serviceExportConfig":
+				return pkg.GetServiceExportConfig(ctx)
+			case "secrets":
+				return pkg.GetSecrets(ctx, worker)
+			case "worker":
+				return pkg.GetWorker(ctx)
+			case "ui-endpoint":
+				pkg.GetUIEndpoint(ctx)
+				return nil
+			default:
+				return util.ValidationErrorf("Invalid object type")
+			}
+		})
 
 	},
 }
@@ -1,12 +1,26 @@
 package cmd
 
 import (
+	"time"
+
 	"github.com/kubeslice/kubeslice-cli/pkg"
 	"github.com/kubeslice/kubeslice-cli/util"
 	"github.com/spf13/cobra"
 )
 
-var withCertManager bool
+var (
+	withCertManager bool
+	listSteps       bool
+	resume          bool
+	atomic          bool
+	parallelism     int
+	onlySteps       = []string{}
+	fromStep        string
+	untilStep       string
+	stepTimeouts    = map[string]string{}
+	pollInterval    time.Duration
+	backoffLimit    int
+)
 
 var installCmd = &cobra.Command{
 	Use:     "install",
@@ -17,37 +31,61 @@ var installCmd = &cobra.Command{
 	KubeSlice functionality`,
 	Args: cobra.NoArgs,
 	Run: func(cmd *cobra.Command, args []string) {
+		if listSteps {
+			pkg.ListInstallSteps()
+			return
+		}
+		if parallelism < 1 {
+			cmd.Help()
+			exitOnError(util.ValidationErrorf("--parallelism must be at least 1"))
+		}
+		if pollInterval <= 0 || backoffLimit < 1 {
+			cmd.Help()
+			exitOnError(util.ValidationErrorf("--poll-interval must be positive and --backoff-limit at least 1"))
+		}
 		// check if config and profile are both set, if so, error out
-		if Config != "" && profile != "" {
+		if len(Config) > 0 && profile != "" {
 			cmd.Help()
-			util.Fatalf("\n %v Cannot use both --config and --profile options", util.Cross)
+			exitOnError(util.ValidationErrorf("Cannot use both --config and --profile options"))
 		}
 		// check if config and profile are both not set, if so, error out
-		if Config == "" && profile == "" {
+		if len(Config) == 0 && profile == "" {
 			cmd.Help()
-			util.Fatalf("\n %v Please pass either --config or --profile option", util.Cross)
+			exitOnError(util.ValidationErrorf("Please pass either --config or --profile option"))
 		}
+		pkg.ProfilesDirectory = profilesDir
+		var err error
 		if profile != "" {
-			switch profile {
-			case pkg.ProfileFullDemo:
-				skipSteps = append(skipSteps, "prometheus")
-			case pkg.ProfileMinimalDemo:
-				skipSteps = append(skipSteps, "prometheus")
-			case pkg.ProfileEntDemo:
-			default:
-				util.Fatalf("%v Unknown profile: %s. Possible values %s", util.Cross, profile, []string{pkg.ProfileFullDemo, pkg.ProfileMinimalDemo, pkg.ProfileEntDemo})
-			}
-			pkg.ReadAndValidateConfiguration("", profile)
+			_, err = pkg.ReadAndValidateConfiguration(nil, profile)
 		} else {
-			pkg.ReadAndValidateConfiguration(Config, "")
+			_, err = pkg.ReadAndValidateConfiguration(Config, "")
+		}
+		exitOnError(err)
+		timeouts := make(map[string]time.Duration)
+		for step, value := range stepTimeouts {
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				exitOnError(util.ValidationErrorf("Invalid --step-timeout for %s: %v", step, err))
+			}
+			timeouts[step] = d
 		}
 		// Default behaviour is not ot install cert-manager
 		if !withCertManager {
 			skipSteps = append(skipSteps, "cert-manager")
 		}
 
-		stepsToSkipMap := mapFromSlice(skipSteps)
-		pkg.Install(stepsToSkipMap)
+		exitOnError(pkg.Install(cmd.Context(), pkg.InstallParams{
+			SkipSteps:    mapFromSlice(skipSteps),
+			Only:         onlySteps,
+			From:         fromStep,
+			Until:        untilStep,
+			Resume:       resume,
+			Parallelism:  parallelism,
+			Atomic:       atomic,
+			StepTimeouts: timeouts,
+			PollInterval: pollInterval,
+			BackoffLimit: backoffLimit,
+		}))
 	},
 }
 
@@ -74,17 +112,38 @@ Supported values:
 		KUBESLICE_IMAGE_PULL_USERNAME : optional : Default 'aveshaenterprise'
 		KUBESLICE_IMAGE_PULL_PASSWORD : required
 
+Profiles can also be defined in files under the profiles directory, which
+take precedence over the built-in ones. Run 'kubeslice-cli profile list' to
+see the available profiles.
 Cannot be used with --config flag.`)
+	installCmd.Flags().StringVar(&profilesDir, "profiles-dir", "", "Directory to read profiles from. Defaults to $KUBESLICE_PROFILES_DIR or ~/.kubeslice/profiles")
 	installCmd.Flags().StringSliceVarP(&skipSteps, "skip", "s", []string{}, `Skips the installation steps (comma-seperated). 
 Supported values:
 	- kind: Skips the creation of kind clusters
 	- calico: Skips the installation of Calico
-	- controller: Skips the installation of KubeSlice Controller
+	- network-info: Skips fetching the network addresses of the clusters
+	- helm-repo: Skips adding the KubeSlice helm repository
+	- cert-manager: Skips the installation of Cert-Manager
+	- controller: Skips the installation of KubeSlice Controller, along with cert-manager and project
+	- project: Skips the creation of the KubeSlice project
 	- worker-registration: Skips the registration of KubeSlice Workers on the Controller
 	- worker: Skips the installation of KubeSlice Worker
 	- demo: Skips the installation of additional example applications
 	- ui: Skips the installtion of enterprise UI components (Kubeslice-Manager)
-	- prometheus: Skips the installation of prometheus`)
+	- prometheus: Skips the installation of prometheus
+	- slice: Skips applying the slices declared in the topology
+	- service-export: Skips applying the service exports declared in the topology
+Steps depending on a skipped step are still run, see --list-steps.`)
 	installCmd.Flags().BoolVarP(&withCertManager, "with-cert-manager", "", false, `Installs Cert-Manager for kubeslice controller (for versions < 0.7.0)`)
+	installCmd.Flags().StringSliceVar(&onlySteps, "only", []string{}, "Runs only the given installation steps (comma-seperated), along with the steps gathering the state they need")
+	installCmd.Flags().StringVar(&fromStep, "from", "", "Runs the installation steps starting from the given step")
+	installCmd.Flags().StringVar(&untilStep, "until", "", "Runs the installation steps up to and including the given step")
+	installCmd.Flags().BoolVar(&resume, "resume", false, "Continues an interrupted install, skipping the steps it finished. Steps whose topology changed since are run again")
+	installCmd.Flags().BoolVar(&atomic, "atomic", false, "Undoes what the install changed when it fails: the kind clusters it created are deleted, the helm releases\nrolled back or uninstalled and the objects it created deleted")
+	installCmd.Flags().StringToStringVar(&stepTimeouts, "step-timeout", map[string]string{}, "Time limits of single installation steps, e.g. --step-timeout=controller=10m,worker=15m. A step\ntaking longer is stopped and the install fails")
+	installCmd.Flags().IntVar(&parallelism, "parallelism", 1, "Number of clusters to create, install Calico, KubeSlice Worker and Prometheus on at the same time")
+	installCmd.Flags().DurationVar(&pollInterval, "poll-interval", 5*time.Second, "How often to check the clusters while waiting for pods, slices and rollouts")
+	installCmd.Flags().IntVar(&backoffLimit, "backoff-limit", 20, "How many times pods may be found failing before the install stops waiting for them to recover")
+	installCmd.Flags().BoolVar(&listSteps, "list-steps", false, "Lists the installation steps in the order they run, along with their dependencies")
 
 }
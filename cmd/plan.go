@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/kubeslice/kubeslice-cli/pkg"
+	"github.com/kubeslice/kubeslice-cli/util"
+	"github.com/spf13/cobra"
+)
+
+var planCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "Shows what install would change on the clusters",
+	Long: `Compares the topology with the clusters and prints, for each cluster, what
+	install would add, change or remove: the helm releases with their chart versions
+	and rendered values, the Project, Cluster and SliceConfig objects and the Prometheus
+	telemetry settings of the workers. Nothing is changed on the clusters.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(Config) > 0 && profile != "" {
+			cmd.Help()
+			exitOnError(util.ValidationErrorf("Cannot use both --config and --profile options"))
+		}
+		if len(Config) == 0 && profile == "" {
+			cmd.Help()
+			exitOnError(util.ValidationErrorf("Please pass either --config or --profile option"))
+		}
+		if outputFormat != "" && outputFormat != "json" {
+			cmd.Help()
+			exitOnError(util.ValidationErrorf("Unsupported output format %s. Supported values json", outputFormat))
+		}
+		if outputFormat == "json" && progressFormat == util.ProgressJSON {
+			exitOnError(util.ValidationErrorf("Cannot use both --output=json and --progress-format=json, both print to stdout"))
+		}
+		// keep stdout for the plan
+		if outputFormat == "json" {
+			util.SetOutput(os.Stderr)
+		}
+		pkg.ProfilesDirectory = profilesDir
+		var err error
+		if profile != "" {
+			_, err = pkg.ReadAndValidateConfiguration(nil, profile)
+		} else {
+			_, err = pkg.ReadAndValidateConfiguration(Config, "")
+		}
+		exitOnError(err)
+		// as install, cert-manager is only compared when it is installed
+		if !withCertManager {
+			skipSteps = append(skipSteps, "cert-manager")
+		}
+		exitOnError(pkg.Plan(cmd.Context(), pkg.PlanParams{
+			SkipSteps:    mapFromSlice(skipSteps),
+			OutputFormat: outputFormat,
+		}))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(planCmd)
+	planCmd.Flags().StringVarP(&profile, "profile", "p", "", "<profile-value> The profile to compare the clusters with. Cannot be used with --config flag")
+	planCmd.Flags().StringVar(&profilesDir, "profiles-dir", "", "Directory to read profiles from. Defaults to $KUBESLICE_PROFILES_DIR or ~/.kubeslice/profiles")
+	planCmd.Flags().StringSliceVarP(&skipSteps, "skip", "s", []string{}, "Installation steps not to compare (comma-seperated), as passed to install --skip")
+	planCmd.Flags().BoolVar(&withCertManager, "with-cert-manager", false, "Compares Cert-Manager as well, as installed by install --with-cert-manager")
+	planCmd.Flags().StringVarP(&outputFormat, "output", "o", "", "Output format. Supported values json. Prints a per-cluster diff when empty")
+}
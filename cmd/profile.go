@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"github.com/kubeslice/kubeslice-cli/pkg"
+	"github.com/spf13/cobra"
+)
+
+var profileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Work with install profiles.",
+	Long: `Install profiles bundle a demo topology with the way it is installed: the steps
+	to skip, the demo applications and the next steps printed at the end. Profiles are
+	read from the files in the profiles directory, named <profile>.yaml, on top of the
+	built-in ones.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+var profileListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists the available install profiles.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		pkg.ProfilesDirectory = profilesDir
+		pkg.ListProfiles()
+	},
+}
+
+var profileShowCmd = &cobra.Command{
+	Use:   "show [name]",
+	Short: "Prints the profile file of an install profile.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		pkg.ProfilesDirectory = profilesDir
+		exitOnError(pkg.ShowProfile(args[0]))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(profileCmd)
+	profileCmd.AddCommand(profileListCmd)
+	profileCmd.AddCommand(profileShowCmd)
+	profileCmd.PersistentFlags().StringVar(&profilesDir, "profiles-dir", "", "Directory to read profiles from. Defaults to $KUBESLICE_PROFILES_DIR or ~/.kubeslice/profiles")
+}
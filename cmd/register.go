@@ -1,6 +1,8 @@
 package cmd
 
 import (
+	"context"
+
 	"github.com/kubeslice/kubeslice-cli/pkg"
 	"github.com/kubeslice/kubeslice-cli/util"
 	"github.com/spf13/cobra"
@@ -14,7 +16,7 @@ var registerCmd = &cobra.Command{
 		var objectName string
 		ns, _ := cmd.Flags().GetString("namespace")
 		if ns == "" {
-			util.Fatalf("Namespace is required")
+			exitOnError(util.ValidationErrorf("Namespace is required"))
 		}
 		filename, _ := cmd.Flags().GetString("filename")
 
@@ -22,13 +24,16 @@ var registerCmd = &cobra.Command{
 			objectName = args[1]
 		}
 
-		pkg.SetCliOptions(pkg.CliParams{Config: Config, Namespace: ns, ObjectName: objectName, ObjectType: args[0], FileName: filename})
-		switch args[0] {
-		case "worker":
-			pkg.RegisterWorker()
-		default:
-			util.Fatalf("Invalid object type")
-		}
+		exitOnError(pkg.SetCliOptions(pkg.CliParams{Config: Config, KubeConfigPath: kubeconfig, ContextName: kubeContext, Namespace: ns, ObjectName: objectName, ObjectType: args[0], FileName: filename}))
+		pkg.PrintControllerContext()
+		runStep(cmd, args[0], func(ctx context.Context) error {
+			switch args[0] {
+			case "worker":
+				return pkg.RegisterWorker(ctx)
+			default:
+				return util.ValidationErrorf("Invalid object type")
+			}
+		})
 	},
 }
 
@@ -1,9 +1,15 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/kubeslice/kubeslice-cli/pkg"
+	"github.com/kubeslice/kubeslice-cli/util"
 	"github.com/spf13/cobra"
 	// "github.com/spf13/cobra/doc"
 )
@@ -18,19 +24,73 @@ var rootCmd = &cobra.Command{
 Use kubeslice-cli to install/uninstall required workloads to run KubeSlice Controller and KubeSlice Worker.
 Additional example applications can also be installed in demo profiles to showcase the
 KubeSlice functionality`,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		exitOnError(util.SetProgressFormat(progressFormat))
+		exitOnError(pkg.SetKubeBackend(kubeBackend))
+		exitOnError(pkg.SetHelmBackend(helmBackend))
+		exitOnError(checkControllerFlags(cmd))
+		if timeout > 0 {
+			var ctx context.Context
+			ctx, cancelTimeout = context.WithTimeout(cmd.Context(), timeout)
+			cmd.SetContext(ctx)
+		}
+	},
+	PersistentPostRun: func(cmd *cobra.Command, args []string) {
+		util.PrintProgressSummary(nil)
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 		cmd.Help()
 	},
 }
 var RootCmd = rootCmd
 
+var (
+	timeout        time.Duration
+	cancelTimeout  context.CancelFunc = func() {}
+	progressFormat string
+	kubeBackend    string
+	helmBackend    string
+	kubeconfig     string
+	kubeContext    string
+)
+
 func Execute() {
-	rootCmd.PersistentFlags().StringVarP(&Config, "config", "c", "", `<path-to-topology-configuration-yaml-file>
+	rootCmd.PersistentFlags().StringSliceVarP(&Config, "config", "c", []string{}, `<path-to-topology-configuration-yaml-file>
 	The yaml file with topology configuration. 
-	Refer: https://github.com/kubeslice/kubeslice-cli/blob/master/samples/template.yaml`)
-	if err := rootCmd.Execute(); err != nil {
+	Refer: https://github.com/kubeslice/kubeslice-cli/blob/master/samples/template.yaml
+	Can be repeated (or comma-seperated) to deep-merge several files in order,
+	e.g. --config=base.yaml --config=prod.yaml`)
+	rootCmd.PersistentFlags().BoolVar(&util.DryRun, "dry-run", false, `Generate the files and print the helm, kubectl, kind and docker commands
+	instead of running them. Values read from the clusters are printed as <placeholders>`)
+	rootCmd.PersistentFlags().DurationVar(&timeout, "timeout", 0, `Time limit for the whole run, e.g. 30m. The commands running when it is
+	reached are stopped. 0 for none`)
+	rootCmd.PersistentFlags().StringVar(&progressFormat, "progress-format", util.ProgressText, `Format of the progress, supported values text, json. json prints one event
+	per line to stdout as each step and command starts, finishes or fails, and a
+	summary last. The text output and the output of the commands go to stderr then`)
+	rootCmd.PersistentFlags().StringVar(&kubeBackend, "kube-backend", "auto", `How to get, apply, patch and delete the resources of the clusters, supported
+	values auto, client-go, kubectl. auto uses client-go and falls back to kubectl
+	for a cluster whose kubeconfig client-go cannot load`)
+	rootCmd.PersistentFlags().StringVar(&helmBackend, "helm-backend", "auto", `How to add the chart repositories and install, upgrade and uninstall the
+	releases, supported values auto, sdk, helm. auto uses the Helm SDK and falls
+	back to the helm binary for a cluster whose kubeconfig the SDK cannot load.
+	Only the helm backend needs helm installed`)
+	rootCmd.PersistentFlags().StringVar(&kubeconfig, "kubeconfig", "", `Kubeconfig of the controller cluster for the get, create, delete, edit,
+	describe and register commands. Defaults to $KUBECONFIG or ~/.kube/config.
+	Must match the controller of --config when both are passed`)
+	rootCmd.PersistentFlags().StringVar(&kubeContext, "context", "", `Context of the controller cluster for the get, create, delete, edit, describe
+	and register commands. Defaults to the current context of the kubeconfig.
+	Must match the controller of --config when both are passed`)
+	// SIGINT or SIGTERM stops the commands running and the waits, a second
+	// one kills the CLI at once
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	defer cancelTimeout()
+	if err := rootCmd.ExecuteContext(ctx); err != nil {
 		fmt.Fprintf(os.Stderr, "Whoops. There was an error while executing kubeslice-cli '%s'", err)
-		os.Exit(1)
+		os.Exit(util.ExitValidation)
 	}
 	//  Uncomment to generate docs for new commands/flags
 	// doc.GenMarkdownTree(rootCmd, "doc")
@@ -21,11 +21,17 @@ var uninstallCmd = &cobra.Command{
 	Short:   "Performs cleanup of Kubeslice components.",
 	Args:    cobra.NoArgs,
 	Run: func(cmd *cobra.Command, args []string) {
-		pkg.ReadAndValidateConfiguration(Config, "")
+		if len(Config) > 0 && profile != "" {
+			cmd.Help()
+			exitOnError(util.ValidationErrorf("Cannot use both --config and --profile options"))
+		}
+		pkg.ProfilesDirectory = profilesDir
+		_, err := pkg.ReadAndValidateConfiguration(Config, profile)
+		exitOnError(err)
 		// if --all flag is passed, other flags should not be allowed
 		if uninstallAll && uninstallUI {
 			cmd.Help()
-			util.Fatalf("\n %v Cannot use other options if --all is passed", util.Cross)
+			exitOnError(util.ValidationErrorf("Cannot use other options if --all is passed"))
 		}
 
 		// if no flags are passed, set uninstallAll true
@@ -54,12 +60,14 @@ var uninstallCmd = &cobra.Command{
 			componentsToUninstall["worker"] = ""
 			workersToUninstall = mapFromSlice(uninstallWorker)
 		}
-		pkg.Uninstall(componentsToUninstall, workersToUninstall)
+		exitOnError(pkg.Uninstall(cmd.Context(), componentsToUninstall, workersToUninstall))
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(uninstallCmd)
+	uninstallCmd.Flags().StringVarP(&profile, "profile", "p", "", "The profile the demo was installed with. Defaults to full-demo when --config is not passed")
+	uninstallCmd.Flags().StringVar(&profilesDir, "profiles-dir", "", "Directory to read profiles from. Defaults to $KUBESLICE_PROFILES_DIR or ~/.kubeslice/profiles")
 	uninstallCmd.Flags().BoolVarP(&uninstallAll, "all", "a", false, `Uninstalls all components (Worker, Controller, UI)`)
 	uninstallCmd.Flags().BoolVarP(&uninstallUI, "ui", "u", false, `Uninstalls enterprise UI components (Kubeslice-Manager)`)
 	// TODO: update the controller version after release
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/kubeslice/kubeslice-cli/pkg"
+	"github.com/kubeslice/kubeslice-cli/util"
+	"github.com/spf13/cobra"
+)
+
+var upgradeOptions = pkg.UpgradeParams{}
+
+var upgradeCmd = &cobra.Command{
+	Use:   "upgrade",
+	Short: "Upgrades KubeSlice to the chart versions of the topology",
+	Long: `Upgrades the KubeSlice Controller and then the KubeSlice Workers, one at a time,
+	to the chart versions set in the topology. Each cluster is upgraded once the previous
+	one is healthy. Version skews the workers do not support, where a worker is newer than
+	the controller or more than one minor version behind it, are refused before anything
+	is upgraded. The values the charts were deployed with are kept unless --reset-values
+	is passed.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(Config) > 0 && profile != "" {
+			cmd.Help()
+			exitOnError(util.ValidationErrorf("Cannot use both --config and --profile options"))
+		}
+		if len(Config) == 0 && profile == "" {
+			cmd.Help()
+			exitOnError(util.ValidationErrorf("Please pass either --config or --profile option"))
+		}
+		if upgradeOptions.PollInterval <= 0 || upgradeOptions.BackoffLimit < 1 {
+			cmd.Help()
+			exitOnError(util.ValidationErrorf("--poll-interval must be positive and --backoff-limit at least 1"))
+		}
+		pkg.ProfilesDirectory = profilesDir
+		var err error
+		if profile != "" {
+			_, err = pkg.ReadAndValidateConfiguration(nil, profile)
+		} else {
+			_, err = pkg.ReadAndValidateConfiguration(Config, "")
+		}
+		exitOnError(err)
+		exitOnError(pkg.Upgrade(cmd.Context(), upgradeOptions))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(upgradeCmd)
+	upgradeCmd.Flags().StringVarP(&profile, "profile", "p", "", "<profile-value> The profile the clusters were installed with. Cannot be used with --config flag")
+	upgradeCmd.Flags().StringVar(&profilesDir, "profiles-dir", "", "Directory to read profiles from. Defaults to $KUBESLICE_PROFILES_DIR or ~/.kubeslice/profiles")
+	upgradeCmd.Flags().BoolVar(&upgradeOptions.ResetValues, "reset-values", false, "Generates the helm values from the topology as install does, instead of keeping the deployed values")
+	upgradeCmd.Flags().BoolVar(&upgradeOptions.Rollback, "rollback", true, "Rolls a worker back to its previous revision when its pods fail to become healthy after the upgrade")
+	upgradeCmd.Flags().DurationVar(&upgradeOptions.PollInterval, "poll-interval", 5*time.Second, "How often to check the clusters while waiting for pods")
+	upgradeCmd.Flags().IntVar(&upgradeOptions.BackoffLimit, "backoff-limit", 20, "How many times pods may be found failing before the upgrade stops waiting for them to recover")
+}
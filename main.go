@@ -2,8 +2,11 @@ package main
 
 import (
 	"github.com/kubeslice/kubeslice-cli/cmd"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 )
 
 func main() {
+	// client-go logs the errors it also returns, which the CLI reports itself
+	utilruntime.ErrorHandlers = []func(error){}
 	cmd.Execute()
 }
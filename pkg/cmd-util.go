@@ -2,8 +2,11 @@ package pkg
 
 import (
 	"fmt"
-	"io/ioutil"
+	"net"
+	"net/url"
 	"os"
+	"path/filepath"
+	"regexp"
 
 	"github.com/go-yaml/yaml"
 	"github.com/kubeslice/kubeslice-cli/pkg/internal"
@@ -11,31 +14,72 @@ import (
 )
 
 const (
-	ProfileFullDemo    = "full-demo"
-	ProfileMinimalDemo = "minimal-demo"
-	ProfileEntDemo     = "enterprise-demo"
-	ClusterTypeKind    = "kind"
+	// ProfileFullDemo is installed when neither a topology nor a profile is passed
+	ProfileFullDemo = "full-demo"
+	ClusterTypeKind = "kind"
 )
 
 type CliParams struct {
-	ObjectType   string // "project", "cluster", "sliceConfig"
-	ObjectName   string // "projectName", "clusterName", "sliceConfigName"
-	Namespace    string // namespace for the workloads
-	FileName     string // path to the resource description file
-	Config       string // cluster
-	OutputFormat string //output format
+	ObjectType   string   // "project", "cluster", "sliceConfig"
+	ObjectName   string   // "projectName", "clusterName", "sliceConfigName"
+	Namespace    string   // namespace for the workloads
+	FileName     string   // path to the resource description file
+	Config       []string // topology files, merged in order
+	OutputFormat string   //output format
 	Key          []string
+	// KubeConfigPath and ContextName select the controller when no topology
+	// is passed, see SetCliOptions
+	KubeConfigPath string
+	ContextName    string
 }
 
 var ApplicationConfiguration *internal.ConfigurationSpecs
 
+// ProfilesDirectory overrides the directory user-defined profiles are read
+// from, see internal.ProfilesDirectory for the default.
+var ProfilesDirectory string
+
+func profilesDirectory() string {
+	if ProfilesDirectory != "" {
+		return ProfilesDirectory
+	}
+	return internal.ProfilesDirectory()
+}
+
 var CliOptions *internal.CliOptionsStruct
 
-func SetCliOptions(cliParams CliParams) {
-	var controllerCluster *internal.Cluster
-	configSpecs := ReadAndValidateConfiguration(cliParams.Config, "")
-	if cliParams.Config != "" {
-		controllerCluster = &configSpecs.Configuration.ClusterConfiguration.ControllerCluster
+// SetKubeBackend sets how the resources of the clusters are read and written,
+// through client-go, kubectl, or client-go falling back to kubectl for the
+// clusters it cannot load the kubeconfig of (auto).
+func SetKubeBackend(backend string) error {
+	switch backend {
+	case internal.KubeBackendAuto, internal.KubeBackendNative, internal.KubeBackendKubectl:
+		internal.KubeBackend = backend
+		return nil
+	}
+	return util.ValidationErrorf("Unsupported kube backend %s. Supported values %s, %s, %s", backend, internal.KubeBackendAuto, internal.KubeBackendNative, internal.KubeBackendKubectl)
+}
+
+// SetHelmBackend sets how the charts are installed, through the Helm SDK,
+// the helm binary, or the SDK falling back to the binary for the clusters it
+// cannot load the kubeconfig of (auto).
+func SetHelmBackend(backend string) error {
+	switch backend {
+	case internal.HelmBackendAuto, internal.HelmBackendSDK, internal.HelmBackendBinary:
+		internal.HelmBackend = backend
+		return nil
+	}
+	return util.ValidationErrorf("Unsupported helm backend %s. Supported values %s, %s, %s", backend, internal.HelmBackendAuto, internal.HelmBackendSDK, internal.HelmBackendBinary)
+}
+
+func SetCliOptions(cliParams CliParams) error {
+	configSpecs, err := ReadAndValidateConfiguration(cliParams.Config, "")
+	if err != nil {
+		return err
+	}
+	controllerCluster, err := selectControllerCluster(cliParams, configSpecs)
+	if err != nil {
+		return err
 	}
 	options := &internal.CliOptionsStruct{
 		Namespace:    cliParams.Namespace,
@@ -49,84 +93,121 @@ func SetCliOptions(cliParams CliParams) {
 	util.ExecutablePaths = map[string]string{
 		"kubectl": "kubectl",
 	}
+	return nil
 }
 
-var defaultConfiguration = &internal.ConfigurationSpecs{
-	Configuration: internal.Configuration{
-		ClusterConfiguration: internal.ClusterConfiguration{
-			Profile: "full-demo",
-			ControllerCluster: internal.Cluster{
-				Name: "ks-ctrl",
-			},
-			WorkerClusters: []internal.Cluster{
-				{
-					Name: "ks-w-1",
-				},
-				{
-					Name: "ks-w-2",
-				},
-			},
-		},
-		KubeSliceConfiguration: internal.KubeSliceConfiguration{
-			ProjectName: "demo",
-		},
-		HelmChartConfiguration: internal.HelmChartConfiguration{
-			RepoAlias: "kubeslice-demo",
-			RepoUrl:   "https://kubeslice.github.io/kubeslice/",
-			CertManagerChart: internal.HelmChart{
-				ChartName: "cert-manager",
-			},
-			ControllerChart: internal.HelmChart{
-				ChartName: "kubeslice-controller",
-			},
-			WorkerChart: internal.HelmChart{
-				ChartName: "kubeslice-worker",
-			},
-		},
-	},
-}
-
-var defaultEntConfiguration = &internal.HelmChartConfiguration{
-	RepoAlias: "kubeslice-ent-demo",
-	RepoUrl:   "https://kubeslice.aveshalabs.io/repository/kubeslice-helm-ent-stage",
-	CertManagerChart: internal.HelmChart{
-		ChartName: "cert-manager",
-	},
-	ControllerChart: internal.HelmChart{
-		ChartName: "kubeslice-controller",
-	},
-	WorkerChart: internal.HelmChart{
-		ChartName: "kubeslice-worker",
-	},
-	UIChart: internal.HelmChart{
-		ChartName: "kubeslice-ui",
-		Values: map[string]interface{}{
-			"kubeslice.uiproxy.service.nodePort": 31000,
-		},
-	},
-	PrometheusChart: internal.HelmChart{
-		ChartName: "prometheus",
-	},
-}
-
-func readConfiguration(fileName string) *internal.ConfigurationSpecs {
-	file, err := ioutil.ReadFile(fileName)
-	if err != nil {
-		util.Fatalf("%s Failed to read configuration file %v", util.Cross, err)
+// selectControllerCluster returns the controller the resource commands talk
+// to: the controller of the topology when one is passed, else the context of
+// --kubeconfig and --context, the current context of the kubeconfig filling
+// in a missing --context. Flags contradicting the topology are refused rather
+// than one of them being preferred, and the current context is resolved once
+// so that every command of the run goes to the same cluster. The controller
+// is nil when there is no kubeconfig to resolve it from.
+func selectControllerCluster(cliParams CliParams, specs *internal.ConfigurationSpecs) (*internal.Cluster, error) {
+	if len(cliParams.Config) > 0 {
+		cluster := &specs.Configuration.ClusterConfiguration.ControllerCluster
+		if cliParams.ContextName != "" && cliParams.ContextName != cluster.ContextName {
+			return nil, util.ValidationErrorf("--context %s does not match the controller context %s of --config, pass one or the other", cliParams.ContextName, cluster.ContextName)
+		}
+		if cliParams.KubeConfigPath != "" && !samePath(cliParams.KubeConfigPath, cluster.KubeConfigPath) {
+			return nil, util.ValidationErrorf("--kubeconfig %s does not match the controller kubeconfig %s of --config, pass one or the other", cliParams.KubeConfigPath, cluster.KubeConfigPath)
+		}
+		return cluster, nil
+	}
+	cluster := &internal.Cluster{KubeConfigPath: cliParams.KubeConfigPath, ContextName: cliParams.ContextName}
+	if cluster.ContextName == "" {
+		current, err := internal.CurrentContext(cluster.KubeConfigPath)
+		if err != nil && cluster.KubeConfigPath != "" {
+			return nil, util.ValidationErrorf("Failed to read the current context of %s, pass --context: %w", cluster.KubeConfigPath, err)
+		}
+		if err != nil {
+			return nil, nil
+		}
+		cluster.ContextName = current
+	}
+	cluster.Name = cluster.ContextName
+	return cluster, nil
+}
+
+func samePath(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+	return absA == absB
+}
+
+// PrintControllerContext prints the controller context the resource commands
+// are about to change, see SetCliOptions.
+func PrintControllerContext() {
+	cluster := CliOptions.Cluster
+	if cluster == nil {
+		util.Printf("%s Using the current context of the default kubeconfig for the controller", util.Warn)
+		return
+	}
+	kubeconfig := cluster.KubeConfigPath
+	if kubeconfig == "" {
+		kubeconfig = "$KUBECONFIG or ~/.kube/config"
+	}
+	util.Printf("%s Using controller context %s, kubeconfig %s", util.Warn, cluster.ContextName, kubeconfig)
+}
 
+func readConfiguration(fileNames []string) (*internal.ConfigurationSpecs, *internal.ConfigLayers, []internal.ConfigError) {
+	layers, errors := internal.LoadConfigLayers(fileNames)
+	if len(errors) > 0 {
+		return nil, nil, errors
+	}
+	warnOutdated(layers)
+	file, err := layers.Merged()
+	if err != nil {
+		return nil, nil, []internal.ConfigError{configError("", "Failed to merge configuration files %v", err)}
 	}
 	specs := &internal.ConfigurationSpecs{}
 	err = yaml.Unmarshal(file, specs)
 	if err != nil {
-		util.Fatalf("%s Failed to parse configuration file %v", util.Cross, err)
+		return nil, nil, []internal.ConfigError{configError("", "Failed to parse configuration file %v", err)}
 	}
-	return specs
+	if errors := internal.ResolveReferences(specs, layers.Dir); len(errors) > 0 {
+		return nil, layers, layers.Locate(errors)
+	}
+	// values files are relative to the file they are listed in
+	for i, cluster := range specs.Configuration.ClusterConfiguration.WorkerClusters {
+		for j, fileName := range cluster.ValuesFiles {
+			if !filepath.IsAbs(fileName) {
+				path := fmt.Sprintf("configuration.cluster_configuration.workers[%d].values_files[%d]", i, j)
+				cluster.ValuesFiles[j] = filepath.Join(layers.Dir(path), fileName)
+			}
+		}
+	}
+	return specs, layers, nil
+}
+
+// warnOutdated prints a deprecation warning for every file written in an older
+// topology version, to stderr so that the output of -o json and render on
+// stdout stays clean.
+func warnOutdated(layers *internal.ConfigLayers) {
+	for _, doc := range layers.Outdated() {
+		fmt.Fprintf(os.Stderr, "%s %s uses the deprecated topology version %s, run `kubeslice-cli config migrate --config=%s` to update it to %s\n",
+			util.Warn, doc.File, doc.APIVersion, doc.File, internal.ConfigAPIVersion)
+	}
+}
+
+func configError(path, format string, a ...interface{}) internal.ConfigError {
+	return internal.ConfigError{Path: path, Message: fmt.Sprintf(format, a...)}
 }
 
-func validateConfiguration(specs *internal.ConfigurationSpecs) []string {
-	var errors = make([]string, 0)
+var (
+	dns1123Label = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+	chartVersion = regexp.MustCompile(`^(\^|~|>=|<=|>|<|=)?v?\d+(\.(\d+|x|\*)){0,2}(-[0-9A-Za-z.-]+)?(\+[0-9A-Za-z.-]+)?$`)
+)
+
+var clusterTypes = []string{ClusterTypeKind, "cloud", "data-center"}
+
+func validateConfiguration(specs *internal.ConfigurationSpecs) []internal.ConfigError {
+	var errors = make([]internal.ConfigError, 0)
 	if specs == nil {
-		errors = append(errors, fmt.Sprintf("%s Invalid Configuration", util.Cross))
+		return append(errors, configError("", "Invalid Configuration"))
 	}
 	cc := &specs.Configuration.ClusterConfiguration
 	ksc := &specs.Configuration.KubeSliceConfiguration
@@ -142,111 +223,363 @@ func validateConfiguration(specs *internal.ConfigurationSpecs) []string {
 		}
 
 	}
+	if cc.ClusterType != "" && !contains(clusterTypes, cc.ClusterType) {
+		errors = append(errors, configError("configuration.cluster_configuration.cluster_type", "unknown cluster type: %s. Possible values %s", cc.ClusterType, clusterTypes))
+	}
 	if cc.Profile != "" {
-		switch cc.Profile {
-		case ProfileFullDemo:
-		case ProfileMinimalDemo:
-		case ProfileEntDemo:
-			if hc.ImagePullSecret.Password == "" {
-				errors = append(errors, fmt.Sprintf("%s Missing image pull secret password. Please set environment variable `KUBESLICE_IMAGE_PULL_PASSWORD`", util.Cross))
+		profile, profileErrors := internal.LoadProfile(profilesDirectory(), cc.Profile)
+		if profile == nil {
+			for _, e := range profileErrors {
+				if e.File == "" {
+					e.Path = "configuration.cluster_configuration.profile"
+				}
+				errors = append(errors, e)
 			}
-		default:
-			errors = append(errors, fmt.Sprintf("%s Unknown profile: %s. Possible values %s", util.Cross, cc.Profile, []string{ProfileFullDemo,
-				ProfileMinimalDemo, ProfileEntDemo}))
+		} else {
+			specs.InstallProfile = profile
 		}
-		if cc.KubeConfigPath != "" || cc.ControllerCluster.KubeConfigPath != "" {
-			errors = append(errors, fmt.Sprintf("%s Cannot specify configuration.cluster_configuration.kube_config_path or configuration.cluster_configuration.controller.kube_config_path when running a kind cluster demo", util.Cross))
+		if cc.KubeConfigPath != "" {
+			errors = append(errors, configError("configuration.cluster_configuration.kube_config_path", "cannot be specified when running a kind cluster demo"))
+		}
+		if cc.ControllerCluster.KubeConfigPath != "" {
+			errors = append(errors, configError("configuration.cluster_configuration.controller.kube_config_path", "cannot be specified when running a kind cluster demo"))
 		}
 		cc.ControllerCluster.KubeConfigPath = internal.KubeconfigPath
 		if cc.ControllerCluster.ContextName != "" {
-			errors = append(errors, fmt.Sprintf("%s Cannot specify configuration.cluster_configuration.controller.context_name when running a kind cluster demo", util.Cross))
+			errors = append(errors, configError("configuration.cluster_configuration.controller.context_name", "cannot be specified when running a kind cluster demo"))
 		}
 		cc.ControllerCluster.ContextName = "kind-" + cc.ControllerCluster.Name
 		if len(cc.WorkerClusters) < 2 {
-			errors = append(errors, fmt.Sprintf("%s At least 2 configuration.cluster_configuration.workers are required for kind cluster Demo", util.Cross))
+			errors = append(errors, configError("configuration.cluster_configuration.workers", "at least 2 workers are required for kind cluster Demo"))
 		}
 		for i, cluster := range cc.WorkerClusters {
 			if cluster.KubeConfigPath != "" {
-				errors = append(errors, fmt.Sprintf("%s Cannot specify configuration.cluster_configuration.kube_config_path or configuration.cluster_configuration.workers[%d].kube_config_path when running a kind cluster demo", util.Cross, i))
+				errors = append(errors, configError(fmt.Sprintf("configuration.cluster_configuration.workers[%d].kube_config_path", i), "cannot be specified when running a kind cluster demo"))
 			}
 			cc.WorkerClusters[i].KubeConfigPath = internal.KubeconfigPath
 			if cluster.ContextName != "" {
-				errors = append(errors, fmt.Sprintf("%s Cannot specify configuration.cluster_configuration.workers[%d].context_name for worker when running a kind cluster demo", util.Cross, i))
+				errors = append(errors, configError(fmt.Sprintf("configuration.cluster_configuration.workers[%d].context_name", i), "cannot be specified for worker when running a kind cluster demo"))
 			}
 			cc.WorkerClusters[i].ContextName = "kind-" + cluster.Name
 		}
 	} else {
 		if cc.KubeConfigPath == "" && cc.ControllerCluster.KubeConfigPath == "" {
-			errors = append(errors, fmt.Sprintf("%s configuration.cluster_configuration.kube_config_path or configuration.cluster_configuration.controller.kube_config_path must be specified when setting up topology", util.Cross))
+			errors = append(errors, configError("configuration.cluster_configuration.controller.kube_config_path", "must be specified when setting up topology, or set configuration.cluster_configuration.kube_config_path"))
 		}
 		if cc.ControllerCluster.KubeConfigPath == "" && cc.KubeConfigPath != "" {
 			cc.ControllerCluster.KubeConfigPath = cc.KubeConfigPath
 		}
 		if cc.ControllerCluster.ContextName == "" {
-			errors = append(errors, fmt.Sprintf("%s configuration.cluster_configuration.controller.context_name must be specified when setting up topology", util.Cross))
+			errors = append(errors, configError("configuration.cluster_configuration.controller.context_name", "must be specified when setting up topology"))
 		}
 		for i, cluster := range cc.WorkerClusters {
 			if cc.KubeConfigPath == "" && cluster.KubeConfigPath == "" {
-				errors = append(errors, fmt.Sprintf("%s configuration.cluster_configuration.kube_config_path or configuration.cluster_configuration.workers[%d].kube_config_path must be specified when setting up topology", util.Cross, i))
+				errors = append(errors, configError(fmt.Sprintf("configuration.cluster_configuration.workers[%d].kube_config_path", i), "must be specified when setting up topology, or set configuration.cluster_configuration.kube_config_path"))
 			}
 			if cluster.KubeConfigPath == "" && cc.KubeConfigPath != "" {
 				cc.WorkerClusters[i].KubeConfigPath = cc.KubeConfigPath
 			}
 			if cluster.ContextName == "" {
-				errors = append(errors, fmt.Sprintf("%s configuration.cluster_configuration.workers[%d].context_name must be specified when setting up topology", util.Cross, i))
+				errors = append(errors, configError(fmt.Sprintf("configuration.cluster_configuration.workers[%d].context_name", i), "must be specified when setting up topology"))
 			}
 		}
 	}
-	if cc.ControllerCluster.Name == "" {
-		errors = append(errors, fmt.Sprintf("%s configuration.cluster_configuration.controller.name must be specified", util.Cross))
+	errors = append(errors, validateClusterNames(cc)...)
+	errors = append(errors, validateClusterValues(cc)...)
+	errors = append(errors, validateSlices(cc, ksc)...)
+	errors = append(errors, validateServiceExports(specs)...)
+	if ksc.ProjectName == "" {
+		errors = append(errors, configError("configuration.kubeslice_configuration.project_name", "must be specified"))
+	} else if !dns1123Label.MatchString(ksc.ProjectName) || len("kubeslice-"+ksc.ProjectName) > 63 {
+		errors = append(errors, configError("configuration.kubeslice_configuration.project_name", "%q is not a valid project name, it must be a lowercase RFC 1123 label of at most 53 characters", ksc.ProjectName))
+	}
+	if hc.RepoAlias == "" {
+		errors = append(errors, configError("configuration.helm_chart_configuration.repo_alias", "must be specified"))
+	}
+	if hc.RepoUrl == "" && !hc.UseLocal {
+		errors = append(errors, configError("configuration.helm_chart_configuration.repo_url", "must be specified"))
+	} else if hc.RepoUrl != "" && !hc.UseLocal {
+		if u, err := url.Parse(hc.RepoUrl); err != nil || u.Host == "" || !contains([]string{"http", "https", "oci"}, u.Scheme) {
+			errors = append(errors, configError("configuration.helm_chart_configuration.repo_url", "%q is not a valid http(s) or oci repository URL", hc.RepoUrl))
+		}
+	}
+	charts := []struct {
+		path     string
+		chart    internal.HelmChart
+		required bool
+	}{
+		{"cert_manager_chart", hc.CertManagerChart, true},
+		{"controller_chart", hc.ControllerChart, true},
+		{"worker_chart", hc.WorkerChart, true},
+		{"ui_chart", hc.UIChart, false},
+		{"prometheus_chart", hc.PrometheusChart, false},
+	}
+	for _, c := range charts {
+		path := "configuration.helm_chart_configuration." + c.path
+		if c.required && c.chart.ChartName == "" {
+			errors = append(errors, configError(path, "must be specified"))
+		}
+		if c.chart.Version != "" && !chartVersion.MatchString(c.chart.Version) {
+			errors = append(errors, configError(path+".version", "%q is not a valid chart version", c.chart.Version))
+		}
+	}
+	return errors
+}
+
+// validateClusterNames checks that every cluster is named, that the names are
+// usable as kubernetes object names and that no two clusters share a name.
+func validateClusterNames(cc *internal.ClusterConfiguration) []internal.ConfigError {
+	var errors = make([]internal.ConfigError, 0)
+	checkName := func(path, name string) {
+		if name == "" {
+			errors = append(errors, configError(path, "must be specified"))
+		} else if !dns1123Label.MatchString(name) || len(name) > 63 {
+			errors = append(errors, configError(path, "%q is not a valid cluster name, it must be a lowercase RFC 1123 label", name))
+		}
 	}
+	checkName("configuration.cluster_configuration.controller.name", cc.ControllerCluster.Name)
+	seen := make(map[string]int)
 	for i, cluster := range cc.WorkerClusters {
+		path := fmt.Sprintf("configuration.cluster_configuration.workers[%d].name", i)
+		checkName(path, cluster.Name)
 		if cluster.Name == "" {
-			errors = append(errors, fmt.Sprintf("%s configuration.cluster_configuration.workers[%d].name must be specified", util.Cross, i))
+			continue
+		}
+		if cluster.Name == cc.ControllerCluster.Name {
+			errors = append(errors, configError(path, "worker %q cannot have the same name as the controller cluster", cluster.Name))
+		}
+		if j, ok := seen[cluster.Name]; ok {
+			errors = append(errors, configError(path, "duplicate worker name %q, already used by configuration.cluster_configuration.workers[%d]", cluster.Name, j))
+		} else {
+			seen[cluster.Name] = i
 		}
 	}
-	if ksc.ProjectName == "" {
-		errors = append(errors, fmt.Sprintf("%s configuration.kubeslice_configuration.project_name must be specified", util.Cross))
+	return errors
+}
+
+// validateClusterValues checks that values overrides are only set on workers
+// and that their values files can be read.
+func validateClusterValues(cc *internal.ClusterConfiguration) []internal.ConfigError {
+	var errors = make([]internal.ConfigError, 0)
+	if len(cc.ControllerCluster.HelmValues) > 0 {
+		errors = append(errors, configError("configuration.cluster_configuration.controller.helm_values", "is only supported on workers, use configuration.helm_chart_configuration.controller_chart.values"))
 	}
-	if hc.RepoAlias == "" {
-		errors = append(errors, fmt.Sprintf("%s configuration.helm_chart_configuration.repo_alias must be specified", util.Cross))
+	if len(cc.ControllerCluster.ValuesFiles) > 0 {
+		errors = append(errors, configError("configuration.cluster_configuration.controller.values_files", "is only supported on workers"))
 	}
-	if hc.RepoUrl == "" && !hc.UseLocal {
-		errors = append(errors, fmt.Sprintf("%s configuration.helm_chart_configuration.repo_url must be specified", util.Cross))
+	for i, cluster := range cc.WorkerClusters {
+		for j, fileName := range cluster.ValuesFiles {
+			if _, err := internal.ClusterValues(internal.Cluster{ValuesFiles: []string{fileName}}); err != nil {
+				errors = append(errors, configError(fmt.Sprintf("configuration.cluster_configuration.workers[%d].values_files[%d]", i, j), "cannot read values file: %v", err))
+			}
+		}
+	}
+	return errors
+}
+
+// validateSlices checks that slices are uniquely named, that their subnets do
+// not overlap and that they only span worker clusters of the topology.
+func validateSlices(cc *internal.ClusterConfiguration, ksc *internal.KubeSliceConfiguration) []internal.ConfigError {
+	var errors = make([]internal.ConfigError, 0)
+	workers := make([]string, 0, len(cc.WorkerClusters))
+	for _, cluster := range cc.WorkerClusters {
+		workers = append(workers, cluster.Name)
+	}
+	seen := make(map[string]int)
+	subnets := make([]*net.IPNet, len(ksc.Slices))
+	for i, slice := range ksc.Slices {
+		path := fmt.Sprintf("configuration.kubeslice_configuration.slices[%d]", i)
+		if slice.Name == "" {
+			errors = append(errors, configError(path+".name", "must be specified"))
+		} else if !dns1123Label.MatchString(slice.Name) || len(slice.Name) > 63 {
+			errors = append(errors, configError(path+".name", "%q is not a valid slice name, it must be a lowercase RFC 1123 label", slice.Name))
+		} else if j, ok := seen[slice.Name]; ok {
+			errors = append(errors, configError(path+".name", "duplicate slice name %q, already used by configuration.kubeslice_configuration.slices[%d]", slice.Name, j))
+		} else {
+			seen[slice.Name] = i
+		}
+		if slice.SliceSubnet == "" {
+			errors = append(errors, configError(path+".slice_subnet", "must be specified"))
+		} else if _, subnet, err := net.ParseCIDR(slice.SliceSubnet); err != nil {
+			errors = append(errors, configError(path+".slice_subnet", "%q is not a valid CIDR subnet", slice.SliceSubnet))
+		} else {
+			subnets[i] = subnet
+			for j := 0; j < i; j++ {
+				if subnets[j] != nil && (subnets[j].Contains(subnet.IP) || subnet.Contains(subnets[j].IP)) {
+					errors = append(errors, configError(path+".slice_subnet", "subnet %s overlaps with %s of slice %q", slice.SliceSubnet, ksc.Slices[j].SliceSubnet, ksc.Slices[j].Name))
+				}
+			}
+		}
+		for j, cluster := range slice.Clusters {
+			if !contains(workers, cluster) {
+				errors = append(errors, configError(fmt.Sprintf("%s.clusters[%d]", path, j), "cluster %q is not one of the workers %s", cluster, workers))
+			}
+		}
+		for j, ns := range slice.ApplicationNamespaces {
+			nsPath := fmt.Sprintf("%s.application_namespaces[%d]", path, j)
+			if !dns1123Label.MatchString(ns.Namespace) {
+				errors = append(errors, configError(nsPath+".namespace", "%q is not a valid namespace name", ns.Namespace))
+			}
+			for k, cluster := range ns.Clusters {
+				if cluster == "*" {
+					continue
+				}
+				if !contains(workers, cluster) {
+					errors = append(errors, configError(fmt.Sprintf("%s.clusters[%d]", nsPath, k), "cluster %q is not one of the workers %s", cluster, workers))
+				} else if len(slice.Clusters) > 0 && !contains(slice.Clusters, cluster) {
+					errors = append(errors, configError(fmt.Sprintf("%s.clusters[%d]", nsPath, k), "cluster %q is not part of slice %q", cluster, slice.Name))
+				}
+			}
+		}
+	}
+	return errors
+}
+
+// validateServiceExports checks that every service export targets a worker
+// which is part of its slice, and selects pods on at least one port.
+func validateServiceExports(specs *internal.ConfigurationSpecs) []internal.ConfigError {
+	var errors = make([]internal.ConfigError, 0)
+	slices := make(map[string]internal.Slice)
+	sliceNames := make([]string, 0)
+	for _, slice := range internal.InstalledSlices(specs) {
+		slices[slice.Name] = slice
+		sliceNames = append(sliceNames, slice.Name)
+	}
+	seen := make(map[string]int)
+	for i, se := range specs.Configuration.KubeSliceConfiguration.ServiceExports {
+		path := fmt.Sprintf("configuration.kubeslice_configuration.service_exports[%d]", i)
+		if !dns1123Label.MatchString(se.Name) {
+			errors = append(errors, configError(path+".name", "%q is not a valid service export name, it must be a lowercase RFC 1123 label", se.Name))
+		}
+		if !dns1123Label.MatchString(se.Namespace) {
+			errors = append(errors, configError(path+".namespace", "%q is not a valid namespace name", se.Namespace))
+		}
+		key := se.Worker + "/" + se.Namespace + "/" + se.Name
+		if j, ok := seen[key]; ok {
+			errors = append(errors, configError(path+".name", "duplicate service export %s/%s on worker %q, already declared by configuration.kubeslice_configuration.service_exports[%d]", se.Namespace, se.Name, se.Worker, j))
+		} else {
+			seen[key] = i
+		}
+		slice, ok := slices[se.Slice]
+		if !ok {
+			errors = append(errors, configError(path+".slice", "unknown slice %q. Possible values %s", se.Slice, sliceNames))
+		}
+		found := false
+		for _, cluster := range specs.Configuration.ClusterConfiguration.WorkerClusters {
+			found = found || cluster.Name == se.Worker
+		}
+		if !found {
+			errors = append(errors, configError(path+".worker", "unknown worker %q", se.Worker))
+		} else if ok && !contains(slice.Clusters, se.Worker) {
+			errors = append(errors, configError(path+".worker", "worker %q is not part of slice %q", se.Worker, se.Slice))
+		} else if ok && !onboarded(slice, se.Namespace, se.Worker) {
+			errors = append(errors, configError(path+".namespace", "namespace %q is not onboarded on worker %q by slice %q", se.Namespace, se.Worker, se.Slice))
+		}
+		if len(se.Selector) == 0 {
+			errors = append(errors, configError(path+".selector", "must be specified"))
+		}
+		if len(se.Ports) == 0 {
+			errors = append(errors, configError(path+".ports", "must be specified"))
+		}
+		for j, port := range se.Ports {
+			portPath := fmt.Sprintf("%s.ports[%d]", path, j)
+			if port.ContainerPort < 1 || port.ContainerPort > 65535 {
+				errors = append(errors, configError(portPath+".container_port", "%d is not a valid port number", port.ContainerPort))
+			}
+			if port.Protocol != "" && !contains([]string{"TCP", "UDP", "SCTP"}, port.Protocol) {
+				errors = append(errors, configError(portPath+".protocol", "unknown protocol: %s. Possible values %s", port.Protocol, []string{"TCP", "UDP", "SCTP"}))
+			}
+		}
 	}
-	if hc.CertManagerChart.ChartName == "" {
-		errors = append(errors, fmt.Sprintf("%s configuration.helm_chart_configuration.cert_manager_chart must be specified", util.Cross))
+	return errors
+}
+
+// onboarded tells whether slice onboards namespace on worker.
+func onboarded(slice internal.Slice, namespace, worker string) bool {
+	for _, ns := range slice.ApplicationNamespaces {
+		if ns.Namespace == namespace && (len(ns.Clusters) == 0 || contains(ns.Clusters, "*") || contains(ns.Clusters, worker)) {
+			return true
+		}
 	}
-	if hc.ControllerChart.ChartName == "" {
-		errors = append(errors, fmt.Sprintf("%s configuration.helm_chart_configuration.controller_chart must be specified", util.Cross))
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
 	}
-	if hc.WorkerChart.ChartName == "" {
-		errors = append(errors, fmt.Sprintf("%s configuration.helm_chart_configuration.worker_chart must be specified", util.Cross))
+	return false
+}
+
+// credentialErrors reports the credentials the install needs but which are
+// not set. They are only required when contacting the clusters.
+func credentialErrors(specs *internal.ConfigurationSpecs) []internal.ConfigError {
+	errors := make([]internal.ConfigError, 0)
+	if specs.Enterprise() && specs.Configuration.HelmChartConfiguration.ImagePullSecret.Password == "" {
+		errors = append(errors, configError("configuration.helm_chart_configuration.image_pull_secret.password", "missing image pull secret password. Please set environment variable `KUBESLICE_IMAGE_PULL_PASSWORD`"))
 	}
 	return errors
 }
 
-func ReadAndValidateConfiguration(fileName, profile string) *internal.ConfigurationSpecs {
+// ValidateConfigurationFiles runs every offline check on a topology and
+// returns the problems found, located in the files where possible. Missing
+// credentials are only warned about, as validating does not need them.
+func ValidateConfigurationFiles(fileNames []string) []internal.ConfigError {
+	specs, layers, errors := readConfiguration(fileNames)
+	if len(errors) > 0 {
+		return errors
+	}
+	if errors := validateConfiguration(specs); len(errors) > 0 {
+		return layers.Locate(errors)
+	}
+	for _, e := range layers.Locate(credentialErrors(specs)) {
+		util.Printf("%s %s, it is required to install", util.Warn, e)
+	}
+	return nil
+}
+
+// RenderConfiguration returns the effective topology of the merged files.
+// Secret references are left as written.
+func RenderConfiguration(fileNames []string) ([]byte, []internal.ConfigError) {
+	layers, errors := internal.LoadConfigLayers(fileNames)
+	if len(errors) > 0 {
+		return nil, errors
+	}
+	warnOutdated(layers)
+	merged, err := layers.Merged()
+	if err != nil {
+		return nil, []internal.ConfigError{configError("", "Failed to merge configuration files %v", err)}
+	}
+	return merged, nil
+}
+
+func ReadAndValidateConfiguration(fileNames []string, profile string) (*internal.ConfigurationSpecs, error) {
 	var specs *internal.ConfigurationSpecs
-	if fileName != "" {
-		specs = readConfiguration(fileName)
+	var errors []internal.ConfigError
+	if len(fileNames) > 0 {
+		var layers *internal.ConfigLayers
+		specs, layers, errors = readConfiguration(fileNames)
+		if len(errors) == 0 {
+			errors = layers.Locate(append(validateConfiguration(specs), credentialErrors(specs)...))
+		}
 	} else {
-		specs = defaultConfiguration
-		specs.Configuration.ClusterConfiguration.ClusterType = ClusterTypeKind
-		// Set defaults for ent demo
-		if profile == ProfileEntDemo {
-			specs.Configuration.ClusterConfiguration.Profile = ProfileEntDemo
-			specs.Configuration.HelmChartConfiguration = *defaultEntConfiguration
+		if profile == "" {
+			profile = ProfileFullDemo
+		}
+		var p *internal.InstallProfile
+		if p, errors = internal.LoadProfile(profilesDirectory(), profile); p != nil {
+			specs = p.DefaultTopology()
+			errors = append(validateConfiguration(specs), credentialErrors(specs)...)
 		}
-
 	}
-	errors := validateConfiguration(specs)
 	if len(errors) > 0 {
-		for _, s := range errors {
-			util.Printf(s)
+		for _, e := range errors {
+			util.Printf("%s %s", util.Cross, e)
 		}
-		util.Fatalf("%s Process failed due to invalid configuration", util.Cross)
+		return nil, util.ValidationErrorf("Process failed due to invalid configuration")
 	}
 	ApplicationConfiguration = specs
-	return specs
+	return specs, nil
 }
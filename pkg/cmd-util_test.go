@@ -0,0 +1,589 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-yaml/yaml"
+	"github.com/kubeslice/kubeslice-cli/pkg/internal"
+	"github.com/kubeslice/kubeslice-cli/util"
+)
+
+const validTopology = `apiVersion: cli.kubeslice.io/v1beta1
+configuration:
+  cluster_configuration:
+    kube_config_path: /tmp/kubeconfig
+    controller:
+      name: controller
+      context_name: ctrl
+    workers:
+      - name: worker-1
+        context_name: w1
+      - name: worker-2
+        context_name: w2
+  kubeslice_configuration:
+    project_name: demo
+  helm_chart_configuration:
+    repo_alias: kubeslice
+    repo_url: https://kubeslice.github.io/kubeslice/
+    cert_manager_chart:
+      chart_name: cert-manager
+    controller_chart:
+      chart_name: kubeslice-controller
+      version: 1.1.1
+    worker_chart:
+      chart_name: kubeslice-worker
+`
+
+func TestValidateConfigurationFiles(t *testing.T) {
+	t.Parallel()
+
+	testDir := t.TempDir()
+
+	tests := []struct {
+		name     string
+		topology string
+		// each expected error is matched as a substring of the rendered error
+		want []string
+	}{
+		{
+			name:     "Valid topology",
+			topology: validTopology,
+		},
+		{
+			name:     "Misspelled key is reported with a suggestion",
+			topology: strings.Replace(validTopology, "kube_config_path", "kubeconfig_path", 1),
+			want:     []string{`:4:5: configuration.cluster_configuration.kubeconfig_path: unknown field "kubeconfig_path", did you mean "kube_config_path"?`},
+		},
+		{
+			name:     "Unquoted number in a string field",
+			topology: strings.Replace(validTopology, "version: 1.1.1", "version: 1.10", 1),
+			want:     []string{":22:16: configuration.helm_chart_configuration.controller_chart.version: expected a string, got number 1.10"},
+		},
+		{
+			name:     "Unknown cluster type",
+			topology: strings.Replace(validTopology, "    controller:\n", "    cluster_type: kube\n    controller:\n", 1),
+			want:     []string{":5:5: configuration.cluster_configuration.cluster_type: unknown cluster type: kube"},
+		},
+		{
+			name:     "Duplicate cluster names",
+			topology: strings.Replace(strings.Replace(validTopology, "name: worker-1", "name: controller", 1), "name: worker-2", "name: worker-3\n        context_name: w3\n      - name: worker-3", 1),
+			want: []string{
+				`:9:9: configuration.cluster_configuration.workers[0].name: worker "controller" cannot have the same name as the controller cluster`,
+				`:13:9: configuration.cluster_configuration.workers[2].name: duplicate worker name "worker-3", already used by configuration.cluster_configuration.workers[1]`,
+			},
+		},
+		{
+			name: "Slices are valid",
+			topology: strings.Replace(validTopology, "    project_name: demo\n", `    project_name: demo
+    slices:
+      - name: red
+        slice_subnet: 10.1.0.0/16
+        application_namespaces:
+          - namespace: iperf
+            clusters: ["*"]
+      - name: blue
+        slice_subnet: 10.2.0.0/16
+        clusters: [worker-2]
+`, 1),
+		},
+		{
+			name: "Overlapping slice subnets and unknown slice clusters",
+			topology: strings.Replace(validTopology, "    project_name: demo\n", `    project_name: demo
+    slices:
+      - name: red
+        slice_subnet: 10.1.0.0/16
+      - name: blue
+        slice_subnet: 10.1.128.0/20
+        clusters: [worker-2, worker-3]
+`, 1),
+			want: []string{
+				`:19:9: configuration.kubeslice_configuration.slices[1].slice_subnet: subnet 10.1.128.0/20 overlaps with 10.1.0.0/16 of slice "red"`,
+				`:20:30: configuration.kubeslice_configuration.slices[1].clusters[1]: cluster "worker-3" is not one of the workers [worker-1 worker-2]`,
+			},
+		},
+		{
+			name: "Service exports must target a worker of their slice",
+			topology: strings.Replace(validTopology, "    project_name: demo\n", `    project_name: demo
+    slices:
+      - name: red
+        slice_subnet: 10.1.0.0/16
+        clusters: [worker-1]
+        application_namespaces:
+          - namespace: iperf
+    service_exports:
+      - name: iperf-server
+        worker: worker-1
+        namespace: iperf
+        slice: red
+        selector:
+          app: iperf-server
+        ports:
+          - name: tcp
+            container_port: 5201
+      - name: iperf-server
+        worker: worker-2
+        namespace: iperf
+        slice: red
+        selector:
+          app: iperf-server
+        ports:
+          - name: tcp
+            container_port: 5201
+            protocol: HTTP
+`, 1),
+			want: []string{
+				`:32:9: configuration.kubeslice_configuration.service_exports[1].worker: worker "worker-2" is not part of slice "red"`,
+				`:40:13: configuration.kubeslice_configuration.service_exports[1].ports[0].protocol: unknown protocol: HTTP`,
+			},
+		},
+		{
+			name: "Service exports must be in a namespace onboarded on their worker",
+			topology: strings.Replace(validTopology, "    project_name: demo\n", `    project_name: demo
+    slices:
+      - name: red
+        slice_subnet: 10.1.0.0/16
+        application_namespaces:
+          - namespace: iperf
+            clusters: [worker-2]
+          - namespace: bookinfo
+            clusters: ["*"]
+    service_exports:
+      - name: iperf-server
+        worker: worker-1
+        namespace: iperf
+        slice: red
+        selector:
+          app: iperf-server
+        ports:
+          - container_port: 5201
+      - name: iperf-server
+        worker: worker-2
+        namespace: iperf
+        slice: red
+        selector:
+          app: iperf-server
+        ports:
+          - container_port: 5201
+      - name: productpage
+        worker: worker-1
+        namespace: bookinfo
+        slice: red
+        selector:
+          app: productpage
+        ports:
+          - container_port: 9080
+      - name: reviews
+        worker: worker-1
+        namespace: reviews
+        slice: red
+        selector:
+          app: reviews
+        ports:
+          - container_port: 9080
+`, 1),
+			want: []string{
+				`:26:9: configuration.kubeslice_configuration.service_exports[0].namespace: namespace "iperf" is not onboarded on worker "worker-1" by slice "red"`,
+				`:50:9: configuration.kubeslice_configuration.service_exports[3].namespace: namespace "reviews" is not onboarded on worker "worker-1" by slice "red"`,
+			},
+		},
+		{
+			name: "Values overrides are only read for workers",
+			topology: strings.Replace(strings.Replace(validTopology, "      context_name: ctrl\n", "      context_name: ctrl\n      helm_values:\n        replicas: 2\n", 1),
+				"        context_name: w2\n", "        context_name: w2\n        helm_values:\n          nodeSelector:\n            kubernetes.io/arch: arm64\n        values_files: [missing-values.yaml]\n", 1),
+			want: []string{
+				":8:7: configuration.cluster_configuration.controller.helm_values: is only supported on workers",
+				":18:24: configuration.cluster_configuration.workers[1].values_files[0]: cannot read values file",
+			},
+		},
+		{
+			name:     "Unsupported version",
+			topology: strings.Replace(validTopology, "v1beta1", "v2", 1),
+			want:     []string{`:1:1: apiVersion: unsupported topology version string "cli.kubeslice.io/v2", expected cli.kubeslice.io/v1beta1`},
+		},
+		{
+			name:     "Missing required chart is located at its parent",
+			topology: strings.Replace(validTopology, "    worker_chart:\n      chart_name: kubeslice-worker\n", "", 1),
+			want:     []string{":15:3: configuration.helm_chart_configuration.worker_chart: must be specified"},
+		},
+	}
+
+	for i, tc := range tests {
+		tc := tc // Capture range variable for parallel execution
+		fileName := filepath.Join(testDir, "topology-"+string(rune('a'+i))+".yaml")
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if err := os.WriteFile(fileName, []byte(tc.topology), 0644); err != nil {
+				t.Fatalf("Failed to setup test: %v", err)
+			}
+
+			errors := ValidateConfigurationFiles([]string{fileName})
+			got := make([]string, 0, len(errors))
+			for _, e := range errors {
+				got = append(got, e.Error())
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("ValidateConfigurationFiles() returned %d errors, want %d\n%s", len(got), len(tc.want), strings.Join(got, "\n"))
+			}
+			for i := range tc.want {
+				if !strings.HasPrefix(got[i], fileName) || !strings.Contains(got[i], tc.want[i]) {
+					t.Errorf("ValidateConfigurationFiles() error mismatch\nwant: %s%s\ngot:  %s", fileName, tc.want[i], got[i])
+				}
+			}
+		})
+	}
+}
+
+func TestValidateConfigurationFilesWithOverlays(t *testing.T) {
+	t.Parallel()
+
+	testDir := t.TempDir()
+	base := filepath.Join(testDir, "base.yaml")
+	if err := os.WriteFile(base, []byte(validTopology), 0644); err != nil {
+		t.Fatalf("Failed to setup test: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		overlay  string
+		files    func(overlay string) []string
+		want     string
+		rendered []string
+	}{
+		{
+			name:    "Workers are merged by name",
+			overlay: "configuration:\n  cluster_configuration:\n    workers:\n      - name: worker-2\n        context_name: w2-prod\n      - name: worker-3\n        context_name: w3\n",
+			files:   func(overlay string) []string { return []string{base, overlay} },
+			rendered: []string{
+				"- context_name: w1\n      name: worker-1\n",
+				"- context_name: w2-prod\n      name: worker-2\n",
+				"- context_name: w3\n      name: worker-3\n",
+			},
+		},
+		{
+			name:     "Base is loaded before the overlay",
+			overlay:  "apiVersion: cli.kubeslice.io/v1beta1\nbase: base.yaml\nconfiguration:\n  kubeslice_configuration:\n    project_name: staging\n",
+			files:    func(overlay string) []string { return []string{overlay} },
+			rendered: []string{"project_name: staging", "name: worker-1"},
+		},
+		{
+			name:    "Error is located in the overlay which sets the value",
+			overlay: "configuration:\n  cluster_configuration:\n    workers:\n      - name: worker-2\n        context_name: w2\n      - name: Worker_4\n        context_name: w4\n",
+			files:   func(overlay string) []string { return []string{base, overlay} },
+			want:    `:6:9: configuration.cluster_configuration.workers[2].name: "Worker_4" is not a valid cluster name`,
+		},
+		{
+			name:    "Base loops are reported",
+			overlay: "base: overlay-d.yaml\n",
+			files:   func(overlay string) []string { return []string{overlay} },
+			want:    ":1:1: base: overlay-d.yaml is included in a loop",
+		},
+	}
+
+	for i, tc := range tests {
+		tc := tc // Capture range variable for parallel execution
+		overlay := filepath.Join(testDir, "overlay-"+string(rune('a'+i))+".yaml")
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if err := os.WriteFile(overlay, []byte(tc.overlay), 0644); err != nil {
+				t.Fatalf("Failed to setup test: %v", err)
+			}
+
+			errors := ValidateConfigurationFiles(tc.files(overlay))
+			if tc.want != "" {
+				if len(errors) != 1 || !strings.HasPrefix(errors[0].Error(), overlay+tc.want) {
+					t.Fatalf("ValidateConfigurationFiles() = %v, want %s%s", errors, overlay, tc.want)
+				}
+				return
+			}
+			if len(errors) > 0 {
+				t.Fatalf("ValidateConfigurationFiles() returned unexpected errors %v", errors)
+			}
+			rendered, _ := RenderConfiguration(tc.files(overlay))
+			for _, want := range tc.rendered {
+				if !strings.Contains(string(rendered), want) {
+					t.Errorf("RenderConfiguration() does not contain %q\n%s", want, rendered)
+				}
+			}
+		})
+	}
+}
+
+func TestMigrateConfiguration(t *testing.T) {
+	t.Parallel()
+
+	fileName := filepath.Join(t.TempDir(), "topology.yaml")
+	legacy := "# demo topology\n" + strings.Replace(validTopology, "apiVersion: cli.kubeslice.io/v1beta1\n", "", 1)
+	legacy = strings.Replace(legacy, "project_name: demo", "project_name: demo # shared by all clusters", 1)
+	if err := os.WriteFile(fileName, []byte(legacy), 0644); err != nil {
+		t.Fatalf("Failed to setup test: %v", err)
+	}
+
+	MigrateConfiguration([]string{fileName}, "")
+
+	migrated, err := os.ReadFile(fileName)
+	if err != nil {
+		t.Fatalf("Failed to read migrated file: %v", err)
+	}
+	// only the apiVersion is added, every other line is kept as written
+	if want := "# demo topology\napiVersion: cli.kubeslice.io/v1beta1\n" + strings.TrimPrefix(legacy, "# demo topology\n"); string(migrated) != want {
+		t.Errorf("MigrateConfiguration() wrote\n%s\nwant\n%s", migrated, want)
+	}
+	if errors := ValidateConfigurationFiles([]string{fileName}); len(errors) > 0 {
+		t.Errorf("ValidateConfigurationFiles() returned errors for the migrated file %v", errors)
+	}
+
+	// an explicit older apiVersion is replaced in place
+	explicit := strings.Replace(validTopology, "apiVersion: cli.kubeslice.io/v1beta1\n", "apiVersion: \"cli.kubeslice.io/v1alpha1\" # old\n", 1)
+	if err := os.WriteFile(fileName, []byte(explicit), 0644); err != nil {
+		t.Fatalf("Failed to setup test: %v", err)
+	}
+	output := filepath.Join(filepath.Dir(fileName), "migrated.yaml")
+	if err := MigrateConfiguration([]string{fileName}, output); err != nil {
+		t.Fatalf("MigrateConfiguration() returned unexpected error %v", err)
+	}
+	migrated, err = os.ReadFile(output)
+	if want := strings.Replace(validTopology, "apiVersion: cli.kubeslice.io/v1beta1\n", "apiVersion: cli.kubeslice.io/v1beta1 # old\n", 1); err != nil || string(migrated) != want {
+		t.Errorf("MigrateConfiguration() --output wrote %s, %v, want\n%s", migrated, err, want)
+	}
+
+	// a current file is copied to the output as it is
+	if err := MigrateConfiguration([]string{output}, filepath.Join(filepath.Dir(fileName), "copy.yaml")); err != nil {
+		t.Fatalf("MigrateConfiguration() returned unexpected error %v", err)
+	}
+	copied, err := os.ReadFile(filepath.Join(filepath.Dir(fileName), "copy.yaml"))
+	if err != nil || string(copied) != string(migrated) {
+		t.Errorf("MigrateConfiguration() --output of a current file wrote %s, %v, want the file unchanged\n%s", copied, err, migrated)
+	}
+}
+
+const teamProfile = `description: Demo of the team
+skip_steps:
+  - ui
+demo:
+  apps: []
+next_steps: |
+  Run {{ kubectl (index .ClusterConfiguration.WorkerClusters 0) }} get pods
+topology:
+  configuration:
+    cluster_configuration:
+      controller:
+        name: team-ctrl
+      workers:
+        - name: team-w-1
+        - name: team-w-2
+    kubeslice_configuration:
+      project_name: team
+    helm_chart_configuration:
+      repo_alias: kubeslice-demo
+      repo_url: https://kubeslice.github.io/kubeslice/
+      cert_manager_chart:
+        chart_name: cert-manager
+      controller_chart:
+        chart_name: kubeslice-controller
+      worker_chart:
+        chart_name: kubeslice-worker
+`
+
+// not parallel, as the profiles directory is shared
+func TestProfiles(t *testing.T) {
+	ProfilesDirectory = t.TempDir()
+	t.Cleanup(func() { ProfilesDirectory = "" })
+	write := func(name, content string) string {
+		fileName := filepath.Join(ProfilesDirectory, name)
+		if err := os.WriteFile(fileName, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to setup test: %v", err)
+		}
+		return fileName
+	}
+	write("team-demo.yaml", teamProfile)
+	broken := write("broken-demo.yaml", strings.Replace(teamProfile, "  - ui", "  - dashboard", 1))
+	topology := write("topology.yaml", "apiVersion: cli.kubeslice.io/v1beta1\nconfiguration:\n  cluster_configuration:\n    profile: team-dmo\n")
+
+	specs, err := ReadAndValidateConfiguration(nil, "team-demo")
+	if err != nil {
+		t.Fatalf("ReadAndValidateConfiguration() returned unexpected error %v", err)
+	}
+	if specs.InstallProfile == nil || specs.InstallProfile.Name != "team-demo" {
+		t.Fatalf("ReadAndValidateConfiguration() did not load profile team-demo, got %v", specs.InstallProfile)
+	}
+	if got := specs.Configuration.KubeSliceConfiguration.ProjectName; got != "team" {
+		t.Errorf("ReadAndValidateConfiguration() project = %s, want team", got)
+	}
+	if got := specs.Configuration.ClusterConfiguration.ControllerCluster.ContextName; got != "kind-team-ctrl" {
+		t.Errorf("ReadAndValidateConfiguration() controller context = %s, want kind-team-ctrl", got)
+	}
+	for _, name := range []string{ProfileFullDemo, ProfileMinimalDemo, ProfileEntDemo} {
+		if profile, errors := internal.LoadProfile(ProfilesDirectory, name); profile == nil || profile.Source != "" {
+			t.Errorf("LoadProfile(%s) = %v, %v, want the built-in profile", name, profile, errors)
+		}
+	}
+
+	errors := ValidateConfigurationFiles([]string{topology})
+	want := topology + ":4:5: configuration.cluster_configuration.profile: unknown profile: team-dmo. Possible values [enterprise-demo full-demo minimal-demo team-demo]"
+	if len(errors) == 0 || errors[0].Error() != want {
+		t.Errorf("ValidateConfigurationFiles() = %v, want %s", errors, want)
+	}
+
+	write("topology.yaml", "apiVersion: cli.kubeslice.io/v1beta1\nconfiguration:\n  cluster_configuration:\n    profile: broken-demo\n")
+	errors = ValidateConfigurationFiles([]string{topology})
+	want = broken + ":3:5: skip_steps[0]: unknown step: dashboard"
+	if len(errors) == 0 || !strings.HasPrefix(errors[0].Error(), want) {
+		t.Errorf("ValidateConfigurationFiles() = %v, want %s", errors, want)
+	}
+}
+
+func TestSetKubeBackend(t *testing.T) {
+	t.Parallel()
+
+	err := SetKubeBackend("kubernetes")
+	if util.ExitCode(err) != util.ExitValidation {
+		t.Errorf("SetKubeBackend(kubernetes) = %v, want a validation error", err)
+	}
+}
+
+func TestSetHelmBackend(t *testing.T) {
+	t.Parallel()
+
+	err := SetHelmBackend("helm3")
+	if util.ExitCode(err) != util.ExitValidation {
+		t.Errorf("SetHelmBackend(helm3) = %v, want a validation error", err)
+	}
+}
+
+const twoContextsKubeconfig = `apiVersion: v1
+kind: Config
+current-context: ctx-a
+contexts:
+- name: ctx-a
+  context: {cluster: a, user: a}
+- name: ctx-b
+  context: {cluster: a, user: a}
+clusters:
+- name: a
+  cluster: {server: "https://127.0.0.1:6443"}
+users:
+- name: a
+  user: {token: x}
+`
+
+// not parallel, as it sets CliOptions, ApplicationConfiguration and the
+// KUBECONFIG environment variable
+func TestSetCliOptionsController(t *testing.T) {
+	dir := t.TempDir()
+	topology := filepath.Join(dir, "topology.yaml")
+	kubeconfig := filepath.Join(dir, "kubeconfig")
+	if err := os.WriteFile(topology, []byte(validTopology), 0644); err != nil {
+		t.Fatalf("Failed to setup test: %v", err)
+	}
+	if err := os.WriteFile(kubeconfig, []byte(twoContextsKubeconfig), 0644); err != nil {
+		t.Fatalf("Failed to setup test: %v", err)
+	}
+	t.Setenv("KUBECONFIG", kubeconfig)
+	t.Cleanup(func() { CliOptions, ApplicationConfiguration, util.ExecutablePaths = nil, nil, nil })
+
+	tests := []struct {
+		name           string
+		params         CliParams
+		wantKubeconfig string
+		wantContext    string
+		wantErr        bool
+	}{
+		{name: "current context", wantContext: "ctx-a"},
+		{name: "context", params: CliParams{ContextName: "ctx-b"}, wantContext: "ctx-b"},
+		{name: "kubeconfig", params: CliParams{KubeConfigPath: kubeconfig}, wantKubeconfig: kubeconfig, wantContext: "ctx-a"},
+		{name: "missing kubeconfig", params: CliParams{KubeConfigPath: filepath.Join(dir, "missing")}, wantErr: true},
+		{name: "config", params: CliParams{Config: []string{topology}}, wantKubeconfig: "/tmp/kubeconfig", wantContext: "ctrl"},
+		{name: "config and matching flags", params: CliParams{Config: []string{topology}, KubeConfigPath: "/tmp/kubeconfig", ContextName: "ctrl"}, wantKubeconfig: "/tmp/kubeconfig", wantContext: "ctrl"},
+		{name: "config and other context", params: CliParams{Config: []string{topology}, ContextName: "ctx-b"}, wantErr: true},
+		{name: "config and other kubeconfig", params: CliParams{Config: []string{topology}, KubeConfigPath: kubeconfig}, wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := SetCliOptions(tc.params)
+			if tc.wantErr {
+				if util.ExitCode(err) != util.ExitValidation {
+					t.Errorf("SetCliOptions() = %v, want a validation error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SetCliOptions() returned unexpected error %v", err)
+			}
+			cluster := CliOptions.Cluster
+			if cluster == nil || cluster.KubeConfigPath != tc.wantKubeconfig || cluster.ContextName != tc.wantContext {
+				t.Errorf("SetCliOptions() controller = %+v, want context %s of kubeconfig %q", cluster, tc.wantContext, tc.wantKubeconfig)
+			}
+		})
+	}
+}
+
+// not parallel, as it sets environment variables and the output
+func TestValidateEnterpriseCredentials(t *testing.T) {
+	t.Setenv("KUBESLICE_IMAGE_PULL_PASSWORD", "")
+	var b bytes.Buffer
+	util.SetOutput(&b)
+	t.Cleanup(func() { util.SetOutput(os.Stdout) })
+	profile, errors := internal.LoadProfile("", ProfileEntDemo)
+	if profile == nil {
+		t.Fatalf("Failed to setup test: %v", errors)
+	}
+	topology, err := yaml.Marshal(profile.DefaultTopology())
+	if err != nil {
+		t.Fatalf("Failed to setup test: %v", err)
+	}
+	fileName := filepath.Join(t.TempDir(), "topology.yaml")
+	if err := os.WriteFile(fileName, topology, 0644); err != nil {
+		t.Fatalf("Failed to setup test: %v", err)
+	}
+
+	if errors := ValidateConfigurationFiles([]string{fileName}); len(errors) > 0 {
+		t.Errorf("ValidateConfigurationFiles() without the image pull password = %v, want no errors", errors)
+	}
+	if want := "missing image pull secret password"; !strings.Contains(b.String(), want) {
+		t.Errorf("ValidateConfigurationFiles() printed %q, want a warning about the %s", b.String(), want)
+	}
+	if _, err := ReadAndValidateConfiguration([]string{fileName}, ""); util.ExitCode(err) != util.ExitValidation {
+		t.Errorf("ReadAndValidateConfiguration() without the image pull password = %v, want a ValidationError", err)
+	}
+}
+
+// not parallel, as it sets the output and ExecutablePaths
+func TestInitConfiguration(t *testing.T) {
+	var b bytes.Buffer
+	util.SetOutput(&b)
+	t.Cleanup(func() {
+		util.SetOutput(os.Stdout)
+		util.ExecutablePaths = nil
+	})
+
+	params := ConfigInitParams{
+		KubeConfigPaths:   []string{"internal/testdata/kubeconfig"},
+		ControllerContext: "kind-controller",
+		WorkerContexts:    []string{"kind-worker-1"},
+		ProjectName:       "Invalid_Project",
+		OutputFile:        filepath.Join(t.TempDir(), "topology.yaml"),
+		NonInteractive:    true,
+		SkipNetworkLookup: true,
+	}
+	if err := InitConfiguration(context.Background(), params); util.ExitCode(err) != util.ExitValidation || err == nil {
+		t.Errorf("InitConfiguration() with an invalid project = %v, want a ValidationError", err)
+	}
+	if _, err := os.Stat(params.OutputFile); !os.IsNotExist(err) {
+		t.Errorf("InitConfiguration() wrote %s, want an invalid topology not to be written", params.OutputFile)
+	}
+
+	params.ProjectName = "demo"
+	if err := InitConfiguration(context.Background(), params); err != nil {
+		t.Fatalf("InitConfiguration() returned unexpected error %v\n%s", err, b.String())
+	}
+	if errors := ValidateConfigurationFiles([]string{params.OutputFile}); len(errors) > 0 {
+		t.Errorf("ValidateConfigurationFiles() returned errors for the generated topology %v", errors)
+	}
+}
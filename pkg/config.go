@@ -0,0 +1,112 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/go-yaml/yaml"
+	"github.com/kubeslice/kubeslice-cli/pkg/internal"
+	"github.com/kubeslice/kubeslice-cli/util"
+)
+
+type ConfigInitParams struct {
+	KubeConfigPaths   []string // kubeconfig files to read contexts from
+	ControllerContext string   // context of the controller cluster
+	WorkerContexts    []string // contexts of the worker clusters
+	ProjectName       string
+	RepoUrl           string
+	ControllerVersion string
+	WorkerVersion     string
+	OutputFile        string // path of the generated topology file
+	NonInteractive    bool   // use flags and defaults only
+	SkipNetworkLookup bool   // do not contact clusters for addresses
+}
+
+func InitConfiguration(ctx context.Context, params ConfigInitParams) error {
+	options := &internal.ConfigInitOptions{
+		KubeConfigPaths:   params.KubeConfigPaths,
+		ControllerContext: params.ControllerContext,
+		WorkerContexts:    params.WorkerContexts,
+		ProjectName:       params.ProjectName,
+		RepoUrl:           params.RepoUrl,
+		ControllerVersion: params.ControllerVersion,
+		WorkerVersion:     params.WorkerVersion,
+		OutputFile:        params.OutputFile,
+		NonInteractive:    params.NonInteractive,
+		SkipNetworkLookup: params.SkipNetworkLookup,
+	}
+	util.ExecutablePaths = map[string]string{
+		"kubectl": "kubectl",
+	}
+	topology, err := internal.InitConfiguration(ctx, options)
+	if err != nil {
+		return err
+	}
+	// the topology is validated before it is written, so that a file which
+	// would fail config validate is never left behind
+	specs := &internal.ConfigurationSpecs{}
+	if err := yaml.Unmarshal([]byte(topology), specs); err != nil {
+		return fmt.Errorf("Failed to parse generated topology configuration %w", err)
+	}
+	if errors := validateConfiguration(specs); len(errors) > 0 {
+		for _, e := range errors {
+			util.Printf("%s %s", util.Cross, e)
+		}
+		return util.ValidationErrorf("Generated topology configuration is not valid, %s was not written", options.OutputFile)
+	}
+	if err := util.DumpFile(topology, options.OutputFile); err != nil {
+		return err
+	}
+	util.Printf("%s Generated topology configuration %s", util.Tick, options.OutputFile)
+	util.Printf("%s Validated %s, run `kubeslice-cli install --config=%s` to set up KubeSlice", util.Tick, options.OutputFile, options.OutputFile)
+	return nil
+}
+
+// MigrateConfiguration rewrites topology files written in an older version to
+// the current one. Files are rewritten in place unless output is set, "-"
+// printing the migrated file instead.
+func MigrateConfiguration(fileNames []string, output string) error {
+	if output != "" && len(fileNames) > 1 {
+		return util.ValidationErrorf("--output can only be used when migrating a single file")
+	}
+	for _, fileName := range fileNames {
+		data, err := ioutil.ReadFile(fileName)
+		if os.IsNotExist(err) {
+			return util.NotFoundErrorf("Failed to read configuration file %w", err)
+		} else if err != nil {
+			return fmt.Errorf("Failed to read configuration file %w", err)
+		}
+		doc, err := internal.ParseConfigDocument(fileName, data)
+		if err != nil {
+			return util.ValidationErrorf("%w", err)
+		}
+		from := doc.APIVersion
+		migrated, err := doc.Migrate()
+		if err != nil {
+			return util.ValidationErrorf("Failed to migrate %s: %w", fileName, err)
+		}
+		if output == "-" {
+			os.Stdout.Write(doc.Data)
+			continue
+		}
+		if !migrated && output == "" {
+			util.Printf("%s %s already uses topology version %s", util.Tick, fileName, internal.ConfigAPIVersion)
+			continue
+		}
+		target := fileName
+		if output != "" {
+			target = output
+		}
+		if err := ioutil.WriteFile(target, doc.Data, 0644); err != nil {
+			return fmt.Errorf("Failed to write %s: %w", target, err)
+		}
+		if !migrated {
+			util.Printf("%s %s already uses topology version %s, copied it to %s", util.Tick, fileName, internal.ConfigAPIVersion, target)
+			continue
+		}
+		util.Printf("%s Migrated %s from %s to %s", util.Tick, target, from, internal.ConfigAPIVersion)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kubeslice/kubeslice-cli/pkg/internal"
+	"github.com/kubeslice/kubeslice-cli/util"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// fakeClusters scripts clusters that are ready as soon as they are asked:
+// the workers get their secrets, slices come up and pods are running.
+func fakeClusters() *util.FakeExecutor {
+	notFound := util.FakeResponse{Stderr: "Error from server (NotFound): not found", Err: errors.New("exit status 1")}
+	f := &util.FakeExecutor{}
+	f.Respond("docker inspect", util.FakeResponse{Stdout: "172.18.0.2"})
+	f.Respond("config view --minify=true", util.FakeResponse{Stdout: "https://10.0.0.1:6443"})
+	f.Respond(" get nodes -o jsonpath={\"ExternalIP=\"}", util.FakeResponse{Stdout: "ExternalIP=\nInternalIP=10.0.0.2"})
+	f.Respond(" get nodes -o jsonpath='", util.FakeResponse{Stdout: "'10.0.0.2'"})
+	f.Respond(" get namespace calico-system", notFound)
+	f.Respond(" get serviceaccounts -o json", util.FakeResponse{Stdout: `{"items":[
+		{"metadata":{"name":"kubeslice-rbac-worker-ks-w-1"}},{"metadata":{"name":"kubeslice-rbac-worker-ks-w-2"}},
+		{"metadata":{"name":"kubeslice-rbac-worker-eks-worker-1"}},{"metadata":{"name":"kubeslice-rbac-worker-gke-worker-2"}},
+		{"metadata":{"name":"kubeslice-rbac-worker-gke-worker-3"}},{"metadata":{"name":"kubeslice-rbac-worker-gke-worker-4"}},
+		{"metadata":{"name":"kubeslice-rbac-worker-aks-worker-1"}},{"metadata":{"name":"kubeslice-rbac-rw-admin"}}]}`})
+	f.Respond(" get secrets ", util.FakeResponse{Stdout: `{"metadata":{"name":"worker-secret"},
+		"data":{"namespace":"a3ViZXNsaWNlLWRlbW8=","controllerEndpoint":"aHR0cHM6Ly8xMC4wLjAuMTo2NDQz","ca.crt":"Y2E=","token":"dG9rZW4="}}`})
+	f.Respond(" get sliceconfigs.controller.kubeslice.io ", notFound)
+	f.Respond(" get clusters.controller.kubeslice.io ", util.FakeResponse{Stdout: `{"status":{"nodeIPs":["10.0.0.2"]}}`})
+	f.Respond(" get workersliceconfigs.worker.kubeslice.io ", util.FakeResponse{Stdout: `{"items":[
+		{"status":{"sliceHealth":{"sliceHealthStatus":"Normal"}}},{"status":{"sliceHealth":{"sliceHealthStatus":"Normal"}}}]}`})
+	f.Respond(" get workerslicegateways.worker.kubeslice.io ", util.FakeResponse{Stdout: `{"items":[{},{}]}`})
+	f.Respond(" get slices.networking.kubeslice.io ", util.FakeResponse{Stdout: `{"status":{"sliceConfig":{"sliceSubnet":"10.1.0.0/16"}}}`})
+	f.Respond(" get deployment ", util.FakeResponse{Stdout: `{"kind":"Deployment","spec":{"replicas":1},
+		"status":{"replicas":1,"updatedReplicas":1,"availableReplicas":1}}`})
+	f.Respond(" get services kubeslice-ui-proxy ", util.FakeResponse{Stdout: "31000"})
+	f.Respond(" list --all --all-namespaces -o json", util.FakeResponse{Stdout: "[]"})
+	f.Respond(" get pods -o json", util.FakeResponse{Stdout: `{"items":[]}`})
+	f.Respond(" get deployments -o json", util.FakeResponse{Stdout: `{"items":[]}`})
+	f.Respond(" get daemonsets -o json", util.FakeResponse{Stdout: `{"items":[]}`})
+	f.Respond(" -o json", util.FakeResponse{Stdout: `{}`})
+	return f
+}
+
+// TestInstallCommands records the commands of the kubectl and helm binary
+// backends only. The default client-go and Helm SDK backends do not go
+// through the executor, so the golden files do not cover them: their calls
+// are tested against fake clusters in the kube-client and helm-client tests
+// of package internal, not as a whole install.
+//
+// not parallel, as it sets the shared application configuration, executor
+// and output, and changes the working directory
+func TestInstallCommands(t *testing.T) {
+	tests := []struct {
+		name     string
+		profile  string
+		topology string
+	}{
+		{name: "full-demo", profile: "full-demo"},
+		{name: "minimal-demo", profile: "minimal-demo"},
+		{name: "enterprise-demo", profile: "enterprise-demo"},
+		{name: "custom-topology", topology: "../samples/custom-topology.yaml"},
+		// the demo slice is applied along with the declared slices
+		{name: "full-demo-slices", topology: "testdata/full-demo-slices.yaml"},
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to setup test: %v", err)
+	}
+	kubeBackend, helmBackend, waitInterval := internal.KubeBackend, internal.HelmBackend, internal.WaitInterval
+	internal.KubeBackend, internal.HelmBackend = internal.KubeBackendKubectl, internal.HelmBackendBinary
+	t.Setenv("KUBESLICE_IMAGE_PULL_USERNAME", "user")
+	t.Setenv("KUBESLICE_IMAGE_PULL_PASSWORD", "password")
+	var b bytes.Buffer
+	util.SetOutput(&b)
+	t.Cleanup(func() {
+		os.Chdir(wd)
+		internal.KubeBackend, internal.HelmBackend, internal.WaitInterval = kubeBackend, helmBackend, waitInterval
+		ApplicationConfiguration, util.ExecutablePaths = nil, nil
+		util.SetOutput(os.Stdout)
+	})
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var topology []string
+			if tc.topology != "" {
+				topology = []string{filepath.Join(wd, tc.topology)}
+			}
+			if err := os.Chdir(t.TempDir()); err != nil {
+				t.Fatalf("Failed to setup test: %v", err)
+			}
+			ApplicationConfiguration, err = ReadAndValidateConfiguration(topology, tc.profile)
+			if err != nil {
+				t.Fatalf("ReadAndValidateConfiguration() returned unexpected error %v", err)
+			}
+			fake := fakeClusters()
+			defer util.SetExecutor(util.SetExecutor(fake))
+			b.Reset()
+
+			params := InstallParams{SkipSteps: map[string]string{}, PollInterval: time.Millisecond}
+			if err := Install(context.Background(), params); err != nil {
+				t.Fatalf("Install() returned unexpected error %v\n%s", err, b.String())
+			}
+
+			got := strings.Join(fake.Commands(), "\n") + "\n"
+			golden := filepath.Join(wd, "testdata", "install-commands", tc.name+".golden")
+			if *update {
+				if err := ioutil.WriteFile(golden, []byte(got), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := ioutil.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("Failed to read golden file, run go test ./pkg -update to create it: %v", err)
+			}
+			if got != string(want) {
+				t.Errorf("Install() ran\n%s\nwant the commands of %s", got, golden)
+			}
+		})
+	}
+}
@@ -0,0 +1,462 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kubeslice/kubeslice-cli/pkg/internal"
+	"github.com/kubeslice/kubeslice-cli/util"
+)
+
+// installStep is a named unit of the install, run after the steps it
+// depends on.
+type installStep struct {
+	name        string
+	description string
+	dependsOn   []string
+	// partOf is the step this step was part of before it had a name of its
+	// own, skipping that step skips this one too as it always did
+	partOf string
+	// implicit steps only gather state for the steps depending on them, they
+	// run whenever one of those does
+	implicit bool
+	// applies tells whether the step has anything to do for the topology,
+	// nil meaning it always does
+	applies func() bool
+	// inputs are the parts of the topology the step uses, a step is run
+	// again on resume when they change
+	inputs func() []interface{}
+	// chart is the chart the step installs, if any
+	chart func() internal.HelmChart
+	// record keeps what the step discovered in the install state, and
+	// restore sets it back on resume, returning false if it is missing
+	record  func(state *internal.InstallState)
+	restore func(state *internal.InstallState) bool
+	run     func(ctx context.Context) error
+	// timeout bounds the run of the step, zero for none
+	timeout time.Duration
+}
+
+// runWithTimeout runs the step, stopping it when it takes longer than its
+// timeout.
+func (s *installStep) runWithTimeout(ctx context.Context) error {
+	if s.timeout <= 0 {
+		return s.run(ctx)
+	}
+	stepCtx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	err := s.run(stepCtx)
+	if err != nil && ctx.Err() == nil && stepCtx.Err() != nil {
+		return util.TimeoutErrorf("Step %s did not finish within %s\n%w", s.name, s.timeout, err)
+	}
+	return err
+}
+
+func (s *installStep) applicable() bool {
+	return s.applies == nil || s.applies()
+}
+
+func (s *installStep) inputHash() (string, error) {
+	if s.inputs == nil {
+		return internal.InputHash()
+	}
+	return internal.InputHash(s.inputs()...)
+}
+
+// installSteps returns the install steps in the order they run, every step
+// coming after the steps it depends on.
+func installSteps() []*installStep {
+	cc := func() *internal.ClusterConfiguration {
+		return &ApplicationConfiguration.Configuration.ClusterConfiguration
+	}
+	ksc := func() *internal.KubeSliceConfiguration {
+		return &ApplicationConfiguration.Configuration.KubeSliceConfiguration
+	}
+	hc := func() *internal.HelmChartConfiguration {
+		return &ApplicationConfiguration.Configuration.HelmChartConfiguration
+	}
+	clusterNames := func() []interface{} {
+		names := []interface{}{cc().ControllerCluster.Name}
+		for _, cluster := range cc().WorkerClusters {
+			names = append(names, cluster.Name)
+		}
+		return names
+	}
+	return []*installStep{
+		{
+			name:        internal.Kind_Component,
+			description: "Creates the kind clusters of a demo profile",
+			applies:     func() bool { return cc().Profile != "" },
+			inputs:      func() []interface{} { return append(clusterNames(), ApplicationConfiguration.Enterprise()) },
+			run: func(ctx context.Context) error {
+				if err := internal.GenerateKindConfiguration(ApplicationConfiguration); err != nil {
+					return err
+				}
+				return internal.CreateKindClusters(ctx, ApplicationConfiguration)
+			},
+		},
+		{
+			name:        internal.Calico_Component,
+			description: "Installs Calico on kind clusters",
+			dependsOn:   []string{internal.Kind_Component},
+			applies:     func() bool { return cc().Profile != "" || cc().ClusterType == ClusterTypeKind },
+			inputs:      clusterNames,
+			run:         func(ctx context.Context) error { return internal.InstallCalico(ctx, cc()) },
+		},
+		{
+			name:        internal.NetworkInfo_Component,
+			description: "Fetches the control plane address and node IP of the clusters",
+			dependsOn:   []string{internal.Kind_Component},
+			implicit:    true,
+			inputs:      func() []interface{} { return []interface{}{cc().ControllerCluster, cc().WorkerClusters} },
+			record:      func(state *internal.InstallState) { state.RecordNetworkInformation(cc()) },
+			restore:     func(state *internal.InstallState) bool { return state.RestoreNetworkInformation(cc()) },
+			run: func(ctx context.Context) error {
+				return internal.GatherNetworkInformation(ctx, ApplicationConfiguration)
+			},
+		},
+		{
+			name:        internal.HelmRepo_Component,
+			description: "Adds the KubeSlice helm repository",
+			implicit:    true,
+			inputs:      func() []interface{} { return []interface{}{hc().RepoAlias, hc().RepoUrl} },
+			run:         func(ctx context.Context) error { return internal.AddHelmCharts(ctx, ApplicationConfiguration) },
+		},
+		{
+			name:        internal.CertManager_Component,
+			description: "Installs Cert-Manager on the controller cluster",
+			dependsOn:   []string{internal.Calico_Component, internal.HelmRepo_Component},
+			partOf:      internal.Controller_Component,
+			inputs:      func() []interface{} { return []interface{}{hc().CertManagerChart} },
+			chart:       func() internal.HelmChart { return hc().CertManagerChart },
+			run:         func(ctx context.Context) error { return internal.InstallCertManager(ctx, ApplicationConfiguration) },
+		},
+		{
+			name:        internal.Controller_Component,
+			description: "Installs the KubeSlice Controller",
+			dependsOn:   []string{internal.Calico_Component, internal.NetworkInfo_Component, internal.HelmRepo_Component},
+			inputs: func() []interface{} {
+				return []interface{}{hc().ControllerChart, hc().ImagePullSecret, cc().ControllerCluster}
+			},
+			chart: func() internal.HelmChart { return hc().ControllerChart },
+			run: func(ctx context.Context) error {
+				return internal.InstallKubeSliceController(ctx, ApplicationConfiguration)
+			},
+		},
+		{
+			name:        internal.Project_Component,
+			description: "Creates the KubeSlice project",
+			dependsOn:   []string{internal.Controller_Component},
+			partOf:      internal.Controller_Component,
+			inputs:      func() []interface{} { return []interface{}{ksc().ProjectName} },
+			run: func(ctx context.Context) error {
+				return internal.CreateKubeSliceProject(ctx, ApplicationConfiguration, nil)
+			},
+		},
+		{
+			name:        internal.UI_install_Component,
+			description: "Installs the enterprise UI components (Kubeslice-Manager)",
+			dependsOn:   []string{internal.Project_Component},
+			inputs:      func() []interface{} { return []interface{}{hc().UIChart, hc().ImagePullSecret} },
+			chart:       func() internal.HelmChart { return hc().UIChart },
+			run:         func(ctx context.Context) error { return internal.InstallKubeSliceUI(ctx, ApplicationConfiguration) },
+		},
+		{
+			name:        internal.Worker_registration_Component,
+			description: "Registers the KubeSlice Workers on the Controller",
+			dependsOn:   []string{internal.NetworkInfo_Component, internal.Project_Component},
+			inputs:      func() []interface{} { return []interface{}{cc().WorkerClusters} },
+			run: func(ctx context.Context) error {
+				return internal.RegisterWorkerClusters(ctx, ApplicationConfiguration, nil)
+			},
+		},
+		{
+			name:        internal.Worker_Component,
+			description: "Installs the KubeSlice Worker",
+			dependsOn:   []string{internal.Calico_Component, internal.HelmRepo_Component, internal.Worker_registration_Component},
+			inputs: func() []interface{} {
+				return []interface{}{hc().WorkerChart, hc().ImagePullSecret, cc().WorkerClusters}
+			},
+			chart: func() internal.HelmChart { return hc().WorkerChart },
+			run:   func(ctx context.Context) error { return internal.InstallKubeSliceWorker(ctx, ApplicationConfiguration) },
+		},
+		{
+			name:        internal.Prometheus_Component,
+			description: "Installs Prometheus on the worker clusters",
+			dependsOn:   []string{internal.Calico_Component, internal.HelmRepo_Component},
+			applies: func() bool {
+				return hc().PrometheusChart.ChartName != ""
+			},
+			inputs: func() []interface{} { return []interface{}{hc().PrometheusChart} },
+			chart:  func() internal.HelmChart { return hc().PrometheusChart },
+			run:    func(ctx context.Context) error { return internal.InstallPrometheus(ctx, ApplicationConfiguration) },
+		},
+		{
+			name:        internal.Slice_Component,
+			description: "Applies the slices declared in the topology",
+			dependsOn:   []string{internal.Worker_Component},
+			applies: func() bool {
+				return len(ksc().Slices) > 0
+			},
+			inputs: func() []interface{} { return []interface{}{ksc().Slices} },
+			run: func(ctx context.Context) error {
+				if err := internal.GenerateSliceConfiguration(ApplicationConfiguration, nil, "", ""); err != nil {
+					return err
+				}
+				return internal.ApplySliceConfiguration(ctx, ApplicationConfiguration, internal.ConfiguredSlices(ApplicationConfiguration))
+			},
+		},
+		{
+			name:        internal.Demo_Component,
+			description: "Installs the example applications of a demo profile",
+			dependsOn:   []string{internal.Worker_Component},
+			applies:     func() bool { return ApplicationConfiguration.InstallProfile != nil },
+			inputs:      func() []interface{} { return []interface{}{string(ApplicationConfiguration.InstallProfile.Data)} },
+			run:         func(ctx context.Context) error { return demo(ctx, ApplicationConfiguration.InstallProfile) },
+		},
+		{
+			// service exports can use the demo slice, so they come last
+			name:        internal.ServiceExport_Component,
+			description: "Applies the service exports declared in the topology",
+			dependsOn:   []string{internal.Worker_Component},
+			applies: func() bool {
+				return len(ksc().ServiceExports) > 0
+			},
+			inputs: func() []interface{} { return []interface{}{ksc().ServiceExports} },
+			run: func(ctx context.Context) error {
+				if err := internal.GenerateServiceExportManifests(ApplicationConfiguration); err != nil {
+					return err
+				}
+				return internal.ApplyServiceExportManifests(ctx, ApplicationConfiguration)
+			},
+		},
+	}
+}
+
+// skippedSteps tells which steps skipSteps skips, a step being skipped along
+// with the step it is part of.
+func skippedSteps(steps []*installStep, skipSteps map[string]string) map[string]bool {
+	skipped := make(map[string]bool)
+	for _, step := range steps {
+		_, skip := skipSteps[step.name]
+		_, skipWhole := skipSteps[step.partOf]
+		skipped[step.name] = skip || step.partOf != "" && skipWhole
+	}
+	return skipped
+}
+
+// selectInstallSteps returns the steps to run for params, in order, along with
+// warnings about steps which will run without the steps they depend on.
+func selectInstallSteps(steps []*installStep, params InstallParams) ([]*installStep, []string, error) {
+	index := make(map[string]int)
+	names := make([]string, 0, len(steps))
+	for i, step := range steps {
+		index[step.name] = i
+		names = append(names, step.name)
+	}
+	lookup := func(flag, name string) (int, error) {
+		i, ok := index[name]
+		if !ok {
+			return 0, fmt.Errorf("unknown step %s passed to %s. Possible values %s", name, flag, names)
+		}
+		return i, nil
+	}
+	for name := range params.StepTimeouts {
+		if _, err := lookup("--step-timeout", name); err != nil {
+			return nil, nil, err
+		}
+	}
+	if len(params.Only) > 0 && (params.From != "" || params.Until != "") {
+		return nil, nil, fmt.Errorf("--only cannot be used with --from or --until")
+	}
+	warnings := make([]string, 0)
+	unknown := make([]string, 0)
+	for name := range params.SkipSteps {
+		if _, ok := index[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		warnings = append(warnings, fmt.Sprintf("ignoring unknown step %s passed to --skip", name))
+	}
+	skipped := skippedSteps(steps, params.SkipSteps)
+
+	selected := make(map[string]bool)
+	explicit := make(map[string]bool)
+	if len(params.Only) > 0 {
+		for _, name := range params.Only {
+			if _, err := lookup("--only", name); err != nil {
+				return nil, nil, err
+			}
+			selected[name] = true
+			explicit[name] = true
+		}
+	} else {
+		from, until := 0, len(steps)-1
+		var err error
+		if params.From != "" {
+			if from, err = lookup("--from", params.From); err != nil {
+				return nil, nil, err
+			}
+		}
+		if params.Until != "" {
+			if until, err = lookup("--until", params.Until); err != nil {
+				return nil, nil, err
+			}
+		}
+		if from > until {
+			return nil, nil, fmt.Errorf("--from %s comes after --until %s", params.From, params.Until)
+		}
+		for _, step := range steps[from : until+1] {
+			selected[step.name] = true
+		}
+		for _, name := range []string{params.From, params.Until} {
+			if name != "" {
+				explicit[name] = true
+			}
+		}
+	}
+	for _, step := range steps {
+		if !selected[step.name] {
+			continue
+		}
+		if skip := skipped[step.name]; skip || !step.applicable() {
+			if explicit[step.name] && !skip {
+				warnings = append(warnings, fmt.Sprintf("step %s does not apply to this topology", step.name))
+			}
+			delete(selected, step.name)
+		}
+	}
+	// pull in the implicit steps the selected steps depend on, dependencies
+	// always come first so a single backward pass is enough
+	for i := len(steps) - 1; i >= 0; i-- {
+		if !selected[steps[i].name] {
+			continue
+		}
+		for _, dependency := range steps[i].dependsOn {
+			step := steps[index[dependency]]
+			if step.implicit && step.applicable() && !skipped[step.name] {
+				selected[step.name] = true
+			}
+		}
+	}
+
+	result := make([]*installStep, 0, len(selected))
+	for _, step := range steps {
+		if !selected[step.name] {
+			continue
+		}
+		result = append(result, step)
+		for _, dependency := range step.dependsOn {
+			if !selected[dependency] && steps[index[dependency]].applicable() {
+				warnings = append(warnings, fmt.Sprintf("step %s depends on %s, which will not run. Make sure it has already been done", step.name, dependency))
+			}
+		}
+	}
+	return result, warnings, nil
+}
+
+// runInstallSteps runs the steps in order, recording each finished step in
+// the install state. On resume, the steps which finished with the same inputs
+// are not run again, unless a step they depend on is. The steps are recorded
+// in journal as well when the install is atomic.
+func runInstallSteps(ctx context.Context, steps []*installStep, resume bool, journal *internal.InstallJournal) error {
+	state, err := internal.LoadInstallState()
+	if err != nil {
+		if resume {
+			return fmt.Errorf("Unable to resume the install %w", err)
+		}
+		util.Printf("%s Ignoring the previous install state %v", util.Warn, err)
+		state = internal.NewInstallState()
+	}
+	hashes := make(map[string]string)
+	rerun := make(map[string]bool)
+	finished, stale := make([]string, 0), make([]string, 0)
+	// hash before restoring anything, the restored values are not inputs
+	for _, step := range steps {
+		if hashes[step.name], err = step.inputHash(); err != nil {
+			return err
+		}
+	}
+	for _, step := range steps {
+		run := !resume || !state.Finished(step.name, hashes[step.name])
+		for _, dependency := range step.dependsOn {
+			run = run || rerun[dependency]
+		}
+		if !run && step.restore != nil && !step.restore(state) {
+			run = true
+		}
+		switch {
+		case run && resume && state.Steps[step.name] != nil:
+			stale = append(stale, step.name)
+		case !run:
+			finished = append(finished, step.name)
+		}
+		rerun[step.name] = run
+	}
+	if resume {
+		if len(state.Steps) == 0 {
+			util.Printf("%s No install state found in %s, starting from the first step", util.Warn, internal.InstallStatePath)
+		}
+		if len(finished) > 0 {
+			util.Printf("%s Resuming install, skipping finished steps: %s", util.Tick, strings.Join(finished, ", "))
+		}
+		if len(stale) > 0 {
+			util.Printf("%s The topology changed since the last install, running again: %s", util.Warn, strings.Join(stale, ", "))
+		}
+	}
+
+	for _, step := range steps {
+		if !rerun[step.name] {
+			continue
+		}
+		if journal != nil {
+			journal.BeginStep(step.name)
+		}
+		before := internal.WorkingDirectoryFiles()
+		if err := util.RunStep(ctx, step.name, step.runWithTimeout); err != nil {
+			if ctx.Err() != nil && journal == nil {
+				util.Printf("%s Install stopped during step %s, the finished steps are recorded in %s. Run install with --resume to continue", util.Warn, step.name, internal.InstallStatePath)
+			}
+			return err
+		}
+		if step.record != nil {
+			step.record(state)
+		}
+		if step.chart != nil {
+			state.RecordChart(step.name, step.chart())
+		}
+		state.Finish(step.name, hashes[step.name], internal.ChangedFiles(before))
+		if err := state.Save(); err != nil {
+			util.Printf("%s Failed to save the install state to %s %v", util.Warn, internal.InstallStatePath, err)
+		}
+	}
+	return nil
+}
+
+// ListInstallSteps prints the install steps in the order they run.
+func ListInstallSteps() {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "NAME\tDEPENDS ON\tDESCRIPTION")
+	for _, step := range installSteps() {
+		dependsOn := strings.Join(step.dependsOn, ",")
+		if dependsOn == "" {
+			dependsOn = "-"
+		}
+		description := step.description
+		if step.implicit {
+			description += " (runs with the steps depending on it)"
+		}
+		if step.partOf != "" {
+			description += fmt.Sprintf(" (skipped by --skip %s too)", step.partOf)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", step.name, dependsOn, description)
+	}
+	w.Flush()
+}
@@ -0,0 +1,205 @@
+package pkg
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kubeslice/kubeslice-cli/pkg/internal"
+	"github.com/kubeslice/kubeslice-cli/util"
+)
+
+// not parallel, as the steps read the shared application configuration
+func TestSelectInstallSteps(t *testing.T) {
+	ApplicationConfiguration = &internal.ConfigurationSpecs{}
+	ApplicationConfiguration.Configuration.ClusterConfiguration.ClusterType = ClusterTypeKind
+	t.Cleanup(func() { ApplicationConfiguration = nil })
+
+	tests := []struct {
+		name     string
+		params   InstallParams
+		want     []string
+		warnings []string
+		err      string
+	}{
+		{
+			name:   "Steps which do not apply to the topology are left out",
+			params: InstallParams{SkipSteps: map[string]string{}},
+			want:   []string{"calico", "network-info", "helm-repo", "cert-manager", "controller", "project", "ui", "worker-registration", "worker"},
+		},
+		{
+			name:     "Only pulls in the implicit steps",
+			params:   InstallParams{Only: []string{"worker"}},
+			want:     []string{"helm-repo", "worker"},
+			warnings: []string{"step worker depends on calico", "step worker depends on worker-registration"},
+		},
+		{
+			name:     "From and until",
+			params:   InstallParams{From: "controller", Until: "project"},
+			want:     []string{"network-info", "helm-repo", "controller", "project"},
+			warnings: []string{"step controller depends on calico"},
+		},
+		{
+			name:     "Skipping a step the others depend on",
+			params:   InstallParams{From: "controller", Until: "worker-registration", SkipSteps: map[string]string{"project": ""}},
+			want:     []string{"network-info", "helm-repo", "controller", "ui", "worker-registration"},
+			warnings: []string{"step controller depends on calico", "step ui depends on project", "step worker-registration depends on project"},
+		},
+		{
+			name:     "Skipping the controller skips cert-manager and the project too",
+			params:   InstallParams{SkipSteps: map[string]string{"controller": ""}},
+			want:     []string{"calico", "network-info", "helm-repo", "ui", "worker-registration", "worker"},
+			warnings: []string{"step ui depends on project", "step worker-registration depends on project"},
+		},
+		{
+			name:   "From after until",
+			params: InstallParams{From: "worker", Until: "controller"},
+			err:    "--from worker comes after --until controller",
+		},
+		{
+			name:   "Unknown step",
+			params: InstallParams{Only: []string{"workers"}},
+			err:    "unknown step workers passed to --only",
+		},
+		{
+			name:   "Timeout of an unknown step",
+			params: InstallParams{StepTimeouts: map[string]time.Duration{"workers": time.Minute}},
+			err:    "unknown step workers passed to --step-timeout",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			steps, warnings, err := selectInstallSteps(installSteps(), tc.params)
+			if tc.err != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), tc.err) {
+					t.Fatalf("selectInstallSteps() error = %v, want %s", err, tc.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("selectInstallSteps() returned unexpected error %v", err)
+			}
+			got := make([]string, 0, len(steps))
+			for _, step := range steps {
+				got = append(got, step.name)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("selectInstallSteps() = %v, want %v", got, tc.want)
+			}
+			if len(warnings) != len(tc.warnings) {
+				t.Fatalf("selectInstallSteps() warnings = %v, want %v", warnings, tc.warnings)
+			}
+			for i := range tc.warnings {
+				if !strings.HasPrefix(warnings[i], tc.warnings[i]) {
+					t.Errorf("selectInstallSteps() warning = %s, want %s", warnings[i], tc.warnings[i])
+				}
+			}
+		})
+	}
+}
+
+// not parallel, as it changes the working directory
+func TestResumeInstall(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to setup test: %v", err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("Failed to setup test: %v", err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	if err := os.Mkdir("kubeslice", 0755); err != nil {
+		t.Fatalf("Failed to setup test: %v", err)
+	}
+
+	inputs := map[string]string{"a": "1", "b": "1", "c": "1"}
+	var ran []string
+	step := func(name string, dependsOn ...string) *installStep {
+		return &installStep{
+			name:      name,
+			dependsOn: dependsOn,
+			inputs:    func() []interface{} { return []interface{}{inputs[name]} },
+			run:       func(ctx context.Context) error { ran = append(ran, name); return nil },
+		}
+	}
+	steps := []*installStep{step("a"), step("b", "a"), step("c", "b")}
+
+	tests := []struct {
+		name   string
+		steps  []*installStep
+		resume bool
+		change string
+		want   []string
+	}{
+		{
+			name:  "Interrupted install",
+			steps: steps[:2],
+			want:  []string{"a", "b"},
+		},
+		{
+			name:   "Resume runs the unfinished steps",
+			steps:  steps,
+			resume: true,
+			want:   []string{"c"},
+		},
+		{
+			name:   "Changed steps run again along with the steps depending on them",
+			steps:  steps,
+			resume: true,
+			change: "b",
+			want:   []string{"b", "c"},
+		},
+		{
+			name:  "Without resume every step runs",
+			steps: steps,
+			want:  []string{"a", "b", "c"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.change != "" {
+				inputs[tc.change] = "2"
+			}
+			ran = nil
+			if err := runInstallSteps(context.Background(), tc.steps, tc.resume, nil); err != nil {
+				t.Fatalf("runInstallSteps() returned unexpected error %v", err)
+			}
+			if !reflect.DeepEqual(ran, tc.want) {
+				t.Errorf("runInstallSteps() ran %v, want %v", ran, tc.want)
+			}
+		})
+	}
+}
+
+func TestInstallStepTimeout(t *testing.T) {
+	t.Parallel()
+
+	step := &installStep{
+		name:    "worker",
+		timeout: 10 * time.Millisecond,
+		run: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	}
+	err := step.runWithTimeout(context.Background())
+	if got := util.ExitCode(err); got != util.ExitTimeout {
+		t.Errorf("runWithTimeout() = %v, exit code %d, want %d", err, got, util.ExitTimeout)
+	}
+	if err == nil || !strings.HasPrefix(err.Error(), "Step worker did not finish within 10ms") {
+		t.Errorf("runWithTimeout() = %v, want the step timing out", err)
+	}
+
+	// the run being interrupted is not the step timing out
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = step.runWithTimeout(ctx)
+	if got := util.ExitCode(err); got != util.ExitInterrupted {
+		t.Errorf("runWithTimeout() = %v, exit code %d, want %d", err, got, util.ExitInterrupted)
+	}
+}
@@ -1,7 +1,19 @@
 package internal
 
 type ConfigurationSpecs struct {
+	// APIVersion is the version of the topology format, see ConfigAPIVersion
+	APIVersion string `yaml:"apiVersion"`
+	// Base is a topology file this file is merged over
+	Base          string        `yaml:"base"`
 	Configuration Configuration `yaml:"configuration"`
+	// InstallProfile is the profile being installed, if any
+	InstallProfile *InstallProfile `yaml:"-"`
+}
+
+// Enterprise tells whether the profile being installed is a KubeSlice
+// Enterprise one.
+func (c *ConfigurationSpecs) Enterprise() bool {
+	return c.InstallProfile != nil && c.InstallProfile.Enterprise
 }
 
 type Configuration struct {
@@ -34,6 +46,53 @@ type HelmChart struct {
 type KubeSliceConfiguration struct {
 	ProjectName  string   `yaml:"project_name"`
 	ProjectUsers []string `yaml:"project_users"`
+	Slices       []Slice  `yaml:"slices"`
+	// ServiceExports are applied to their worker once their slice is ready
+	ServiceExports []ServiceExport `yaml:"service_exports"`
+}
+
+type Slice struct {
+	Name             string `yaml:"name"`
+	SliceSubnet      string `yaml:"slice_subnet"`
+	SliceGatewayType string `yaml:"slice_gateway_type"`
+	// Worker clusters connected by the slice, all workers when empty
+	Clusters              []string               `yaml:"clusters"`
+	QosProfile            QosProfile             `yaml:"qos_profile"`
+	ApplicationNamespaces []ApplicationNamespace `yaml:"application_namespaces"`
+}
+
+type ServiceExport struct {
+	Name      string `yaml:"name"`
+	Worker    string `yaml:"worker"`
+	Namespace string `yaml:"namespace"`
+	Slice     string `yaml:"slice"`
+	// Labels of the pods backing the exported service
+	Selector       map[string]string   `yaml:"selector"`
+	Ports          []ServiceExportPort `yaml:"ports"`
+	IngressEnabled bool                `yaml:"ingress_enabled"`
+}
+
+type ServiceExportPort struct {
+	Name          string `yaml:"name"`
+	ContainerPort int    `yaml:"container_port"`
+	Protocol      string `yaml:"protocol"`
+}
+
+// QosProfile of a slice, Priority is a pointer as 0 is a valid priority
+// which must not be taken for unset.
+type QosProfile struct {
+	QueueType               string `yaml:"queue_type"`
+	Priority                *int   `yaml:"priority"`
+	TcType                  string `yaml:"tc_type"`
+	BandwidthCeilingKbps    int    `yaml:"bandwidth_ceiling_kbps"`
+	BandwidthGuaranteedKbps int    `yaml:"bandwidth_guaranteed_kbps"`
+	DscpClass               string `yaml:"dscp_class"`
+}
+
+type ApplicationNamespace struct {
+	Namespace string `yaml:"namespace"`
+	// Clusters the namespace is onboarded on, '*' for every cluster of the slice
+	Clusters []string `yaml:"clusters"`
 }
 
 type ClusterConfiguration struct {
@@ -50,6 +109,11 @@ type Cluster struct {
 	KubeConfigPath      string `yaml:"kube_config_path"`
 	ControlPlaneAddress string `yaml:"control_plane_address"`
 	NodeIP              string `yaml:"node_ip"`
+	// HelmValues and ValuesFiles are deep-merged over the worker chart values
+	// and the generated worker values for this cluster only, helm_values
+	// taking precedence
+	HelmValues  map[string]interface{} `yaml:"helm_values"`
+	ValuesFiles []string               `yaml:"values_files"`
 }
 
 type ImagePullSecrets struct {
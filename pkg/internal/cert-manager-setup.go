@@ -1,60 +1,65 @@
 package internal
 
 import (
+	"context"
 	"fmt"
-	"log"
-	"time"
 
 	"github.com/kubeslice/kubeslice-cli/util"
 )
 
-func InstallCertManager(ApplicationConfiguration *ConfigurationSpecs) {
+func InstallCertManager(ctx context.Context, ApplicationConfiguration *ConfigurationSpecs) error {
 
 	cc := ApplicationConfiguration.Configuration.ClusterConfiguration
 	hc := ApplicationConfiguration.Configuration.HelmChartConfiguration
 	util.Printf("\nInstall Cert Manager to Controller Cluster...")
 
-	installCertManager(cc.ControllerCluster, hc)
+	if err := installCertManager(ctx, cc.ControllerCluster, hc); err != nil {
+		return err
+	}
 	util.Printf("%s Successfully installed helm chart %s/%s", util.Tick, hc.RepoAlias, hc.CertManagerChart.ChartName)
-	time.Sleep(200 * time.Millisecond)
 
 	util.Printf("%s Waiting for Cert Manager Pods to be Healthy...", util.Wait)
-	PodVerification("Waiting for Cert Manager Pods to be Healthy", cc.ControllerCluster, "cert-manager")
+	if err := PodVerification(ctx, "Waiting for Cert Manager Pods to be Healthy", cc.ControllerCluster, "cert-manager"); err != nil {
+		return err
+	}
 
 	util.Printf("%s Successfully installed cert manager.\n", util.Tick)
-
+	return nil
 }
-func UninstallCertManager(ApplicationConfiguration *ConfigurationSpecs) {
+func UninstallCertManager(ctx context.Context, ApplicationConfiguration *ConfigurationSpecs) error {
 
 	cc := ApplicationConfiguration.Configuration.ClusterConfiguration
 	hc := ApplicationConfiguration.Configuration.HelmChartConfiguration
 	util.Printf("\nUninstalling Cert Manager...")
 
-	err := uninstallCertManager(cc.ControllerCluster, hc)
-	if err == nil {
-		util.Printf("%s Successfully uninstalled cert manager.\n", util.Tick)
-	} else {
+	if err := uninstallCertManager(ctx, cc.ControllerCluster, hc); err != nil {
 		util.Printf("%s Failed to uninstall cert manager.\n", util.Cross)
+		return err
 	}
-
+	util.Printf("%s Successfully uninstalled cert manager.\n", util.Tick)
+	return nil
 }
 
-func installCertManager(cluster Cluster, hc HelmChartConfiguration) {
-	args := make([]string, 0)
-	args = append(args, "--kube-context", cluster.ContextName, "--kubeconfig", cluster.KubeConfigPath, "upgrade", "-i", "cert-manager", fmt.Sprintf("%s/%s", hc.RepoAlias, hc.CertManagerChart.ChartName), "--namespace", "cert-manager", "--create-namespace", "--set", "installCRDs=true")
-	if hc.CertManagerChart.Version != "" {
-		args = append(args, "--version", hc.CertManagerChart.Version)
-	}
-	err := util.RunCommand("helm", args...)
+func installCertManager(ctx context.Context, cluster Cluster, hc HelmChartConfiguration) error {
+	recordRelease(ctx, cluster, "cert-manager", "cert-manager")
+	client, err := NewHelmClient(nil, &cluster)
 	if err != nil {
-		log.Fatalf("Process failed %v", err)
+		return err
 	}
+	return client.Upgrade(ctx, HelmReleaseSpec{
+		Name:            "cert-manager",
+		Namespace:       "cert-manager",
+		Chart:           fmt.Sprintf("%s/%s", hc.RepoAlias, hc.CertManagerChart.ChartName),
+		Version:         hc.CertManagerChart.Version,
+		Set:             []string{"installCRDs=true"},
+		Install:         true,
+		CreateNamespace: true,
+	})
 }
-func uninstallCertManager(cluster Cluster, hc HelmChartConfiguration) error {
-	args := make([]string, 0)
-	args = append(args, "--kube-context", cluster.ContextName, "--kubeconfig", cluster.KubeConfigPath, "uninstall", "cert-manager", "--namespace", "cert-manager")
-
-	err := util.RunCommand("helm", args...)
-	return err
-
+func uninstallCertManager(ctx context.Context, cluster Cluster, hc HelmChartConfiguration) error {
+	client, err := NewHelmClient(nil, &cluster)
+	if err != nil {
+		return err
+	}
+	return client.Uninstall(ctx, "cert-manager", "cert-manager")
 }
@@ -1,8 +1,8 @@
 package internal
 
 import (
+	"context"
 	"fmt"
-	"time"
 
 	"github.com/kubeslice/kubeslice-cli/util"
 )
@@ -42,67 +42,84 @@ var regionTemplates = map[string]string{
 	"ks-w-2": regionTemplate2,
 }
 
-func RegisterWorkerClusters(ApplicationConfiguration *ConfigurationSpecs, cliOptions *CliOptionsStruct) {
+func RegisterWorkerClusters(ctx context.Context, ApplicationConfiguration *ConfigurationSpecs, cliOptions *CliOptionsStruct) error {
 	util.Printf("\nRegistering Worker Clusters with Project...")
 
 	if cliOptions != nil {
 		if cliOptions.FileName == "" {
 			cliOptions.FileName = kubesliceDirectory + "/" + "custom-" + clusterRegistrationFileName
-			generateClusterRegistrationManifest(ApplicationConfiguration, cliOptions.FileName, cliOptions.Namespace)
+			if err := generateClusterRegistrationManifest(ApplicationConfiguration, cliOptions.FileName, cliOptions.Namespace); err != nil {
+				return err
+			}
 		}
 		util.Printf("%s Generated cluster registration manifest %s", util.Tick, cliOptions.FileName)
-		time.Sleep(200 * time.Millisecond)
-		ApplyKubectlManifest(cliOptions.FileName, cliOptions.Namespace, cliOptions.Cluster)
+		if err := ApplyKubectlManifest(ctx, cliOptions.FileName, cliOptions.Namespace, cliOptions.Cluster); err != nil {
+			return err
+		}
 		util.Printf("%s Applied %s", util.Tick, cliOptions.FileName)
-		time.Sleep(200 * time.Millisecond)
 	} else {
 		ac := ApplicationConfiguration.Configuration
-		generateClusterRegistrationManifest(ApplicationConfiguration, kubesliceDirectory+"/"+clusterRegistrationFileName, "kubeslice-"+ac.KubeSliceConfiguration.ProjectName)
+		if err := generateClusterRegistrationManifest(ApplicationConfiguration, kubesliceDirectory+"/"+clusterRegistrationFileName, "kubeslice-"+ac.KubeSliceConfiguration.ProjectName); err != nil {
+			return err
+		}
 		util.Printf("%s Generated cluster registration manifest %s", util.Tick, clusterRegistrationFileName)
-		time.Sleep(200 * time.Millisecond)
 
-		ApplyKubectlManifest(kubesliceDirectory+"/"+clusterRegistrationFileName, "kubeslice-"+ac.KubeSliceConfiguration.ProjectName, &ac.ClusterConfiguration.ControllerCluster)
+		if err := ApplyKubectlManifest(ctx, kubesliceDirectory+"/"+clusterRegistrationFileName, "kubeslice-"+ac.KubeSliceConfiguration.ProjectName, &ac.ClusterConfiguration.ControllerCluster); err != nil {
+			return err
+		}
 		util.Printf("%s Applied %s", util.Tick, clusterRegistrationFileName)
-		time.Sleep(200 * time.Millisecond)
 	}
 	util.Printf("Registered Worker Clusters with Project.")
+	return nil
+}
+
+func generateClusterRegistrationManifest(ApplicationConfiguration *ConfigurationSpecs, filename string, namespace string) error {
+	return util.DumpFile(renderClusterRegistrationManifest(ApplicationConfiguration, namespace), filename)
 }
 
-func generateClusterRegistrationManifest(ApplicationConfiguration *ConfigurationSpecs, filename string, namespace string) {
+func renderClusterRegistrationManifest(ApplicationConfiguration *ConfigurationSpecs, namespace string) string {
 	var clusterRegistrationContent = ""
 	var regionTemplate = "{}"
 	if namespace == "" {
 		namespace = "kubeslice-" + ApplicationConfiguration.Configuration.KubeSliceConfiguration.ProjectName
 	}
 	for _, cluster := range ApplicationConfiguration.Configuration.ClusterConfiguration.WorkerClusters {
-		if ApplicationConfiguration.Configuration.ClusterConfiguration.Profile == ProfileEntDemo {
-			regionTemplate = regionTemplates[cluster.Name]
+		if region, ok := regionTemplates[cluster.Name]; ok && ApplicationConfiguration.Enterprise() {
+			regionTemplate = region
 		}
 		clusterRegistrationContent = clusterRegistrationContent + fmt.Sprintf(clusterRegistrationTemplate, cluster.Name, namespace, regionTemplate)
 	}
-	util.DumpFile(clusterRegistrationContent, filename)
+	return clusterRegistrationContent
 }
 
-func GetKubeSliceCluster(clusterName string, namespace string, controllerCluster *Cluster, outputFormat string) {
+func GetKubeSliceCluster(ctx context.Context, clusterName string, namespace string, controllerCluster *Cluster, outputFormat string) error {
 	util.Printf("\nFetching KubeSlice Worker...")
-	GetKubectlResources(ClusterObject, clusterName, namespace, controllerCluster, outputFormat)
-	time.Sleep(200 * time.Millisecond)
+	if err := GetKubectlResources(ctx, ClusterObject, clusterName, namespace, controllerCluster, outputFormat); err != nil {
+		return err
+	}
+	return nil
 }
 
-func DeleteKubeSliceCluster(clusterName string, namespace string, controllerCluster *Cluster) {
+func DeleteKubeSliceCluster(ctx context.Context, clusterName string, namespace string, controllerCluster *Cluster) error {
 	util.Printf("\nDeleting KubeSlice Worker...")
-	DeleteKubectlResources(ClusterObject, clusterName, namespace, controllerCluster)
-	time.Sleep(200 * time.Millisecond)
+	if err := DeleteKubectlResources(ctx, ClusterObject, clusterName, namespace, controllerCluster); err != nil {
+		return err
+	}
+	return nil
 }
 
-func EditKubeSliceCluster(clusterName string, namespace string, controllerCluster *Cluster) {
+func EditKubeSliceCluster(ctx context.Context, clusterName string, namespace string, controllerCluster *Cluster) error {
 	util.Printf("\nEditing KubeSlice Worker...")
-	EditKubectlResources(ClusterObject, clusterName, namespace, controllerCluster)
-	time.Sleep(200 * time.Millisecond)
+	if err := EditKubectlResources(ctx, ClusterObject, clusterName, namespace, controllerCluster); err != nil {
+		return err
+	}
+	return nil
 }
 
-func DescribeKubeSliceCluster(clusterName string, namespace string, controllerCluster *Cluster) {
+func DescribeKubeSliceCluster(ctx context.Context, clusterName string, namespace string, controllerCluster *Cluster) error {
 	util.Printf("\nDescribe KubeSlice Worker...")
-	DescribeKubectlResources(ClusterObject, clusterName, namespace, controllerCluster)
-	time.Sleep(200 * time.Millisecond)
+	if err := DescribeKubectlResources(ctx, ClusterObject, clusterName, namespace, controllerCluster); err != nil {
+		return err
+	}
+	return nil
 }
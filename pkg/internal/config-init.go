@@ -0,0 +1,401 @@
+package internal
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/kubeslice/kubeslice-cli/util"
+	"gopkg.in/yaml.v2"
+)
+
+const defaultTopologyRepoUrl = "https://kubeslice.github.io/kubeslice/"
+
+// ConfigInitOptions holds the answers of the config init wizard. Fields set
+// from flags are used as-is, the rest are prompted for unless NonInteractive.
+type ConfigInitOptions struct {
+	KubeConfigPaths   []string
+	ControllerContext string
+	WorkerContexts    []string
+	ProjectName       string
+	RepoUrl           string
+	ControllerVersion string
+	WorkerVersion     string
+	OutputFile        string
+	NonInteractive    bool
+	SkipNetworkLookup bool
+}
+
+// KubeContext is a context found in one of the user's kubeconfig files.
+type KubeContext struct {
+	Name           string
+	KubeConfigPath string
+	Current        bool
+}
+
+type kubeConfigFile struct {
+	CurrentContext string `yaml:"current-context"`
+	Contexts       []struct {
+		Name string `yaml:"name"`
+	} `yaml:"contexts"`
+}
+
+// KubeConfigPaths returns the kubeconfig files kubectl would use: $KUBECONFIG
+// if it is set, ~/.kube/config otherwise.
+func KubeConfigPaths() []string {
+	paths := make([]string, 0)
+	for _, path := range filepath.SplitList(os.Getenv("KUBECONFIG")) {
+		if path != "" {
+			paths = append(paths, path)
+		}
+	}
+	if len(paths) == 0 {
+		if home, err := os.UserHomeDir(); err == nil {
+			paths = append(paths, filepath.Join(home, ".kube", "config"))
+		}
+	}
+	return paths
+}
+
+// ReadKubeContexts lists the contexts of the given kubeconfig files. Like
+// kubectl, the first file defining a context or the current-context wins.
+func ReadKubeContexts(paths []string) ([]KubeContext, error) {
+	contexts := make([]KubeContext, 0)
+	seen := make(map[string]bool)
+	currentContext := ""
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			if os.IsNotExist(err) && len(paths) > 1 {
+				continue
+			}
+			return nil, fmt.Errorf("failed to read kubeconfig %s: %v", path, err)
+		}
+		kubeConfig := kubeConfigFile{}
+		if err := yaml.Unmarshal(data, &kubeConfig); err != nil {
+			return nil, fmt.Errorf("failed to parse kubeconfig %s: %v", path, err)
+		}
+		if absolutePath, err := filepath.Abs(path); err == nil {
+			path = absolutePath
+		}
+		if currentContext == "" {
+			currentContext = kubeConfig.CurrentContext
+		}
+		for _, context := range kubeConfig.Contexts {
+			if !seen[context.Name] {
+				seen[context.Name] = true
+				contexts = append(contexts, KubeContext{Name: context.Name, KubeConfigPath: path})
+			}
+		}
+	}
+	for i := range contexts {
+		contexts[i].Current = contexts[i].Name == currentContext
+	}
+	if len(contexts) == 0 {
+		return nil, fmt.Errorf("no contexts found in kubeconfig %s", strings.Join(paths, string(os.PathListSeparator)))
+	}
+	return contexts, nil
+}
+
+var invalidClusterNameCharacters = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// ClusterNameFromContext derives a valid cluster name from a context name,
+// e.g. gke_my-project_us-east1_prod becomes gke-my-project-us-east1-prod.
+func ClusterNameFromContext(context string) string {
+	name := invalidClusterNameCharacters.ReplaceAllString(strings.ToLower(context), "-")
+	if len(name) > 63 {
+		name = name[len(name)-63:]
+	}
+	return strings.Trim(name, "-")
+}
+
+// uniqueClusterName derives the name of the n-th cluster from its context,
+// suffixed with -2, -3... when it is already used, or cluster-n when the
+// context has no usable characters.
+func uniqueClusterName(context string, n int, used map[string]bool) string {
+	name := ClusterNameFromContext(context)
+	if name == "" {
+		name = fmt.Sprintf("cluster-%d", n)
+	}
+	unique := name
+	for i := 2; used[unique]; i++ {
+		suffix := fmt.Sprintf("-%d", i)
+		base := name
+		if len(base)+len(suffix) > 63 {
+			base = strings.TrimLeft(base[len(base)+len(suffix)-63:], "-")
+		}
+		unique = base + suffix
+	}
+	return unique
+}
+
+// InitConfiguration builds a topology from existing kubeconfig contexts and
+// returns it, options.OutputFile being where it is to be written.
+func InitConfiguration(ctx context.Context, options *ConfigInitOptions) (string, error) {
+	return initConfiguration(ctx, options, os.Stdin, os.Stdout)
+}
+
+// initConfiguration is InitConfiguration reading the answers from in and
+// writing the prompts to out.
+func initConfiguration(ctx context.Context, options *ConfigInitOptions, in io.Reader, out io.Writer) (string, error) {
+	if len(options.KubeConfigPaths) == 0 {
+		options.KubeConfigPaths = KubeConfigPaths()
+	}
+	contexts, err := ReadKubeContexts(options.KubeConfigPaths)
+	if err != nil {
+		return "", err
+	}
+	p := &prompter{in: bufio.NewReader(in), out: out, nonInteractive: options.NonInteractive}
+
+	if !options.NonInteractive {
+		util.Printf("\nContexts found in kubeconfig:")
+		for i, context := range contexts {
+			current := ""
+			if context.Current {
+				current = " (current)"
+			}
+			util.Printf("  [%d] %s%s\t%s", i+1, context.Name, current, context.KubeConfigPath)
+		}
+	}
+
+	if options.ControllerContext == "" {
+		defaultController := ""
+		for _, context := range contexts {
+			if context.Current {
+				defaultController = context.Name
+			}
+		}
+		selected, err := p.selectContexts("Select the controller cluster context", contexts, []string{defaultController}, false)
+		if err != nil {
+			return "", err
+		}
+		options.ControllerContext = selected[0]
+	}
+	if len(options.WorkerContexts) == 0 {
+		others := make([]string, 0)
+		for _, context := range contexts {
+			if context.Name != options.ControllerContext {
+				others = append(others, context.Name)
+			}
+		}
+		if options.WorkerContexts, err = p.selectContexts("Select the worker cluster contexts (comma-separated)", contexts, others, true); err != nil {
+			return "", err
+		}
+	}
+
+	used := make(map[string]bool)
+	controller, err := p.cluster(options.ControllerContext, contexts, 1, used)
+	if err != nil {
+		return "", err
+	}
+	workers := make([]Cluster, 0, len(options.WorkerContexts))
+	for i, context := range options.WorkerContexts {
+		worker, err := p.cluster(context, contexts, i+2, used)
+		if err != nil {
+			return "", err
+		}
+		workers = append(workers, worker)
+	}
+	if !options.SkipNetworkLookup {
+		util.Printf("\nFetching Network Address for Clusters...")
+		lookupNetworkInformation(ctx, &controller)
+		for i := range workers {
+			lookupNetworkInformation(ctx, &workers[i])
+		}
+	}
+
+	for _, question := range []struct {
+		text         string
+		value        *string
+		defaultValue string
+	}{
+		{"KubeSlice project name", &options.ProjectName, "demo"},
+		{"Helm repository URL", &options.RepoUrl, defaultTopologyRepoUrl},
+		{"Controller chart version (leave blank for latest)", &options.ControllerVersion, ""},
+		{"Worker chart version (leave blank for latest)", &options.WorkerVersion, ""},
+		{"Write topology to", &options.OutputFile, "topology.yaml"},
+	} {
+		if *question.value, err = p.ask(question.text, *question.value, question.defaultValue); err != nil {
+			return "", err
+		}
+	}
+	return generateTopology(options, controller, workers), nil
+}
+
+func lookupNetworkInformation(ctx context.Context, cluster *Cluster) {
+	if address, err := _getControlPlaneAddress(ctx, cluster); err == nil && address != "" {
+		cluster.ControlPlaneAddress = address
+		util.Printf("%s Control Plane Address fetched %s for %s", util.Tick, address, cluster.Name)
+	} else {
+		util.Printf("%s Unable to fetch Control Plane Address for %s, it will be discovered during install", util.Warn, cluster.Name)
+	}
+	if ip, err := _getNodeIP(ctx, cluster); err == nil && ip != "" {
+		cluster.NodeIP = ip
+		util.Printf("%s Node IP fetched %s for %s", util.Tick, ip, cluster.Name)
+	} else {
+		util.Printf("%s Unable to fetch Node IP for %s, it will be discovered during install", util.Warn, cluster.Name)
+	}
+}
+
+const topologyTemplate = `apiVersion: %s
+configuration:
+  cluster_configuration:
+    controller:
+%s    workers:
+%s  kubeslice_configuration:
+    project_name: %s
+  helm_chart_configuration:
+    repo_alias: kubeslice
+    repo_url: %s
+    cert_manager_chart:
+      chart_name: cert-manager
+    controller_chart:
+      chart_name: kubeslice-controller
+%s    worker_chart:
+      chart_name: kubeslice-worker
+%s`
+
+func generateTopology(options *ConfigInitOptions, controller Cluster, workers []Cluster) string {
+	workerContent := ""
+	for _, worker := range workers {
+		workerContent += "    - " + strings.TrimPrefix(clusterTopology(worker, "      "), "      ")
+	}
+	return fmt.Sprintf(topologyTemplate,
+		ConfigAPIVersion,
+		clusterTopology(controller, "      "),
+		workerContent,
+		yamlScalar(options.ProjectName),
+		yamlScalar(options.RepoUrl),
+		optionalField("      ", "version", options.ControllerVersion),
+		optionalField("      ", "version", options.WorkerVersion),
+	)
+}
+
+func clusterTopology(cluster Cluster, indent string) string {
+	return optionalField(indent, "name", cluster.Name) +
+		optionalField(indent, "context_name", cluster.ContextName) +
+		optionalField(indent, "kube_config_path", cluster.KubeConfigPath) +
+		optionalField(indent, "control_plane_address", cluster.ControlPlaneAddress) +
+		optionalField(indent, "node_ip", cluster.NodeIP)
+}
+
+func optionalField(indent, key, value string) string {
+	if value == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s%s: %s\n", indent, key, yamlScalar(value))
+}
+
+// yamlScalar renders value as a yaml string, quoting it when needed.
+func yamlScalar(value string) string {
+	out, err := yaml.Marshal(value)
+	if err != nil {
+		return strconv.Quote(value)
+	}
+	return strings.TrimSuffix(string(out), "\n")
+}
+
+// maxSelectionAttempts bounds how many times an invalid context selection is
+// asked again.
+const maxSelectionAttempts = 3
+
+type prompter struct {
+	in             *bufio.Reader
+	out            io.Writer
+	nonInteractive bool
+	// eof is set once the answers are exhausted
+	eof bool
+}
+
+// ask returns value if it was already set, otherwise prompts for it.
+func (p *prompter) ask(question, value, defaultValue string) (string, error) {
+	if value != "" {
+		return value, nil
+	}
+	if p.nonInteractive {
+		return defaultValue, nil
+	}
+	if defaultValue != "" {
+		fmt.Fprintf(p.out, "%s [%s]: ", question, defaultValue)
+	} else {
+		fmt.Fprintf(p.out, "%s: ", question)
+	}
+	answer, err := p.in.ReadString('\n')
+	p.eof = err == io.EOF
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("Failed to read answer %w", err)
+	}
+	if answer = strings.TrimSpace(answer); answer == "" {
+		return defaultValue, nil
+	}
+	return answer, nil
+}
+
+// selectContexts prompts for context numbers or names until a valid selection
+// is made, giving up after maxSelectionAttempts or when there are no answers
+// left.
+func (p *prompter) selectContexts(question string, contexts []KubeContext, defaults []string, multiple bool) ([]string, error) {
+	if p.nonInteractive {
+		return nil, util.ValidationErrorf("Please pass --controller-context and --worker-contexts with --non-interactive")
+	}
+	for attempt := 1; ; attempt++ {
+		answer, err := p.ask(question, "", strings.Join(defaults, ","))
+		if err != nil {
+			return nil, err
+		}
+		selected := make([]string, 0)
+		valid := answer != ""
+		for _, choice := range strings.Split(answer, ",") {
+			choice = strings.TrimSpace(choice)
+			if i, err := strconv.Atoi(choice); err == nil && i >= 1 && i <= len(contexts) {
+				selected = append(selected, contexts[i-1].Name)
+			} else if findContext(choice, contexts) != nil {
+				selected = append(selected, choice)
+			} else {
+				valid = false
+			}
+		}
+		if valid && (multiple || len(selected) == 1) {
+			return selected, nil
+		}
+		util.Printf("%s Invalid selection %q", util.Cross, answer)
+		if p.eof || attempt == maxSelectionAttempts {
+			return nil, util.ValidationErrorf("No valid answer to %q, please pass --controller-context and --worker-contexts", question)
+		}
+	}
+}
+
+// cluster asks for the name of the n-th cluster, defaulting to one derived
+// from its context that is not in used yet.
+func (p *prompter) cluster(context string, contexts []KubeContext, n int, used map[string]bool) (Cluster, error) {
+	kubeContext := findContext(context, contexts)
+	if kubeContext == nil {
+		return Cluster{}, util.NotFoundErrorf("Context %s not found in kubeconfig", context)
+	}
+	name, err := p.ask(fmt.Sprintf("Name for cluster %s", context), "", uniqueClusterName(context, n, used))
+	if err != nil {
+		return Cluster{}, err
+	}
+	used[name] = true
+	return Cluster{
+		Name:           name,
+		ContextName:    kubeContext.Name,
+		KubeConfigPath: kubeContext.KubeConfigPath,
+	}, nil
+}
+
+func findContext(name string, contexts []KubeContext) *KubeContext {
+	for i := range contexts {
+		if contexts[i].Name == name {
+			return &contexts[i]
+		}
+	}
+	return nil
+}
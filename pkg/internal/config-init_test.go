@@ -0,0 +1,267 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kubeslice/kubeslice-cli/util"
+	"gopkg.in/yaml.v2"
+)
+
+func TestReadKubeContexts(t *testing.T) {
+	t.Parallel()
+
+	kind, err := filepath.Abs("testdata/kubeconfig")
+	if err != nil {
+		t.Fatalf("Failed to setup test: %v", err)
+	}
+	gke, err := filepath.Abs("testdata/kubeconfig-gke")
+	if err != nil {
+		t.Fatalf("Failed to setup test: %v", err)
+	}
+	missing := filepath.Join(t.TempDir(), "missing")
+
+	tests := []struct {
+		name    string
+		paths   []string
+		want    []KubeContext
+		wantErr string
+	}{
+		{
+			name:  "single file",
+			paths: []string{kind},
+			want: []KubeContext{
+				{Name: "kind-controller", KubeConfigPath: kind, Current: true},
+				{Name: "kind-worker-1", KubeConfigPath: kind},
+			},
+		},
+		{
+			name:  "first file wins",
+			paths: []string{kind, missing, gke},
+			want: []KubeContext{
+				{Name: "kind-controller", KubeConfigPath: kind, Current: true},
+				{Name: "kind-worker-1", KubeConfigPath: kind},
+				{Name: "gke_demo_us-east1_worker-2", KubeConfigPath: gke},
+			},
+		},
+		{
+			name:  "current context of the first file setting it",
+			paths: []string{gke, kind},
+			want: []KubeContext{
+				{Name: "kind-worker-1", KubeConfigPath: gke},
+				{Name: "gke_demo_us-east1_worker-2", KubeConfigPath: gke, Current: true},
+				{Name: "kind-controller", KubeConfigPath: kind},
+			},
+		},
+		{name: "missing file", paths: []string{missing}, wantErr: "failed to read kubeconfig " + missing},
+		{name: "no contexts", paths: []string{missing, missing + "-2"}, wantErr: "no contexts found in kubeconfig"},
+	}
+	for _, tc := range tests {
+		tc := tc // Capture range variable for parallel execution
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			contexts, err := ReadKubeContexts(tc.paths)
+			if tc.wantErr != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), tc.wantErr) {
+					t.Errorf("ReadKubeContexts() = %v, want error %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReadKubeContexts() returned unexpected error %v", err)
+			}
+			if !reflect.DeepEqual(contexts, tc.want) {
+				t.Errorf("ReadKubeContexts() = %v, want %v", contexts, tc.want)
+			}
+		})
+	}
+}
+
+func TestGenerateTopology(t *testing.T) {
+	t.Parallel()
+
+	options := &ConfigInitOptions{ProjectName: "yes", RepoUrl: defaultTopologyRepoUrl, WorkerVersion: "0.5.0"}
+	controller := Cluster{Name: "kind-controller", ContextName: "kind-controller", KubeConfigPath: "/home/user/.kube/config"}
+	workers := []Cluster{
+		{Name: "worker-1", ContextName: "kind-worker-1", KubeConfigPath: "/home/user/.kube/config", ControlPlaneAddress: "https://172.18.0.3:6443", NodeIP: "172.18.0.3"},
+		{Name: "worker-2", ContextName: "gke_demo_us-east1_worker-2: #1", KubeConfigPath: "/home/user/gke config"},
+	}
+	topology := generateTopology(options, controller, workers)
+
+	specs := ConfigurationSpecs{}
+	if err := yaml.UnmarshalStrict([]byte(topology), &specs); err != nil {
+		t.Fatalf("generateTopology() = %s, which does not parse: %v", topology, err)
+	}
+	if specs.APIVersion != ConfigAPIVersion {
+		t.Errorf("generateTopology() has apiVersion %q, want %q", specs.APIVersion, ConfigAPIVersion)
+	}
+	clusters := specs.Configuration.ClusterConfiguration
+	if !reflect.DeepEqual(clusters.ControllerCluster, controller) || !reflect.DeepEqual(clusters.WorkerClusters, workers) {
+		t.Errorf("generateTopology() has clusters %v and %v, want %v and %v", clusters.ControllerCluster, clusters.WorkerClusters, controller, workers)
+	}
+	if got := specs.Configuration.KubeSliceConfiguration.ProjectName; got != "yes" {
+		t.Errorf("generateTopology() has project %q, want %q", got, "yes")
+	}
+	helm := specs.Configuration.HelmChartConfiguration
+	if helm.RepoUrl != defaultTopologyRepoUrl || helm.ControllerChart.Version != "" || helm.WorkerChart.Version != "0.5.0" {
+		t.Errorf("generateTopology() has helm configuration %+v, want repository %s and worker chart 0.5.0", helm, defaultTopologyRepoUrl)
+	}
+}
+
+// initTopology runs config init with options answering the prompts with
+// answers, and returns the topology it generated.
+func initTopology(t *testing.T, options *ConfigInitOptions, answers string) string {
+	t.Helper()
+	if len(options.KubeConfigPaths) == 0 {
+		options.KubeConfigPaths = []string{"testdata/kubeconfig", "testdata/kubeconfig-gke"}
+	}
+	options.OutputFile = "topology.yaml"
+	options.SkipNetworkLookup = true
+	topology, err := initConfiguration(context.Background(), options, strings.NewReader(answers), &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("initConfiguration() returned unexpected error %v", err)
+	}
+	return topology
+}
+
+// clusterNames returns the names of the controller and workers of topology.
+func clusterNames(t *testing.T, topology string) []string {
+	t.Helper()
+	specs := ConfigurationSpecs{}
+	if err := yaml.Unmarshal([]byte(topology), &specs); err != nil {
+		t.Fatalf("initConfiguration() generated %s, which does not parse: %v", topology, err)
+	}
+	clusters := specs.Configuration.ClusterConfiguration
+	names := []string{clusters.ControllerCluster.Name}
+	for _, worker := range clusters.WorkerClusters {
+		names = append(names, worker.Name)
+	}
+	return names
+}
+
+// writeKubeConfig writes a kubeconfig with contexts, and no current context.
+func writeKubeConfig(t *testing.T, contexts ...string) string {
+	t.Helper()
+	kubeConfig := "apiVersion: v1\nkind: Config\ncontexts:\n"
+	for _, context := range contexts {
+		kubeConfig += "- name: " + yamlScalar(context) + "\n"
+	}
+	path := filepath.Join(t.TempDir(), "kubeconfig")
+	if err := ioutil.WriteFile(path, []byte(kubeConfig), 0644); err != nil {
+		t.Fatalf("Failed to setup test: %v", err)
+	}
+	return path
+}
+
+// not parallel, as it sets the output
+func TestInitConfiguration(t *testing.T) {
+	util.SetOutput(&bytes.Buffer{})
+	t.Cleanup(func() { util.SetOutput(os.Stdout) })
+
+	// every default accepted
+	prompted := initTopology(t, &ConfigInitOptions{}, strings.Repeat("\n", 10))
+	nonInteractive := initTopology(t, &ConfigInitOptions{
+		ControllerContext: "kind-controller",
+		WorkerContexts:    []string{"kind-worker-1", "gke_demo_us-east1_worker-2"},
+		NonInteractive:    true,
+	}, "")
+	if prompted != nonInteractive {
+		t.Errorf("config init --non-interactive wrote\n%s\nwant the topology of the prompts accepting the defaults\n%s", nonInteractive, prompted)
+	}
+
+	// contexts selected by number and name, the cluster renamed
+	answers := "2\n3,kind-controller\n\ngke-worker\n\nproject\n\n\n1.0.0\n"
+	prompted = initTopology(t, &ConfigInitOptions{}, answers)
+	specs := ConfigurationSpecs{}
+	if err := yaml.Unmarshal([]byte(prompted), &specs); err != nil {
+		t.Fatalf("initConfiguration() wrote %s, which does not parse: %v", prompted, err)
+	}
+	if names, want := clusterNames(t, prompted), []string{"kind-worker-1", "gke-worker", "kind-controller"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("initConfiguration() wrote clusters %v, want %v", names, want)
+	}
+	if got := specs.Configuration.KubeSliceConfiguration.ProjectName; got != "project" {
+		t.Errorf("initConfiguration() wrote project %q, want %q", got, "project")
+	}
+	if got := specs.Configuration.HelmChartConfiguration.WorkerChart.Version; got != "1.0.0" {
+		t.Errorf("initConfiguration() wrote worker chart version %q, want %q", got, "1.0.0")
+	}
+
+	if _, err := initConfiguration(context.Background(), &ConfigInitOptions{KubeConfigPaths: []string{"testdata/kubeconfig"}, NonInteractive: true}, strings.NewReader(""), &bytes.Buffer{}); util.ExitCode(err) != util.ExitValidation {
+		t.Errorf("initConfiguration() --non-interactive without contexts = %v, want a ValidationError", err)
+	}
+
+	// contexts deriving the same name, or none at all
+	kubeConfig := writeKubeConfig(t, "ctrl", "w_1", "w.1", "___")
+	nonInteractive = initTopology(t, &ConfigInitOptions{
+		KubeConfigPaths:   []string{kubeConfig},
+		ControllerContext: "ctrl",
+		WorkerContexts:    []string{"w_1", "w.1", "___"},
+		NonInteractive:    true,
+	}, "")
+	if names, want := clusterNames(t, nonInteractive), []string{"ctrl", "w-1", "w-1-2", "cluster-4"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("initConfiguration() --non-interactive wrote clusters %v, want %v", names, want)
+	}
+}
+
+// not parallel, as it sets the output
+func TestInitConfigurationInvalidSelection(t *testing.T) {
+	util.SetOutput(&bytes.Buffer{})
+	t.Cleanup(func() { util.SetOutput(os.Stdout) })
+
+	// no current context to default the controller to, and the only context
+	// left for the workers is the controller
+	kubeConfig := writeKubeConfig(t, "kind-controller")
+	tests := []struct {
+		name    string
+		answers string
+	}{
+		{name: "no answers", answers: ""},
+		{name: "no answers for the workers", answers: "1\n"},
+		{name: "invalid answers", answers: strings.Repeat("2\n", 10)},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			options := &ConfigInitOptions{KubeConfigPaths: []string{kubeConfig}, SkipNetworkLookup: true}
+			if _, err := initConfiguration(context.Background(), options, strings.NewReader(tc.answers), &bytes.Buffer{}); util.ExitCode(err) != util.ExitValidation || err == nil {
+				t.Errorf("initConfiguration() = %v, want a ValidationError", err)
+			}
+		})
+	}
+}
+
+func TestUniqueClusterName(t *testing.T) {
+	t.Parallel()
+
+	long := strings.Repeat("a", 70)
+	tests := []struct {
+		context string
+		used    []string
+		want    string
+	}{
+		{context: "kind-worker-1", want: "kind-worker-1"},
+		{context: "w_1", used: []string{"w-1"}, want: "w-1-2"},
+		{context: "w.1", used: []string{"w-1", "w-1-2"}, want: "w-1-3"},
+		{context: "___", want: "cluster-3"},
+		{context: "___", used: []string{"cluster-3"}, want: "cluster-3-2"},
+		{context: long, used: []string{strings.Repeat("a", 63)}, want: strings.Repeat("a", 61) + "-2"},
+	}
+	for _, tc := range tests {
+		tc := tc // Capture range variable for parallel execution
+		t.Run(tc.context, func(t *testing.T) {
+			t.Parallel()
+			used := make(map[string]bool)
+			for _, name := range tc.used {
+				used[name] = true
+			}
+			if got := uniqueClusterName(tc.context, 3, used); got != tc.want {
+				t.Errorf("uniqueClusterName(%q, 3, %v) = %q, want %q", tc.context, tc.used, got, tc.want)
+			}
+		})
+	}
+}
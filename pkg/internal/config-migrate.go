@@ -0,0 +1,153 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// ConfigAPIVersionV1Alpha1 is the original topology format. Files without
+	// an apiVersion are read as this version.
+	ConfigAPIVersionV1Alpha1 = "cli.kubeslice.io/v1alpha1"
+	// ConfigAPIVersion is the current topology format.
+	ConfigAPIVersion = "cli.kubeslice.io/v1beta1"
+)
+
+// configMigration rewrites a topology from one version to the next. Every
+// older version has one, so that older files keep decoding: they are migrated
+// in memory before being checked and decoded against the current schema.
+type configMigration struct {
+	from    string
+	to      string
+	migrate func(root *yaml.Node)
+}
+
+var configMigrations = []configMigration{
+	{
+		// v1beta1 only adds the apiVersion itself, the layout is unchanged
+		from: ConfigAPIVersionV1Alpha1,
+		to:   ConfigAPIVersion,
+	},
+}
+
+// knownVersion tells whether version can be read.
+func knownVersion(version string) bool {
+	if version == ConfigAPIVersion {
+		return true
+	}
+	for _, m := range configMigrations {
+		if m.from == version {
+			return true
+		}
+	}
+	return false
+}
+
+// checkVersion returns the apiVersion the document was written in, or an
+// error located at the apiVersion if it cannot be read.
+func (d *ConfigDocument) checkVersion() (string, error) {
+	node := d.content()
+	if node == nil || node.Kind != yaml.MappingNode {
+		return ConfigAPIVersion, nil
+	}
+	value, key := lookupKey(node, "apiVersion")
+	if value == nil {
+		return ConfigAPIVersionV1Alpha1, nil
+	}
+	if value.Kind != yaml.ScalarNode || !knownVersion(value.Value) {
+		return "", d.errorAt(key, "apiVersion", "unsupported topology version %s, expected %s", describeNode(value), ConfigAPIVersion)
+	}
+	return value.Value, nil
+}
+
+// Migrate rewrites the document to the current topology format and returns
+// false if it already was up to date. When no migration changes the layout,
+// only the apiVersion line of the file is rewritten; otherwise the file is
+// encoded again, keeping its comments.
+func (d *ConfigDocument) Migrate() (bool, error) {
+	if d.APIVersion == ConfigAPIVersion {
+		return false, nil
+	}
+	root := d.content()
+	if root == nil || root.Kind != yaml.MappingNode {
+		return false, fmt.Errorf("%s is not a topology configuration", d.File)
+	}
+	version := d.APIVersion
+	changed := false
+	for _, m := range configMigrations {
+		if m.from != version {
+			continue
+		}
+		if m.migrate != nil {
+			m.migrate(root)
+			changed = true
+		}
+		version = m.to
+	}
+	if !changed && root.Style&yaml.FlowStyle == 0 && len(root.Content) > 0 {
+		d.Data = setAPIVersionLine(d.Data, root, version)
+		setAPIVersion(root, version)
+		return true, nil
+	}
+	setAPIVersion(root, version)
+	data, err := d.Encode()
+	if err != nil {
+		return false, err
+	}
+	d.Data = data
+	return true, nil
+}
+
+// matches the scalar at the start of a line: quoted, or up to a space or comment
+var leadingScalar = regexp.MustCompile(`^("[^"]*"|'[^']*'|[^\s#]+)`)
+
+// setAPIVersionLine sets the apiVersion in the text of a block style topology,
+// leaving every other line as written. A missing apiVersion is inserted above
+// the first key, below the comments at the top of the file.
+func setAPIVersionLine(data []byte, root *yaml.Node, version string) []byte {
+	lines := strings.SplitAfter(string(data), "\n")
+	if value, _ := lookupKey(root, "apiVersion"); value != nil {
+		line := []rune(lines[value.Line-1])
+		start := value.Column - 1
+		rest := leadingScalar.ReplaceAllLiteralString(string(line[start:]), version)
+		lines[value.Line-1] = string(line[:start]) + rest
+		return []byte(strings.Join(lines, ""))
+	}
+	first := root.Content[0].Line - 1
+	lines = append(lines[:first], append([]string{"apiVersion: " + version + "\n"}, lines[first:]...)...)
+	return []byte(strings.Join(lines, ""))
+}
+
+// setAPIVersion sets the apiVersion of a topology, adding it as the first key
+// if it is missing.
+func setAPIVersion(root *yaml.Node, version string) {
+	if value, _ := lookupKey(root, "apiVersion"); value != nil {
+		value.Value = version
+		return
+	}
+	key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "apiVersion"}
+	value := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: version}
+	// keep a leading comment at the top of the file rather than above apiVersion
+	if len(root.Content) > 0 && root.Content[0].HeadComment != "" {
+		key.HeadComment, root.Content[0].HeadComment = root.Content[0].HeadComment, ""
+	}
+	root.Content = append([]*yaml.Node{key, value}, root.Content...)
+}
+
+// Encode renders the document as yaml, including its comments.
+func (d *ConfigDocument) Encode() ([]byte, error) {
+	var b bytes.Buffer
+	encoder := yaml.NewEncoder(&b)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(d.Root); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
@@ -0,0 +1,181 @@
+package internal
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+// ConfigLayers are the topology files making up a configuration in the order
+// they are merged: bases first, each file overriding the ones before it.
+type ConfigLayers struct {
+	Documents []*ConfigDocument
+	merged    map[interface{}]interface{}
+}
+
+// LoadConfigLayers reads the given topology files along with the files they
+// name under base:, checks each of them against the schema and deep-merges
+// them in order.
+func LoadConfigLayers(fileNames []string) (*ConfigLayers, []ConfigError) {
+	layers := &ConfigLayers{}
+	errors := make([]ConfigError, 0)
+	for _, fileName := range fileNames {
+		errors = append(errors, layers.load(fileName, nil)...)
+	}
+	if len(errors) > 0 {
+		return nil, errors
+	}
+	layers.merged = make(map[interface{}]interface{})
+	for _, doc := range layers.Documents {
+		layer := make(map[interface{}]interface{})
+		if err := yaml.Unmarshal(doc.Data, &layer); err != nil {
+			return nil, []ConfigError{{File: doc.File, Message: err.Error()}}
+		}
+		delete(layer, "base")
+		layers.merged = mergeLayers(layers.merged, layer)
+	}
+	return layers, nil
+}
+
+func (l *ConfigLayers) load(fileName string, chain []string) []ConfigError {
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return []ConfigError{{File: fileName, Message: fmt.Sprintf("failed to read configuration file %v", err)}}
+	}
+	doc, err := ParseConfigDocument(fileName, data)
+	if err != nil {
+		return []ConfigError{err.(ConfigError)}
+	}
+	// older files are decoded by migrating them in memory first
+	if _, err := doc.Migrate(); err != nil {
+		return []ConfigError{{File: fileName, Message: err.Error()}}
+	}
+	if errors := doc.CheckSchema(&ConfigurationSpecs{}); len(errors) > 0 {
+		return errors
+	}
+	if baseNode, baseKey := lookupKey(doc.content(), "base"); baseNode != nil && baseNode.Value != "" {
+		base := baseNode.Value
+		if !filepath.IsAbs(base) {
+			base = filepath.Join(filepath.Dir(fileName), base)
+		}
+		chain = append(chain, fileName)
+		for _, f := range chain {
+			if sameFile(f, base) {
+				return []ConfigError{doc.errorAt(baseKey, "base", "%s is included in a loop: %s -> %s", baseNode.Value, strings.Join(chain, " -> "), base)}
+			}
+		}
+		if _, err := os.Stat(base); err != nil {
+			return []ConfigError{doc.errorAt(baseKey, "base", "cannot read base configuration %s", base)}
+		}
+		if errors := l.load(base, chain); len(errors) > 0 {
+			return errors
+		}
+	}
+	l.Documents = append(l.Documents, doc)
+	return nil
+}
+
+func sameFile(a, b string) bool {
+	aInfo, aErr := os.Stat(a)
+	bInfo, bErr := os.Stat(b)
+	if aErr != nil || bErr != nil {
+		return filepath.Clean(a) == filepath.Clean(b)
+	}
+	return os.SameFile(aInfo, bInfo)
+}
+
+// Outdated returns the layers written in an older topology version.
+func (l *ConfigLayers) Outdated() []*ConfigDocument {
+	outdated := make([]*ConfigDocument, 0)
+	for _, doc := range l.Documents {
+		if doc.APIVersion != ConfigAPIVersion {
+			outdated = append(outdated, doc)
+		}
+	}
+	return outdated
+}
+
+// Merged returns the effective topology as yaml.
+func (l *ConfigLayers) Merged() ([]byte, error) {
+	return yaml.Marshal(l.merged)
+}
+
+// Locate fills in the file and line of errors, using the last layer which
+// sets the offending value. Errors about values no layer sets are located in
+// the last file.
+func (l *ConfigLayers) Locate(errors []ConfigError) []ConfigError {
+	for i := range errors {
+		if errors[i].File != "" || errors[i].Path == "" {
+			continue
+		}
+		doc, node := l.find(errors[i].Path)
+		errors[i].File = doc.File
+		if node != nil {
+			errors[i].Line, errors[i].Column = node.Line, node.Column
+		}
+	}
+	return errors
+}
+
+// Dir returns the directory of the layer which sets path, so that relative
+// file references resolve next to the file they were written in.
+func (l *ConfigLayers) Dir(path string) string {
+	doc, _ := l.find(path)
+	return filepath.Dir(doc.File)
+}
+
+func (l *ConfigLayers) find(path string) (*ConfigDocument, *yaml3.Node) {
+	segments := l.segments(path)
+	last := l.Documents[len(l.Documents)-1]
+	for i := len(l.Documents) - 1; i >= 0; i-- {
+		if node, exact := l.Documents[i].lookup(segments); exact {
+			return l.Documents[i], node
+		}
+	}
+	node, _ := last.lookup(segments)
+	return last, node
+}
+
+// uniqueName tells whether the item at index is the only one with its name,
+// duplicates being matched by their index instead.
+func uniqueName(list []interface{}, index int) bool {
+	name := list[index].(map[interface{}]interface{})["name"]
+	for i, item := range list {
+		if i != index && item.(map[interface{}]interface{})["name"] == name {
+			return false
+		}
+	}
+	return true
+}
+
+// segments splits path and names the items of named lists, as their index in
+// the merged configuration may differ from the one in each layer.
+func (l *ConfigLayers) segments(path string) []pathSegment {
+	segments := make([]pathSegment, 0)
+	var current interface{} = l.merged
+	for _, key := range splitPath(path) {
+		segment := pathSegment{key: key}
+		switch typed := current.(type) {
+		case map[interface{}]interface{}:
+			current = typed[key]
+		case []interface{}:
+			current = nil
+			if index, err := strconv.Atoi(key); err == nil && index < len(typed) {
+				current = typed[index]
+				if namedList(typed) && uniqueName(typed, index) {
+					segment.name = typed[index].(map[interface{}]interface{})["name"].(string)
+				}
+			}
+		default:
+			current = nil
+		}
+		segments = append(segments, segment)
+	}
+	return segments
+}
@@ -0,0 +1,103 @@
+package internal
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+const (
+	envReferencePrefix  = "env:"
+	fileReferencePrefix = "file:"
+)
+
+// matches $${ (an escaped ${) and ${NAME} or ${NAME:-default}
+var envInterpolation = regexp.MustCompile(`\$\$\{|\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// ResolveReferences replaces secret references in the string fields of the
+// configuration, so that secrets do not have to be written in the topology file:
+//
+//	env:NAME       the value of the environment variable NAME
+//	file:PATH      the contents of PATH, relative to baseDir(path)
+//	${NAME}        interpolates the environment variable NAME, ${NAME:-default} when unset
+//
+// The helm values are passed to the charts as they are written, a value
+// starting with env: or file: there is not a reference. Errors name the
+// reference that failed, never the value it resolves to.
+func ResolveReferences(specs *ConfigurationSpecs, baseDir func(path string) string) []ConfigError {
+	r := &referenceResolver{baseDir: baseDir, errors: make([]ConfigError, 0)}
+	r.resolveValue(reflect.ValueOf(specs).Elem(), "")
+	return r.errors
+}
+
+type referenceResolver struct {
+	baseDir func(path string) string
+	errors  []ConfigError
+}
+
+func (r *referenceResolver) resolveValue(v reflect.Value, path string) {
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if name := yamlName(v.Type().Field(i)); name != "" {
+				r.resolveValue(v.Field(i), joinPath(path, name))
+			}
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			r.resolveValue(v.Index(i), fmt.Sprintf("%s[%d]", path, i))
+		}
+	case reflect.String:
+		v.SetString(r.resolveString(v.String(), path))
+	}
+}
+
+func (r *referenceResolver) resolveString(value, path string) string {
+	switch {
+	case strings.HasPrefix(value, envReferencePrefix):
+		name := strings.TrimPrefix(value, envReferencePrefix)
+		resolved, ok := os.LookupEnv(name)
+		if !ok {
+			r.errorf(path, "environment variable %s referenced by %q is not set", name, value)
+		}
+		return resolved
+	case strings.HasPrefix(value, fileReferencePrefix) && !strings.HasPrefix(value, "file://"):
+		fileName := strings.TrimPrefix(value, fileReferencePrefix)
+		if strings.HasPrefix(fileName, "~/") {
+			if home, err := os.UserHomeDir(); err == nil {
+				fileName = filepath.Join(home, fileName[2:])
+			}
+		}
+		if !filepath.IsAbs(fileName) {
+			fileName = filepath.Join(r.baseDir(path), fileName)
+		}
+		data, err := ioutil.ReadFile(fileName)
+		if err != nil {
+			r.errorf(path, "cannot read file %s referenced by %q", fileName, value)
+			return ""
+		}
+		return strings.TrimRight(string(data), "\r\n")
+	}
+	return envInterpolation.ReplaceAllStringFunc(value, func(match string) string {
+		if match == "$${" {
+			return "${"
+		}
+		groups := envInterpolation.FindStringSubmatch(match)
+		if resolved, ok := os.LookupEnv(groups[1]); ok {
+			return resolved
+		}
+		if groups[2] != "" {
+			return groups[3]
+		}
+		r.errorf(path, "environment variable %s referenced by %q is not set", groups[1], match)
+		return ""
+	})
+}
+
+func (r *referenceResolver) errorf(path, format string, a ...interface{}) {
+	r.errors = append(r.errors, ConfigError{Path: path, Message: fmt.Sprintf(format, a...)})
+}
@@ -0,0 +1,102 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// not parallel, as it sets environment variables
+func TestResolveReferences(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "password"), []byte("from-file\n"), 0644); err != nil {
+		t.Fatalf("Failed to setup test: %v", err)
+	}
+	t.Setenv("KUBESLICE_TEST_PASSWORD", "from-env")
+	t.Setenv("KUBESLICE_TEST_HOST", "charts.example.com")
+
+	tests := []struct {
+		name       string
+		value      string
+		want       string
+		wantErrors []string
+	}{
+		{name: "plain value", value: "s3cret", want: "s3cret"},
+		{name: "env reference", value: "env:KUBESLICE_TEST_PASSWORD", want: "from-env"},
+		{name: "file reference", value: "file:password", want: "from-file"},
+		{name: "absolute file reference", value: "file:" + filepath.Join(dir, "password"), want: "from-file"},
+		{name: "file url", value: "file:///charts", want: "file:///charts"},
+		{name: "interpolation", value: "https://${KUBESLICE_TEST_HOST}/kubeslice", want: "https://charts.example.com/kubeslice"},
+		{name: "interpolation default", value: "${KUBESLICE_TEST_UNSET:-fallback}", want: "fallback"},
+		{name: "interpolation default when set", value: "${KUBESLICE_TEST_PASSWORD:-fallback}", want: "from-env"},
+		{name: "escaped interpolation", value: "$${KUBESLICE_TEST_PASSWORD}", want: "${KUBESLICE_TEST_PASSWORD}"},
+		{
+			name:       "unset env reference",
+			value:      "env:KUBESLICE_TEST_UNSET",
+			wantErrors: []string{`environment variable KUBESLICE_TEST_UNSET referenced by "env:KUBESLICE_TEST_UNSET" is not set`},
+		},
+		{
+			name:       "missing file",
+			value:      "file:missing",
+			wantErrors: []string{`cannot read file ` + filepath.Join(dir, "missing") + ` referenced by "file:missing"`},
+		},
+		{
+			name:       "unset interpolation",
+			value:      "${KUBESLICE_TEST_PASSWORD}:${KUBESLICE_TEST_UNSET}",
+			want:       "from-env:",
+			wantErrors: []string{`environment variable KUBESLICE_TEST_UNSET referenced by "${KUBESLICE_TEST_UNSET}" is not set`},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			specs := &ConfigurationSpecs{}
+			specs.Configuration.HelmChartConfiguration.HelmPassword = tc.value
+			errors := ResolveReferences(specs, func(string) string { return dir })
+			messages := make([]string, 0, len(errors))
+			for _, err := range errors {
+				if err.Path != "configuration.helm_chart_configuration.helm_password" {
+					t.Errorf("ResolveReferences() returned error at %s, want it at the helm password", err.Path)
+				}
+				if strings.Contains(err.Message, "from-env") || strings.Contains(err.Message, "from-file") {
+					t.Errorf("ResolveReferences() returned error %q, which leaks a resolved value", err.Message)
+				}
+				messages = append(messages, err.Message)
+			}
+			if strings.Join(messages, "\n") != strings.Join(tc.wantErrors, "\n") {
+				t.Errorf("ResolveReferences() returned errors %q, want %q", messages, tc.wantErrors)
+			}
+			if len(tc.wantErrors) == 0 && specs.Configuration.HelmChartConfiguration.HelmPassword != tc.want {
+				t.Errorf("ResolveReferences() resolved %q to %q, want %q", tc.value, specs.Configuration.HelmChartConfiguration.HelmPassword, tc.want)
+			}
+		})
+	}
+}
+
+// not parallel, as it sets environment variables
+func TestResolveReferencesFields(t *testing.T) {
+	t.Setenv("KUBESLICE_TEST_PASSWORD", "from-env")
+
+	specs := &ConfigurationSpecs{}
+	specs.Configuration.HelmChartConfiguration.ImagePullSecret.Password = "env:KUBESLICE_TEST_PASSWORD"
+	specs.Configuration.ClusterConfiguration.WorkerClusters = []Cluster{{
+		Name:       "worker-1",
+		HelmValues: map[string]interface{}{"prefix": "env:literal", "path": "file:values.yaml", "template": "${KUBESLICE_TEST_PASSWORD}"},
+	}}
+	specs.Configuration.HelmChartConfiguration.WorkerChart.Values = map[string]interface{}{"args": []interface{}{"env:KUBESLICE_TEST_PASSWORD"}}
+	if errors := ResolveReferences(specs, func(string) string { return "." }); len(errors) > 0 {
+		t.Fatalf("ResolveReferences() returned unexpected errors %v", errors)
+	}
+	if got := specs.Configuration.HelmChartConfiguration.ImagePullSecret.Password; got != "from-env" {
+		t.Errorf("ResolveReferences() resolved the image pull password to %q, want %q", got, "from-env")
+	}
+	wantHelmValues := map[string]interface{}{"prefix": "env:literal", "path": "file:values.yaml", "template": "${KUBESLICE_TEST_PASSWORD}"}
+	if got := specs.Configuration.ClusterConfiguration.WorkerClusters[0].HelmValues; !reflect.DeepEqual(got, wantHelmValues) {
+		t.Errorf("ResolveReferences() changed the worker helm values to %v, want them as written %v", got, wantHelmValues)
+	}
+	wantValues := map[string]interface{}{"args": []interface{}{"env:KUBESLICE_TEST_PASSWORD"}}
+	if got := specs.Configuration.HelmChartConfiguration.WorkerChart.Values; !reflect.DeepEqual(got, wantValues) {
+		t.Errorf("ResolveReferences() changed the chart values to %v, want them as written %v", got, wantValues)
+	}
+}
@@ -0,0 +1,351 @@
+package internal
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigError describes a single problem in a topology configuration, along
+// with the file, line and YAML path of the offending value when known.
+type ConfigError struct {
+	File    string
+	Line    int
+	Column  int
+	Path    string
+	Message string
+}
+
+func (e ConfigError) Error() string {
+	var b strings.Builder
+	if e.File != "" {
+		b.WriteString(e.File)
+		if e.Line > 0 {
+			fmt.Fprintf(&b, ":%d:%d", e.Line, e.Column)
+		}
+		b.WriteString(": ")
+	}
+	if e.Path != "" {
+		b.WriteString(e.Path)
+		b.WriteString(": ")
+	}
+	b.WriteString(e.Message)
+	return b.String()
+}
+
+// ConfigDocument is a parsed topology file which keeps the underlying yaml
+// nodes around, so that errors can be traced back to where they were written.
+type ConfigDocument struct {
+	File string
+	Data []byte
+	Root *yaml.Node
+	// APIVersion is the topology version the file was written in
+	APIVersion string
+}
+
+var yamlErrorLine = regexp.MustCompile(`line (\d+)`)
+
+func ParseConfigDocument(fileName string, data []byte) (*ConfigDocument, error) {
+	root := &yaml.Node{}
+	if err := yaml.Unmarshal(data, root); err != nil {
+		configErr := ConfigError{File: fileName, Message: strings.TrimPrefix(err.Error(), "yaml: ")}
+		if m := yamlErrorLine.FindStringSubmatch(err.Error()); m != nil {
+			configErr.Line, _ = strconv.Atoi(m[1])
+			configErr.Column = 1
+		}
+		return nil, configErr
+	}
+	doc := &ConfigDocument{File: fileName, Data: data, Root: root}
+	version, err := doc.checkVersion()
+	if err != nil {
+		return nil, err
+	}
+	doc.APIVersion = version
+	return doc, nil
+}
+
+// content returns the top level node of the document, or nil for an empty file.
+func (d *ConfigDocument) content() *yaml.Node {
+	if d.Root.Kind == yaml.DocumentNode {
+		if len(d.Root.Content) == 0 {
+			return nil
+		}
+		return d.Root.Content[0]
+	}
+	return d.Root
+}
+
+// CheckSchema strictly checks the document against the yaml tags of v.
+// Unknown keys and values of the wrong type are reported with their location.
+func (d *ConfigDocument) CheckSchema(v interface{}) []ConfigError {
+	node := d.content()
+	if node == nil {
+		return []ConfigError{{File: d.File, Message: "configuration file is empty"}}
+	}
+	return d.checkNode(node, reflect.TypeOf(v), "")
+}
+
+func (d *ConfigDocument) checkNode(node *yaml.Node, t reflect.Type, path string) []ConfigError {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+		return nil
+	}
+	errors := make([]ConfigError, 0)
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			return append(errors, d.errorAt(node, path, "expected a mapping, got %s", describeNode(node)))
+		}
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			childPath := joinPath(path, key.Value)
+			field, ok := fields[key.Value]
+			if !ok {
+				message := fmt.Sprintf("unknown field %q", key.Value)
+				if suggestion := closestField(key.Value, fields); suggestion != "" {
+					message = fmt.Sprintf("%s, did you mean %q?", message, suggestion)
+				}
+				errors = append(errors, d.errorAt(key, childPath, message))
+				continue
+			}
+			errors = append(errors, d.checkNode(value, field.Type, childPath)...)
+		}
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			return append(errors, d.errorAt(node, path, "expected a list, got %s", describeNode(node)))
+		}
+		for i, item := range node.Content {
+			errors = append(errors, d.checkNode(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			return append(errors, d.errorAt(node, path, "expected a mapping, got %s", describeNode(node)))
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			errors = append(errors, d.checkNode(value, t.Elem(), joinPath(path, key.Value))...)
+		}
+	case reflect.String:
+		if node.Kind != yaml.ScalarNode || node.Tag != "!!str" {
+			message := fmt.Sprintf("expected a string, got %s", describeNode(node))
+			if node.Kind == yaml.ScalarNode {
+				message += ", quote the value to keep it as written"
+			}
+			errors = append(errors, d.errorAt(node, path, message))
+		}
+	case reflect.Bool:
+		if node.Kind != yaml.ScalarNode || node.Tag != "!!bool" {
+			errors = append(errors, d.errorAt(node, path, "expected true or false, got %s", describeNode(node)))
+		}
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		if node.Kind != yaml.ScalarNode || node.Tag != "!!int" {
+			errors = append(errors, d.errorAt(node, path, "expected an integer, got %s", describeNode(node)))
+		}
+	}
+	return errors
+}
+
+// Lookup returns the node at path, or the node of its closest existing parent
+// along with false. Mapping entries resolve to their key so the reported line
+// is where the field is written.
+func (d *ConfigDocument) Lookup(path string) (*yaml.Node, bool) {
+	segments := make([]pathSegment, 0)
+	for _, segment := range splitPath(path) {
+		segments = append(segments, pathSegment{key: segment})
+	}
+	return d.lookup(segments)
+}
+
+// pathSegment is a mapping key or a list index. List items can also be
+// matched by their name, which is stable across overlays.
+type pathSegment struct {
+	key  string
+	name string
+}
+
+func (d *ConfigDocument) lookup(segments []pathSegment) (*yaml.Node, bool) {
+	current := d.content()
+	if current == nil {
+		return nil, false
+	}
+	found := current
+	for _, segment := range segments {
+		if current.Kind == yaml.AliasNode {
+			current = current.Alias
+		}
+		var next, anchor *yaml.Node
+		if current.Kind == yaml.SequenceNode {
+			for i, item := range current.Content {
+				if segment.name != "" && item.Kind == yaml.MappingNode {
+					if name, _ := lookupKey(item, "name"); name != nil && name.Value == segment.name {
+						next = item
+						break
+					}
+				} else if segment.name == "" && strconv.Itoa(i) == segment.key {
+					next = item
+					break
+				}
+			}
+			anchor = next
+		} else if current.Kind == yaml.MappingNode {
+			next, anchor = lookupKey(current, segment.key)
+		}
+		if next == nil {
+			return found, false
+		}
+		current, found = next, anchor
+	}
+	return found, true
+}
+
+// lookupKey returns the value and key nodes of key in a mapping node.
+func lookupKey(mapping *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1], mapping.Content[i]
+		}
+	}
+	return nil, nil
+}
+
+func (d *ConfigDocument) errorAt(node *yaml.Node, path, format string, a ...interface{}) ConfigError {
+	return ConfigError{
+		File:    d.File,
+		Line:    node.Line,
+		Column:  node.Column,
+		Path:    path,
+		Message: fmt.Sprintf(format, a...),
+	}
+}
+
+// splitPath turns "a.b[0].c" into ["a", "b", "0", "c"].
+func splitPath(path string) []string {
+	segments := make([]string, 0)
+	for _, part := range strings.Split(path, ".") {
+		for part != "" {
+			open := strings.Index(part, "[")
+			if open == -1 {
+				segments = append(segments, part)
+				break
+			}
+			if open > 0 {
+				segments = append(segments, part[:open])
+			}
+			end := strings.Index(part, "]")
+			if end < open {
+				break
+			}
+			segments = append(segments, part[open+1:end])
+			part = part[end+1:]
+		}
+	}
+	return segments
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func yamlFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < t.NumField(); i++ {
+		if name := yamlName(t.Field(i)); name != "" {
+			fields[name] = t.Field(i)
+		}
+	}
+	return fields
+}
+
+// yamlName returns the key a struct field is decoded from, or "" if the field
+// is never decoded.
+func yamlName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+	if name == "-" || field.PkgPath != "" {
+		return ""
+	}
+	if name == "" {
+		name = strings.ToLower(field.Name)
+	}
+	return name
+}
+
+func describeNode(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "a mapping"
+	case yaml.SequenceNode:
+		return "a list"
+	}
+	switch node.Tag {
+	case "!!str":
+		return fmt.Sprintf("string %q", node.Value)
+	case "!!int":
+		return fmt.Sprintf("integer %s", node.Value)
+	case "!!float":
+		return fmt.Sprintf("number %s", node.Value)
+	case "!!bool":
+		return fmt.Sprintf("boolean %s", node.Value)
+	}
+	return fmt.Sprintf("%q", node.Value)
+}
+
+// closestField suggests a known field for a misspelled key, e.g.
+// kubeconfig_path -> kube_config_path.
+func closestField(key string, fields map[string]reflect.StructField) string {
+	normalize := func(s string) string {
+		return strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(s))
+	}
+	best, bestDistance := "", 3
+	for name := range fields {
+		if normalize(name) == normalize(key) {
+			return name
+		}
+		if distance := levenshtein(name, key); distance < bestDistance || (distance == bestDistance && name < best) {
+			best, bestDistance = name, distance
+		}
+	}
+	return best
+}
+
+func levenshtein(a, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min3(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
@@ -11,13 +11,18 @@ const (
 
 	Kind_Component                = "kind"
 	Calico_Component              = "calico"
+	NetworkInfo_Component         = "network-info"
+	HelmRepo_Component            = "helm-repo"
 	Controller_Component          = "controller"
+	Project_Component             = "project"
 	Worker_registration_Component = "worker-registration"
 	UI_install_Component          = "ui"
 	Worker_Component              = "worker"
 	Demo_Component                = "demo"
 	CertManager_Component         = "cert-manager"
 	Prometheus_Component          = "prometheus"
+	Slice_Component               = "slice"
+	ServiceExport_Component       = "service-export"
 	SecretObject                  = "secrets"
 	OutputFormatYaml              = "yaml"
 	OutputFormatJson              = "json"
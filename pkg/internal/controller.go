@@ -1,9 +1,8 @@
 package internal
 
 import (
+	"context"
 	"fmt"
-	"log"
-	"time"
 
 	"github.com/kubeslice/kubeslice-cli/util"
 )
@@ -21,66 +20,78 @@ kubeslice:
     endpoint: %s
 `
 
-func InstallKubeSliceController(ApplicationConfiguration *ConfigurationSpecs) {
+func InstallKubeSliceController(ctx context.Context, ApplicationConfiguration *ConfigurationSpecs) error {
 	util.Printf("\nInstalling KubeSlice Controller...")
 
 	cc := ApplicationConfiguration.Configuration.ClusterConfiguration
 	hc := ApplicationConfiguration.Configuration.HelmChartConfiguration
-	generateControllerValuesFile(cc.ControllerCluster, ApplicationConfiguration.Configuration.HelmChartConfiguration)
+	if err := generateControllerValuesFile(cc.ControllerCluster, ApplicationConfiguration.Configuration.HelmChartConfiguration); err != nil {
+		return err
+	}
 	util.Printf("%s Generated Helm Values file for Controller Installation %s", util.Tick, controllerValuesFileName)
-	time.Sleep(200 * time.Millisecond)
 
-	installKubeSliceController(cc.ControllerCluster, hc)
+	if err := installKubeSliceController(ctx, cc.ControllerCluster, hc); err != nil {
+		return err
+	}
 	util.Printf("%s Successfully installed helm chart %s/%s", util.Tick, hc.RepoAlias, hc.ControllerChart.ChartName)
-	time.Sleep(2 * time.Second)
 
 	util.Printf("%s Waiting for KubeSlice Controller Pods to be Healthy...", util.Wait)
-	PodVerification("Waiting for KubeSlice Controller Pods to be Healthy", cc.ControllerCluster, KUBESLICE_CONTROLLER_NAMESPACE)
+	if err := PodVerification(ctx, "Waiting for KubeSlice Controller Pods to be Healthy", cc.ControllerCluster, KUBESLICE_CONTROLLER_NAMESPACE); err != nil {
+		return err
+	}
 
-	if ApplicationConfiguration.Configuration.ClusterConfiguration.Profile != "" && ApplicationConfiguration.Configuration.ClusterConfiguration.Profile == ProfileEntDemo {
+	if ApplicationConfiguration.Enterprise() {
 		util.Printf("%s Waiting for KubeSlice Trial License to be Ready...", util.Wait)
-		LicenseVerification("Waiting for KubeSlice Trial License to be Ready", cc.ControllerCluster, KUBESLICE_CONTROLLER_NAMESPACE)
+		if err := LicenseVerification(ctx, "Waiting for KubeSlice Trial License to be Ready", cc.ControllerCluster, KUBESLICE_CONTROLLER_NAMESPACE); err != nil {
+			return err
+		}
 	}
 
 	util.Printf("%s Successfully installed KubeSlice Controller.\n", util.Tick)
-
+	return nil
 }
 
-func UninstallKubeSliceController(ApplicationConfiguration *ConfigurationSpecs) {
+func UninstallKubeSliceController(ctx context.Context, ApplicationConfiguration *ConfigurationSpecs) error {
 	util.Printf("\nUninstalling KubeSlice Controller...")
 	cc := ApplicationConfiguration.Configuration.ClusterConfiguration
-	time.Sleep(200 * time.Millisecond)
-	uninstallKubeSliceController(cc.ControllerCluster)
-	time.Sleep(200 * time.Millisecond)
+	if err := uninstallKubeSliceController(ctx, cc.ControllerCluster); err != nil {
+		return err
+	}
 	util.Printf("%s Successfully uninstalled KubeSlice Controller", util.Tick)
 	// wait for pods to be cleaned up.
 	// util.Printf("%s Waiting for KubeSlice Manager Pods to be removed...", util.Wait)
+	return nil
 }
 
-func generateControllerValuesFile(cluster Cluster, hcConfig HelmChartConfiguration) {
-	err := generateValuesFile(kubesliceDirectory+"/"+controllerValuesFileName, &hcConfig.ControllerChart, fmt.Sprintf(controllerValuesTemplate+generateImagePullSecretsValue(hcConfig.ImagePullSecret), cluster.ControlPlaneAddress))
-	if err != nil {
-		log.Fatalf("%s %s", util.Cross, err)
-	}
+func controllerValuesDefaults(cluster Cluster, hcConfig HelmChartConfiguration) string {
+	return fmt.Sprintf(controllerValuesTemplate+generateImagePullSecretsValue(hcConfig.ImagePullSecret), cluster.ControlPlaneAddress)
 }
 
-func installKubeSliceController(cluster Cluster, hc HelmChartConfiguration) {
-	args := make([]string, 0)
-	args = append(args, "--kube-context", cluster.ContextName, "--kubeconfig", cluster.KubeConfigPath, "upgrade", "-i", KUBESLICE_CONTROLLER_NAMESPACE, fmt.Sprintf("%s/%s", hc.RepoAlias, hc.ControllerChart.ChartName), "--namespace", KUBESLICE_CONTROLLER_NAMESPACE, "--create-namespace", "-f", kubesliceDirectory+"/"+controllerValuesFileName)
-	if hc.ControllerChart.Version != "" {
-		args = append(args, "--version", hc.ControllerChart.Version)
-	}
-	err := util.RunCommand("helm", args...)
+func generateControllerValuesFile(cluster Cluster, hcConfig HelmChartConfiguration) error {
+	return generateValuesFile(kubesliceDirectory+"/"+controllerValuesFileName, &hcConfig.ControllerChart, controllerValuesDefaults(cluster, hcConfig))
+}
+
+func installKubeSliceController(ctx context.Context, cluster Cluster, hc HelmChartConfiguration) error {
+	recordRelease(ctx, cluster, KUBESLICE_CONTROLLER_NAMESPACE, KUBESLICE_CONTROLLER_NAMESPACE)
+	client, err := NewHelmClient(nil, &cluster)
 	if err != nil {
-		log.Fatalf("Process failed %v", err)
+		return err
 	}
+	return client.Upgrade(ctx, HelmReleaseSpec{
+		Name:            KUBESLICE_CONTROLLER_NAMESPACE,
+		Namespace:       KUBESLICE_CONTROLLER_NAMESPACE,
+		Chart:           fmt.Sprintf("%s/%s", hc.RepoAlias, hc.ControllerChart.ChartName),
+		Version:         hc.ControllerChart.Version,
+		ValuesFiles:     []string{kubesliceDirectory + "/" + controllerValuesFileName},
+		Install:         true,
+		CreateNamespace: true,
+	})
 }
 
-func uninstallKubeSliceController(cluster Cluster) {
-	args := make([]string, 0)
-	args = append(args, "--kube-context", cluster.ContextName, "--kubeconfig", cluster.KubeConfigPath, "uninstall", KUBESLICE_CONTROLLER_NAMESPACE, "--namespace", KUBESLICE_CONTROLLER_NAMESPACE)
-	err := util.RunCommand("helm", args...)
+func uninstallKubeSliceController(ctx context.Context, cluster Cluster) error {
+	client, err := NewHelmClient(nil, &cluster)
 	if err != nil {
-		log.Fatalf("Process failed %v", err)
+		return err
 	}
+	return client.Uninstall(ctx, KUBESLICE_CONTROLLER_NAMESPACE, KUBESLICE_CONTROLLER_NAMESPACE)
 }
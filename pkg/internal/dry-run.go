@@ -0,0 +1,9 @@
+package internal
+
+import "fmt"
+
+// dryRunValue is the placeholder of a value read from a live cluster in
+// dry-run mode, e.g. <node-ip of ks-w-1>.
+func dryRunValue(value, clusterName string) string {
+	return fmt.Sprintf("<%s of %s>", value, clusterName)
+}
@@ -2,15 +2,15 @@ package internal
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
 	"strings"
-	"time"
 
 	"github.com/kubeslice/kubeslice-cli/util"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
 
 const (
@@ -24,95 +24,106 @@ kubeslice:
       type: %s
 `
 
-func InstallKubeSliceUI(ApplicationConfiguration *ConfigurationSpecs) {
+func InstallKubeSliceUI(ctx context.Context, ApplicationConfiguration *ConfigurationSpecs) error {
 	util.Printf("\nInstalling KubeSlice Manager...")
 	if ApplicationConfiguration.Configuration.HelmChartConfiguration.UIChart.ChartName == "" {
 		util.Printf("%s Skipping Kubeslice Manager installaition. UI Helm Chart not found in topology file.", util.Warn)
-		return
+		return nil
 	}
 	cc := ApplicationConfiguration.Configuration.ClusterConfiguration
 	hc := ApplicationConfiguration.Configuration.HelmChartConfiguration
-	time.Sleep(200 * time.Millisecond)
 
 	clusterType := ApplicationConfiguration.Configuration.ClusterConfiguration.ClusterType
 	filename := "helm-values-ui.yaml"
-	generateUIValuesFile(clusterType, cc.ControllerCluster, ApplicationConfiguration.Configuration.HelmChartConfiguration)
+	if err := generateUIValuesFile(clusterType, cc.ControllerCluster, ApplicationConfiguration.Configuration.HelmChartConfiguration); err != nil {
+		return err
+	}
 	util.Printf("%s Generated Helm Values file for Kubeslice Manager Installation %s", util.Tick, filename)
-	time.Sleep(200 * time.Millisecond)
 
-	installKubeSliceUI(cc.ControllerCluster, hc)
+	if err := installKubeSliceUI(ctx, cc.ControllerCluster, hc); err != nil {
+		return err
+	}
 	util.Printf("%s Successfully installed helm chart %s/%s", util.Tick, hc.RepoAlias, hc.UIChart.ChartName)
-	time.Sleep(200 * time.Millisecond)
 
 	util.Printf("%s Waiting for KubeSlice Manager Pods to be Healthy...", util.Wait)
-	PodVerification("Waiting for KubeSlice Manager Pods to be Healthy", cc.ControllerCluster, "kubernetes-dashboard")
+	if err := PodVerification(ctx, "Waiting for KubeSlice Manager Pods to be Healthy", cc.ControllerCluster, "kubernetes-dashboard"); err != nil {
+		return err
+	}
 	util.Printf("%s Successfully installed KubeSlice Manager.\n", util.Tick)
+	return nil
 }
 
-func UninstallKubeSliceUI(ApplicationConfiguration *ConfigurationSpecs) {
+func UninstallKubeSliceUI(ctx context.Context, ApplicationConfiguration *ConfigurationSpecs) error {
 	util.Printf("\nUninstalling KubeSlice Manager...")
 	cc := ApplicationConfiguration.Configuration.ClusterConfiguration
-	time.Sleep(200 * time.Millisecond)
-	ok, err := uninstallKubeSliceUI(cc.ControllerCluster)
+	ok, err := uninstallKubeSliceUI(ctx, cc.ControllerCluster)
 	if err != nil {
-		log.Fatalf("Process failed %v", err)
+		return fmt.Errorf("Process failed %w", err)
 	}
 	if ok {
-		time.Sleep(200 * time.Millisecond)
 		util.Printf("%s Successfully uninstalled KubeSlice Manager", util.Tick)
 	}
+	return nil
 }
 
-func generateUIValuesFile(clusterType string, cluster Cluster, hcConfig HelmChartConfiguration) {
+func uiValuesDefaults(clusterType string, hcConfig HelmChartConfiguration) string {
 	serviceType := ""
 	if clusterType == "kind" {
 		serviceType = "NodePort"
 	} else {
 		serviceType = "LoadBalancer"
 	}
-	err := generateValuesFile(kubesliceDirectory+"/"+uiValuesFileName, &hcConfig.UIChart, fmt.Sprintf(UIValuesTemplate+generateImagePullSecretsValue(hcConfig.ImagePullSecret), serviceType))
-	if err != nil {
-		log.Fatalf("%s %s", util.Cross, err)
-	}
+	return fmt.Sprintf(UIValuesTemplate+generateImagePullSecretsValue(hcConfig.ImagePullSecret), serviceType)
 }
 
-func installKubeSliceUI(cluster Cluster, hc HelmChartConfiguration) {
-	args := make([]string, 0)
-	args = append(args, "--kube-context", cluster.ContextName, "--kubeconfig", cluster.KubeConfigPath, "upgrade", "-i", "kubeslice-ui", fmt.Sprintf("%s/%s", hc.RepoAlias, hc.UIChart.ChartName), "--namespace", KUBESLICE_CONTROLLER_NAMESPACE, "-f", kubesliceDirectory+"/"+uiValuesFileName)
-	if hc.UIChart.Version != "" {
-		args = append(args, "--version", hc.UIChart.Version)
-	}
-	err := util.RunCommand("helm", args...)
+func generateUIValuesFile(clusterType string, cluster Cluster, hcConfig HelmChartConfiguration) error {
+	return generateValuesFile(kubesliceDirectory+"/"+uiValuesFileName, &hcConfig.UIChart, uiValuesDefaults(clusterType, hcConfig))
+}
+
+func installKubeSliceUI(ctx context.Context, cluster Cluster, hc HelmChartConfiguration) error {
+	recordRelease(ctx, cluster, "kubeslice-ui", KUBESLICE_CONTROLLER_NAMESPACE)
+	client, err := NewHelmClient(nil, &cluster)
 	if err != nil {
-		log.Fatalf("Process failed %v", err)
-	}
+		return err
+	}
+	return client.Upgrade(ctx, HelmReleaseSpec{
+		Name:        "kubeslice-ui",
+		Namespace:   KUBESLICE_CONTROLLER_NAMESPACE,
+		Chart:       fmt.Sprintf("%s/%s", hc.RepoAlias, hc.UIChart.ChartName),
+		Version:     hc.UIChart.Version,
+		ValuesFiles: []string{kubesliceDirectory + "/" + uiValuesFileName},
+		Install:     true,
+	})
 }
 
-func uninstallKubeSliceUI(cluster Cluster) (bool, error) {
-	args := make([]string, 0)
-	// fetching UI release
-	args = append(args, "--kube-context", cluster.ContextName, "--kubeconfig", cluster.KubeConfigPath, "status", "kubeslice-ui", "--namespace", KUBESLICE_CONTROLLER_NAMESPACE)
-	err := util.RunCommandWithoutPrint("helm", args...)
+func uninstallKubeSliceUI(ctx context.Context, cluster Cluster) (bool, error) {
+	client, err := NewHelmClient(nil, &cluster)
 	if err != nil {
-		util.Printf("%s KubeSlice Manager not installed, skipping uninstall.", util.Cross)
-		return false, nil
-	} else {
-		args = make([]string, 0)
-		args = append(args, "--kube-context", cluster.ContextName, "--kubeconfig", cluster.KubeConfigPath, "uninstall", "kubeslice-ui", "--namespace", KUBESLICE_CONTROLLER_NAMESPACE)
-		err = util.RunCommand("helm", args...)
-		if err != nil {
+		return false, err
+	}
+	// fetching UI release
+	if _, err := client.Status(ctx, "kubeslice-ui", KUBESLICE_CONTROLLER_NAMESPACE); err != nil {
+		if util.ExitCode(err) != util.ExitNotFound {
 			return false, err
 		}
+		util.Printf("%s KubeSlice Manager not installed, skipping uninstall.", util.Cross)
+		return false, nil
+	}
+	if err := client.Uninstall(ctx, "kubeslice-ui", KUBESLICE_CONTROLLER_NAMESPACE); err != nil {
+		return false, err
 	}
 	return true, nil
 }
 
-func GetUIEndpoint(cc *Cluster, profile string) string {
+func GetUIEndpoint(ctx context.Context, cc *Cluster, enterprise bool) string {
 	util.Printf("\nFetching KubeSlice Manager Endpoint...")
 	ep := ""
 
 	var outB, errB bytes.Buffer
-	err := util.RunCommandCustomIO("kubectl", &outB, &errB, true, "--context="+cc.ContextName, "--kubeconfig="+cc.KubeConfigPath, "get", "services", "kubeslice-ui-proxy", "-n", KUBESLICE_CONTROLLER_NAMESPACE, "-o", "jsonpath='{.spec}'")
+	err := util.RunCommandCustomIO(ctx, "kubectl", &outB, &errB, true, "--context="+cc.ContextName, "--kubeconfig="+cc.KubeConfigPath, "get", "services", "kubeslice-ui-proxy", "-n", KUBESLICE_CONTROLLER_NAMESPACE, "-o", "jsonpath='{.spec}'")
+	if util.DryRun {
+		return dryRunValue("kubeslice-manager-endpoint", cc.Name)
+	}
 	if err == nil {
 		jsonMap := make(map[string]interface{})
 		err = json.Unmarshal(outB.Bytes()[1:len(outB.Bytes())-1], &jsonMap)
@@ -121,7 +132,7 @@ func GetUIEndpoint(cc *Cluster, profile string) string {
 		}
 		switch jsonMap["type"] {
 		case "NodePort":
-			if profile == ProfileEntDemo {
+			if enterprise {
 				ep = fmt.Sprintf("https://%s:%d", "localhost", 8443)
 			} else {
 				ports := jsonMap["ports"].([]interface{})
@@ -129,7 +140,7 @@ func GetUIEndpoint(cc *Cluster, profile string) string {
 					portMap := port.(map[string]interface{})
 					if portMap["name"] == "http" { // Assuming that http is the name of the port that you want to use
 						nodePort := int(portMap["nodePort"].(float64))
-						nodeIP, err := getNodeIP(cc)
+						nodeIP, err := getNodeIP(ctx, cc)
 						if err == nil {
 							ep = fmt.Sprintf("https://%s:%d", strings.Trim(nodeIP, "'"), nodePort)
 						} else {
@@ -165,48 +176,51 @@ func GetUIEndpoint(cc *Cluster, profile string) string {
 	return ep
 }
 
-func findUserSecret(username string, projectName string, cc Cluster) string {
-	var outB, errB bytes.Buffer
-	err := util.RunCommandCustomIO("kubectl", &outB, &errB, true, "--context="+cc.ContextName, "--kubeconfig="+cc.KubeConfigPath, "get", "sa", "-n", "kubeslice-"+projectName, "-o", "name")
+func findUserSecret(ctx context.Context, username string, projectName string, cc Cluster) (string, error) {
+	secret, err := findServiceAccountSecret(ctx, &cc, "kubeslice-"+projectName, "rbac-rw-"+username)
 	if err != nil {
-		log.Fatalf("Process failed %v", err)
+		return "", err
 	}
-
-	var secret string
-	for _, line := range strings.Split(outB.String(), "\n") {
-		if strings.Contains(line, "rbac-rw-"+username) {
-			secret = fmt.Sprintf("secrets/%s", strings.TrimPrefix(line, "serviceaccount/"))
-			break
-		}
+	if util.DryRun {
+		return dryRunValue("user-secret", username), nil
 	}
 	if secret == "" {
-		log.Fatalf("failed to find secret for %s", username)
+		return "", util.NotFoundErrorf("failed to find secret for %s", username)
 	}
-	return secret
+	return secret, nil
 }
 
-func GetUIAdminToken(cc *Cluster, username, projectName string) string {
+func GetUIAdminToken(ctx context.Context, cc *Cluster, username, projectName string) (string, error) {
 	util.Printf("\nFetching KubeSlice Manager Admin Token...")
-	secret := findUserSecret(username, projectName, *cc)
+	secret, err := findUserSecret(ctx, username, projectName, *cc)
+	if err != nil {
+		return "", err
+	}
 
-	var outB, errB bytes.Buffer
-	err := util.RunCommandCustomIO("kubectl", &outB, &errB, false, "--context="+cc.ContextName, "--kubeconfig="+cc.KubeConfigPath, "get", secret, "-n", "kubeslice-"+projectName, "-o", "jsonpath={.data.token}")
+	client, err := NewKubeClient(cc)
 	if err != nil {
-		log.Fatalf("Process failed %v", err)
+		return "", err
+	}
+	obj, err := client.Get(ctx, SecretObject, "kubeslice-"+projectName, secret)
+	if err != nil {
+		return "", err
+	}
+	if util.DryRun {
+		return dryRunValue("admin-token", cc.Name), nil
 	}
-	x := outB.String()
+	x, _, _ := unstructured.NestedString(obj.Object, "data", "token")
 	// base64 decode
 	data, err := base64.StdEncoding.DecodeString(x)
 	if err != nil {
-		log.Fatalf("Unable to decode token %v", err)
+		return "", fmt.Errorf("Unable to decode token %w", err)
 	}
-	return string(data)
+	return string(data), nil
 
 }
 
-func getNodeIP(cc *Cluster) (string, error) {
+func getNodeIP(ctx context.Context, cc *Cluster) (string, error) {
 	var outB, errB bytes.Buffer
-	err := util.RunCommandCustomIO("kubectl", &outB, &errB, true, "--context="+cc.ContextName, "--kubeconfig="+cc.KubeConfigPath, "get", "nodes", "-o", "jsonpath='{.items[*].status.addresses[?(@.type==\"InternalIP\")].address}'")
+	err := util.RunCommandCustomIO(ctx, "kubectl", &outB, &errB, true, "--context="+cc.ContextName, "--kubeconfig="+cc.KubeConfigPath, "get", "nodes", "-o", "jsonpath='{.items[*].status.addresses[?(@.type==\"InternalIP\")].address}'")
 	if err == nil {
 		nodeIPs := strings.FieldsFunc(outB.String(), func(c rune) bool { return c == ' ' || c == '\n' })
 		if len(nodeIPs) > 0 {
@@ -2,9 +2,7 @@ package internal
 
 import (
 	"fmt"
-	"log"
 	"os"
-	"time"
 
 	"github.com/kubeslice/kubeslice-cli/util"
 )
@@ -12,7 +10,6 @@ import (
 const (
 	kubesliceDirectory = "kubeslice"
 	kindSubDirectory   = "kind"
-	ProfileEntDemo     = "enterprise-demo"
 )
 
 const kubesliceControllerTemplate = `
@@ -60,36 +57,42 @@ nodes:
             node-labels: "kubeslice.io/node-type=gateway"
 `
 
-func DeleteKubeSliceDirectory() {
+func DeleteKubeSliceDirectory() error {
 	err := os.RemoveAll(kubesliceDirectory)
 	if err != nil {
-		log.Fatalf("\nFailed to delete directory %s\n", kubesliceDirectory)
+		return fmt.Errorf("Failed to delete directory %s: %w", kubesliceDirectory, err)
 	}
+	return nil
 }
 
-func GenerateKubeSliceDirectory() {
-	util.CreateDirectoryPath(kubesliceDirectory)
+func GenerateKubeSliceDirectory() error {
+	return util.CreateDirectoryPath(kubesliceDirectory)
 }
 
-func GenerateKindConfiguration(ApplicationConfiguration *ConfigurationSpecs) {
+func GenerateKindConfiguration(ApplicationConfiguration *ConfigurationSpecs) error {
 	cc := ApplicationConfiguration.Configuration.ClusterConfiguration
 	directory := kubesliceDirectory + "/" + kindSubDirectory
 	util.Printf("\nGenerating Kind configuration files to %s directory...", directory)
 
-	util.CreateDirectoryPath(directory)
+	if err := util.CreateDirectoryPath(directory); err != nil {
+		return err
+	}
 
 	controllerTemplate := kubesliceControllerTemplate
-	if ApplicationConfiguration.Configuration.ClusterConfiguration.Profile == ProfileEntDemo {
+	if ApplicationConfiguration.Enterprise() {
 		controllerTemplate = kubesliceEntControllerTemplate
 	}
 
-	util.DumpFile(fmt.Sprintf(controllerTemplate, cc.ControllerCluster.Name), directory+"/"+cc.ControllerCluster.Name+".yaml")
+	if err := util.DumpFile(fmt.Sprintf(controllerTemplate, cc.ControllerCluster.Name), directory+"/"+cc.ControllerCluster.Name+".yaml"); err != nil {
+		return err
+	}
 	util.Printf("%s Generated %s", util.Tick, directory+"/"+cc.ControllerCluster.Name+".yaml")
-	time.Sleep(200 * time.Millisecond)
 
 	for _, cluster := range cc.WorkerClusters {
-		util.DumpFile(fmt.Sprintf(kubesliceWorkerTemplate, cluster.Name), directory+"/"+cluster.Name+".yaml")
+		if err := util.DumpFile(fmt.Sprintf(kubesliceWorkerTemplate, cluster.Name), directory+"/"+cluster.Name+".yaml"); err != nil {
+			return err
+		}
 		util.Printf("%s Generated %s", util.Tick, directory+"/"+cluster.Name+".yaml")
-		time.Sleep(200 * time.Millisecond)
 	}
+	return nil
 }
@@ -2,91 +2,113 @@ package internal
 
 import (
 	"bytes"
+	"context"
 	"fmt"
-	"os"
 	"strings"
-	"time"
 
 	"github.com/kubeslice/kubeslice-cli/util"
 )
 
-func GatherNetworkInformation(ApplicationConfiguration *ConfigurationSpecs) {
+func GatherNetworkInformation(ctx context.Context, ApplicationConfiguration *ConfigurationSpecs) error {
 	util.Printf("\nFetching Network Address for Clusters...")
 
 	if ApplicationConfiguration.Configuration.ClusterConfiguration.Profile == "" && ApplicationConfiguration.Configuration.ClusterConfiguration.ClusterType != "kind" {
-		setControlPlaneAddress(&ApplicationConfiguration.Configuration.ClusterConfiguration)
-		setNodeIP(&ApplicationConfiguration.Configuration.ClusterConfiguration)
+		if err := setControlPlaneAddress(ctx, &ApplicationConfiguration.Configuration.ClusterConfiguration); err != nil {
+			return err
+		}
+		if err := setNodeIP(ctx, &ApplicationConfiguration.Configuration.ClusterConfiguration); err != nil {
+			return err
+		}
 	} else {
-		setNodeIPForKindClusters(&ApplicationConfiguration.Configuration.ClusterConfiguration)
+		if err := setNodeIPForKindClusters(ctx, &ApplicationConfiguration.Configuration.ClusterConfiguration); err != nil {
+			return err
+		}
 	}
 
 	util.Printf("Successfully fetched network addresses for clusters.")
+	return nil
 }
 
-func setNodeIPForKindClusters(clusterConfig *ClusterConfiguration) {
+func setNodeIPForKindClusters(ctx context.Context, clusterConfig *ClusterConfiguration) error {
 	clusters := getAllClusters(clusterConfig)
 	for _, cluster := range clusters {
-		ip := runDockerInspectForNodeIP(cluster.Name)
+		ip, err := runDockerInspectForNodeIP(ctx, cluster.Name)
+		if err != nil {
+			return err
+		}
 		cluster.NodeIP = ip
 		cluster.ControlPlaneAddress = "https://" + ip + ":6443"
 		util.Printf("%s Fetched Network Address for %s : %s", util.Tick, cluster.Name, ip)
-		time.Sleep(200 * time.Millisecond)
-
 	}
+	return nil
 }
 
-func runDockerInspectForNodeIP(clusterName string) string {
+func runDockerInspectForNodeIP(ctx context.Context, clusterName string) (string, error) {
 	var outB, errB bytes.Buffer
-	err := util.RunCommandCustomIO("docker", &outB, &errB, true, "inspect", "--format={{.NetworkSettings.Networks.kind.IPAddress}}", fmt.Sprintf("%s-control-plane", clusterName))
+	err := util.RunCommandCustomIO(ctx, "docker", &outB, &errB, true, "inspect", "--format={{.NetworkSettings.Networks.kind.IPAddress}}", fmt.Sprintf("%s-control-plane", clusterName))
 	if err != nil {
-		util.Printf("%s Failed to run command\nOutput: %s\nError: %s %v", util.Cross, outB.String(), errB.String(), err)
-		os.Exit(1)
+		return "", fmt.Errorf("Failed to run command\nOutput: %s\nError: %s %w", outB.String(), errB.String(), err)
+	}
+	if util.DryRun {
+		return dryRunValue("node-ip", clusterName), nil
 	}
-	return strings.TrimSpace(outB.String())
+	return strings.TrimSpace(outB.String()), nil
 }
 
-func setControlPlaneAddress(clusterConfig *ClusterConfiguration) {
+func setControlPlaneAddress(ctx context.Context, clusterConfig *ClusterConfiguration) error {
 	for _, cluster := range getAllClusters(clusterConfig) {
 		if cluster.ControlPlaneAddress == "" {
-			ip := _getControlPlaneAddress(cluster)
+			ip, err := _getControlPlaneAddress(ctx, cluster)
+			if err != nil {
+				return err
+			}
 			cluster.ControlPlaneAddress = ip
 			util.Printf("%s Control Plane Address fetched %s for %s", util.Tick, cluster.ControlPlaneAddress, cluster.Name)
 		}
 	}
+	return nil
 }
 
-func _getControlPlaneAddress(cluster *Cluster) string {
+func _getControlPlaneAddress(ctx context.Context, cluster *Cluster) (string, error) {
 	var outB, errB bytes.Buffer
-	err := util.RunCommandCustomIO("kubectl", &outB, &errB, true, "--context="+cluster.ContextName, "--kubeconfig="+cluster.KubeConfigPath, "config", "view", "--minify=true", "-o", "jsonpath={.clusters[0].cluster.server}")
+	err := util.RunCommandCustomIO(ctx, "kubectl", &outB, &errB, true, "--context="+cluster.ContextName, "--kubeconfig="+cluster.KubeConfigPath, "config", "view", "--minify=true", "-o", "jsonpath={.clusters[0].cluster.server}")
 	if err != nil {
-		util.Printf("%s Failed to run command\nOutput: %s\nError: %s %v", util.Cross, outB.String(), errB.String(), err)
-		os.Exit(1)
+		return "", fmt.Errorf("Failed to run command\nOutput: %s\nError: %s %w", outB.String(), errB.String(), err)
 	}
-	return outB.String()
+	if util.DryRun {
+		return dryRunValue("control-plane-address", cluster.Name), nil
+	}
+	return outB.String(), nil
 }
 
-func setNodeIP(clusterConfig *ClusterConfiguration) {
+func setNodeIP(ctx context.Context, clusterConfig *ClusterConfiguration) error {
 	for _, cluster := range getAllClusters(clusterConfig) {
 		if cluster.NodeIP == "" {
-			ip := _getNodeIP(cluster)
+			ip, err := _getNodeIP(ctx, cluster)
+			if err != nil {
+				return err
+			}
 			cluster.NodeIP = ip
 			util.Printf("%s Node IP fetched %s for %s", util.Tick, cluster.NodeIP, cluster.Name)
 		}
 	}
+	return nil
 }
 
-func _getNodeIP(cluster *Cluster) string {
+func _getNodeIP(ctx context.Context, cluster *Cluster) (string, error) {
 	var outB, errB bytes.Buffer
-	err := util.RunCommandCustomIO("kubectl", &outB, &errB, true, "--context="+cluster.ContextName, "--kubeconfig="+cluster.KubeConfigPath, "get", "nodes", "-o", "jsonpath={\"ExternalIP=\"}{.items[0].status.addresses[?(@.type==\"ExternalIP\")].address}{\"\\n\"}{\"InternalIP=\"}{.items[0].status.addresses[?(@.type==\"InternalIP\")].address}")
+	err := util.RunCommandCustomIO(ctx, "kubectl", &outB, &errB, true, "--context="+cluster.ContextName, "--kubeconfig="+cluster.KubeConfigPath, "get", "nodes", "-o", "jsonpath={\"ExternalIP=\"}{.items[0].status.addresses[?(@.type==\"ExternalIP\")].address}{\"\\n\"}{\"InternalIP=\"}{.items[0].status.addresses[?(@.type==\"InternalIP\")].address}")
 	if err != nil {
-		util.Printf("%s Failed to run command\nOutput: %s\nError: %s %v", util.Cross, outB.String(), errB.String(), err)
-		os.Exit(1)
+		return "", fmt.Errorf("Failed to run command\nOutput: %s\nError: %s %w", outB.String(), errB.String(), err)
+	}
+	if util.DryRun {
+		return dryRunValue("node-ip", cluster.Name), nil
 	}
 	for _, s := range strings.Split(outB.String(), "\n") {
 		splits := strings.Split(s, "=")
-		if strings.TrimSpace(splits[1]) != "" {
-			return strings.TrimSpace(splits[1])
+		if len(splits) > 1 && strings.TrimSpace(splits[1]) != "" {
+			return strings.TrimSpace(splits[1]), nil
 		}
 	}
-	return ""
+	return "", nil
 }
@@ -0,0 +1,449 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/kubeslice/kubeslice-cli/util"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/cli/values"
+	"helm.sh/helm/v3/pkg/getter"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/repo"
+	"helm.sh/helm/v3/pkg/storage/driver"
+)
+
+// Backends of HelmBackend
+const (
+	HelmBackendAuto   = "auto"
+	HelmBackendSDK    = "sdk"
+	HelmBackendBinary = "helm"
+)
+
+// HelmBackend selects the HelmClient of the clusters: the Helm SDK, the helm
+// binary, or auto for the SDK falling back to the binary when the kubeconfig
+// cannot be loaded. Dry runs always use the binary, whose commands they print.
+var HelmBackend = HelmBackendAuto
+
+// HelmReleaseSpec is a release to install or upgrade.
+type HelmReleaseSpec struct {
+	Name      string
+	Namespace string
+	// Chart is <repo alias>/<chart name>, or the path of a local chart
+	Chart string
+	// Version of the chart, the latest if empty
+	Version     string
+	ValuesFiles []string
+	// Set are key=value overrides of the values
+	Set []string
+	// Install installs the release if it does not exist, as upgrade -i does
+	Install         bool
+	CreateNamespace bool
+	// ReuseValues keeps the values of the release, the values files and
+	// overrides are merged into them
+	ReuseValues bool
+}
+
+// HelmClient manages the chart repositories and the releases of a cluster.
+// Releases that do not exist are NotFoundErrors.
+type HelmClient interface {
+	// AddRepository adds or updates the chart repository name, with the
+	// credentials passed to every domain it redirects to if username is set.
+	AddRepository(ctx context.Context, name, url, username, password string) error
+	// UpdateRepositories fetches the latest charts of the repositories.
+	UpdateRepositories(ctx context.Context) error
+	// Upgrade upgrades or installs a release.
+	Upgrade(ctx context.Context, spec HelmReleaseSpec) error
+	// Uninstall uninstalls the release name.
+	Uninstall(ctx context.Context, name, namespace string) error
+	// Rollback rolls the release name back to revision.
+	Rollback(ctx context.Context, name, namespace, revision string) error
+	// Status returns the last revision of the release name.
+	Status(ctx context.Context, name, namespace string) (*HelmRelease, error)
+	// List returns the releases of every namespace, failed ones included.
+	List(ctx context.Context) ([]HelmRelease, error)
+	// GetValues returns the values the release name was deployed with.
+	GetValues(ctx context.Context, name, namespace string) (map[string]interface{}, error)
+}
+
+// NewHelmClient returns the HelmClient of cluster printing to out, cluster is
+// nil for working on the repositories only.
+func NewHelmClient(out *util.Output, cluster *Cluster) (HelmClient, error) {
+	if util.DryRun || HelmBackend == HelmBackendBinary {
+		return &helmBinaryClient{out: out, cluster: cluster}, nil
+	}
+	client := &helmSDKClient{out: out, cluster: cluster}
+	if cluster == nil {
+		return client, nil
+	}
+	_, err := client.settings("").RESTClientGetter().ToRESTConfig()
+	if err == nil {
+		return client, nil
+	}
+	if HelmBackend == HelmBackendSDK {
+		return nil, util.ValidationErrorf("Failed to load the kubeconfig: %w", err)
+	}
+	return &helmBinaryClient{out: out, cluster: cluster}, nil
+}
+
+func helmRelease(r *release.Release) HelmRelease {
+	h := HelmRelease{Name: r.Name, Namespace: r.Namespace, Revision: strconv.Itoa(r.Version)}
+	if r.Chart != nil && r.Chart.Metadata != nil {
+		h.Chart = r.Chart.Metadata.Name + "-" + r.Chart.Metadata.Version
+	}
+	if r.Info != nil {
+		h.Status = r.Info.Status.String()
+	}
+	return h
+}
+
+// helmSDKClient runs the actions of the Helm SDK. They are configured from
+// the HELM_* environment variables, as the helm binary is.
+type helmSDKClient struct {
+	out     *util.Output
+	cluster *Cluster
+	// config, when set, is used instead of the configuration of the cluster
+	config *action.Configuration
+}
+
+func (c *helmSDKClient) settings(namespace string) *cli.EnvSettings {
+	settings := cli.New()
+	if c.cluster != nil {
+		settings.KubeConfig, settings.KubeContext = c.cluster.KubeConfigPath, c.cluster.ContextName
+	}
+	settings.SetNamespace(namespace)
+	return settings
+}
+
+func (c *helmSDKClient) configuration(ctx context.Context, namespace string) (*action.Configuration, *cli.EnvSettings, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, fmt.Errorf("helm not run: %w", err)
+	}
+	settings := c.settings(namespace)
+	if c.config != nil {
+		return c.config, settings, nil
+	}
+	cfg := new(action.Configuration)
+	if err := cfg.Init(settings.RESTClientGetter(), namespace, os.Getenv("HELM_DRIVER"), func(string, ...interface{}) {}); err != nil {
+		return nil, nil, err
+	}
+	return cfg, settings, nil
+}
+
+func (c *helmSDKClient) name() string {
+	if c.cluster == nil {
+		return ""
+	}
+	return " on " + c.cluster.Name
+}
+
+func (c *helmSDKClient) AddRepository(ctx context.Context, name, url, username, password string) error {
+	settings := c.settings("")
+	c.out.Printf("%s Adding helm repository %s %s", util.Run, name, url)
+	file, err := repo.LoadFile(settings.RepositoryConfig)
+	if errors.Is(err, os.ErrNotExist) {
+		file = repo.NewFile()
+	} else if err != nil {
+		return err
+	}
+	entry := &repo.Entry{Name: name, URL: url}
+	if username != "" && password != "" {
+		entry.Username, entry.Password, entry.PassCredentialsAll = username, password, true
+	}
+	if err := downloadIndex(ctx, settings, entry); err != nil {
+		return err
+	}
+	file.Update(entry)
+	if err := os.MkdirAll(filepath.Dir(settings.RepositoryConfig), 0755); err != nil {
+		return err
+	}
+	return file.WriteFile(settings.RepositoryConfig, 0644)
+}
+
+func (c *helmSDKClient) UpdateRepositories(ctx context.Context) error {
+	settings := c.settings("")
+	c.out.Printf("%s Updating helm repositories", util.Run)
+	file, err := repo.LoadFile(settings.RepositoryConfig)
+	if err != nil {
+		return fmt.Errorf("no helm repositories to update %w", err)
+	}
+	for _, entry := range file.Repositories {
+		if err := downloadIndex(ctx, settings, entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func downloadIndex(ctx context.Context, settings *cli.EnvSettings, entry *repo.Entry) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("helm not run: %w", err)
+	}
+	r, err := repo.NewChartRepository(entry, getter.All(settings))
+	if err != nil {
+		return err
+	}
+	r.CachePath = settings.RepositoryCache
+	if _, err := r.DownloadIndexFile(); err != nil {
+		return fmt.Errorf("failed to reach helm repository %s %s: %w", entry.Name, entry.URL, err)
+	}
+	return nil
+}
+
+func (c *helmSDKClient) Upgrade(ctx context.Context, spec HelmReleaseSpec) error {
+	cfg, settings, err := c.configuration(ctx, spec.Namespace)
+	if err != nil {
+		return err
+	}
+	install := false
+	if spec.Install {
+		history := action.NewHistory(cfg)
+		history.Max = 1
+		if _, err := history.Run(spec.Name); errors.Is(err, driver.ErrReleaseNotFound) {
+			install = true
+		} else if err != nil {
+			return err
+		}
+	}
+	options := action.ChartPathOptions{Version: spec.Version}
+	path, err := options.LocateChart(spec.Chart, settings)
+	if err != nil {
+		return err
+	}
+	chart, err := loader.Load(path)
+	if err != nil {
+		return err
+	}
+	vals, err := (&values.Options{ValueFiles: spec.ValuesFiles, Values: spec.Set}).MergeValues(getter.All(settings))
+	if err != nil {
+		return err
+	}
+	if install {
+		c.out.Printf("%s Installing helm release %s/%s from %s %s%s", util.Run, spec.Namespace, spec.Name, spec.Chart, chart.Metadata.Version, c.name())
+		i := action.NewInstall(cfg)
+		i.ReleaseName, i.Namespace, i.CreateNamespace = spec.Name, spec.Namespace, spec.CreateNamespace
+		_, err = i.RunWithContext(ctx, chart, vals)
+		return err
+	}
+	c.out.Printf("%s Upgrading helm release %s/%s to %s %s%s", util.Run, spec.Namespace, spec.Name, spec.Chart, chart.Metadata.Version, c.name())
+	u := action.NewUpgrade(cfg)
+	u.Namespace, u.ReuseValues = spec.Namespace, spec.ReuseValues
+	_, err = u.RunWithContext(ctx, spec.Name, chart, vals)
+	return err
+}
+
+func (c *helmSDKClient) Uninstall(ctx context.Context, name, namespace string) error {
+	cfg, _, err := c.configuration(ctx, namespace)
+	if err != nil {
+		return err
+	}
+	c.out.Printf("%s Uninstalling helm release %s/%s%s", util.Run, namespace, name, c.name())
+	if _, err := action.NewUninstall(cfg).Run(name); err != nil {
+		return releaseError(err, name)
+	}
+	return nil
+}
+
+func (c *helmSDKClient) Rollback(ctx context.Context, name, namespace, revision string) error {
+	cfg, _, err := c.configuration(ctx, namespace)
+	if err != nil {
+		return err
+	}
+	version, err := strconv.Atoi(revision)
+	if err != nil {
+		return util.ValidationErrorf("Invalid revision %s of release %s", revision, name)
+	}
+	c.out.Printf("%s Rolling helm release %s/%s back to revision %s%s", util.Run, namespace, name, revision, c.name())
+	rollback := action.NewRollback(cfg)
+	rollback.Version = version
+	return releaseError(rollback.Run(name), name)
+}
+
+func (c *helmSDKClient) Status(ctx context.Context, name, namespace string) (*HelmRelease, error) {
+	cfg, _, err := c.configuration(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	r, err := action.NewStatus(cfg).Run(name)
+	if err != nil {
+		return nil, releaseError(err, name)
+	}
+	h := helmRelease(r)
+	return &h, nil
+}
+
+func (c *helmSDKClient) List(ctx context.Context) ([]HelmRelease, error) {
+	cfg, _, err := c.configuration(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	list := action.NewList(cfg)
+	list.AllNamespaces, list.StateMask = true, action.ListAll
+	found, err := list.Run()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list helm releases %w", err)
+	}
+	releases := make([]HelmRelease, 0, len(found))
+	for _, r := range found {
+		releases = append(releases, helmRelease(r))
+	}
+	return releases, nil
+}
+
+func (c *helmSDKClient) GetValues(ctx context.Context, name, namespace string) (map[string]interface{}, error) {
+	cfg, _, err := c.configuration(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	values, err := action.NewGetValues(cfg).Run(name)
+	if err != nil {
+		return nil, releaseError(err, name)
+	}
+	if values == nil {
+		values = make(map[string]interface{})
+	}
+	return values, nil
+}
+
+func releaseError(err error, name string) error {
+	if errors.Is(err, driver.ErrReleaseNotFound) {
+		return util.NotFoundErrorf("release %s not found", name)
+	}
+	return err
+}
+
+// helmBinaryClient runs the helm binary.
+type helmBinaryClient struct {
+	out     *util.Output
+	cluster *Cluster
+}
+
+func (c *helmBinaryClient) args(args ...string) []string {
+	if c.cluster == nil {
+		return args
+	}
+	return append([]string{"--kube-context", c.cluster.ContextName, "--kubeconfig", c.cluster.KubeConfigPath}, args...)
+}
+
+func (c *helmBinaryClient) run(ctx context.Context, args ...string) error {
+	if err := c.out.RunCommand(ctx, "helm", c.args(args...)...); err != nil {
+		return fmt.Errorf("Process failed %w", err)
+	}
+	return nil
+}
+
+// output runs helm without printing it, and returns what it printed.
+func (c *helmBinaryClient) output(ctx context.Context, name string, args ...string) ([]byte, error) {
+	var outB, errB bytes.Buffer
+	if err := c.out.RunCommandCustomIO(ctx, "helm", &outB, &errB, true, c.args(args...)...); err != nil {
+		if name != "" && strings.Contains(errB.String(), "not found") {
+			return nil, util.NotFoundErrorf("release %s not found", name)
+		}
+		return nil, fmt.Errorf("Process failed %w\n%s", err, errB.String())
+	}
+	return outB.Bytes(), nil
+}
+
+func (c *helmBinaryClient) AddRepository(ctx context.Context, name, url, username, password string) error {
+	args := []string{"repo", "add", name, url, "--force-update"}
+	if username == "" || password == "" {
+		return c.run(ctx, args...)
+	}
+	// the password goes through stdin, not to be seen in the process list
+	args = append(args, "--pass-credentials", "--username", username, "--password-stdin")
+	if err := c.out.RunCommandWithInput(ctx, "helm", password, c.args(args...)...); err != nil {
+		return fmt.Errorf("Process failed %w", err)
+	}
+	return nil
+}
+
+func (c *helmBinaryClient) UpdateRepositories(ctx context.Context) error {
+	return c.run(ctx, "repo", "update")
+}
+
+func (c *helmBinaryClient) Upgrade(ctx context.Context, spec HelmReleaseSpec) error {
+	args := []string{"upgrade"}
+	if spec.Install {
+		args = append(args, "-i")
+	}
+	args = append(args, spec.Name, spec.Chart, "--namespace", spec.Namespace)
+	if spec.CreateNamespace {
+		args = append(args, "--create-namespace")
+	}
+	for _, file := range spec.ValuesFiles {
+		args = append(args, "-f", file)
+	}
+	for _, value := range spec.Set {
+		args = append(args, "--set", value)
+	}
+	if spec.ReuseValues {
+		args = append(args, "--reuse-values")
+	}
+	if spec.Version != "" {
+		args = append(args, "--version", spec.Version)
+	}
+	return c.run(ctx, args...)
+}
+
+func (c *helmBinaryClient) Uninstall(ctx context.Context, name, namespace string) error {
+	return c.run(ctx, "uninstall", name, "--namespace", namespace)
+}
+
+func (c *helmBinaryClient) Rollback(ctx context.Context, name, namespace, revision string) error {
+	return c.run(ctx, "rollback", name, revision, "--namespace", namespace)
+}
+
+func (c *helmBinaryClient) Status(ctx context.Context, name, namespace string) (*HelmRelease, error) {
+	data, err := c.output(ctx, name, "status", name, "--namespace", namespace, "-o", "json")
+	if err != nil {
+		return nil, err
+	}
+	if util.DryRun {
+		return &HelmRelease{Name: name, Namespace: namespace}, nil
+	}
+	var r release.Release
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("failed to read the status of %s %v", name, err)
+	}
+	h := helmRelease(&r)
+	return &h, nil
+}
+
+func (c *helmBinaryClient) List(ctx context.Context) ([]HelmRelease, error) {
+	data, err := c.output(ctx, "", "list", "--all", "--all-namespaces", "-o", "json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list helm releases %w", err)
+	}
+	releases := make([]HelmRelease, 0)
+	if util.DryRun {
+		return releases, nil
+	}
+	if err := json.Unmarshal(data, &releases); err != nil {
+		return nil, fmt.Errorf("failed to read helm releases %v", err)
+	}
+	return releases, nil
+}
+
+func (c *helmBinaryClient) GetValues(ctx context.Context, name, namespace string) (map[string]interface{}, error) {
+	data, err := c.output(ctx, name, "get", "values", name, "--namespace", namespace, "-o", "json")
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]interface{})
+	if util.DryRun {
+		return values, nil
+	}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("failed to read the values of %s %v", name, err)
+	}
+	return values, nil
+}
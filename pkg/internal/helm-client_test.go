@@ -0,0 +1,146 @@
+package internal
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kubeslice/kubeslice-cli/util"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chartutil"
+	kubefake "helm.sh/helm/v3/pkg/kube/fake"
+	"helm.sh/helm/v3/pkg/storage"
+	"helm.sh/helm/v3/pkg/storage/driver"
+)
+
+// not parallel, as it sets the executor, HelmBackend and ExecutablePaths
+func TestHelmBinaryAddRepository(t *testing.T) {
+	backend := HelmBackend
+	HelmBackend, util.ExecutablePaths = HelmBackendBinary, map[string]string{"helm": "helm"}
+	t.Cleanup(func() { HelmBackend, util.ExecutablePaths = backend, nil })
+	fake := &util.FakeExecutor{}
+	defer util.SetExecutor(util.SetExecutor(fake))
+
+	client, err := NewHelmClient(nil, nil)
+	if err != nil {
+		t.Fatalf("NewHelmClient() returned unexpected error %v", err)
+	}
+	if err := client.AddRepository(context.Background(), "kubeslice", "https://charts", "robot", "s3cret"); err != nil {
+		t.Fatalf("AddRepository() returned unexpected error %v", err)
+	}
+	want := "helm repo add kubeslice https://charts --force-update --pass-credentials --username robot --password-stdin"
+	if got := fake.Commands(); strings.Join(got, "\n") != want {
+		t.Errorf("AddRepository() ran %q, want %q", got, want)
+	}
+	if got := fake.Inputs(); len(got) != 1 || got[0] != "s3cret" {
+		t.Errorf("AddRepository() wrote %q to helm, want the password", got)
+	}
+}
+
+// sdkClient returns a helmSDKClient keeping its releases in memory, and the
+// directory of a chart rendering its values into a ConfigMap.
+func sdkClient(t *testing.T) (*helmSDKClient, string) {
+	t.Helper()
+	chart := t.TempDir()
+	files := map[string]string{
+		"Chart.yaml":               "apiVersion: v2\nname: demo\nversion: 0.1.0\n",
+		"values.yaml":              "replicas: 1\n",
+		"templates/configmap.yaml": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: demo\ndata:\n  replicas: {{ .Values.replicas | quote }}\n",
+	}
+	for name, content := range files {
+		fileName := filepath.Join(chart, name)
+		if err := os.MkdirAll(filepath.Dir(fileName), 0755); err != nil {
+			t.Fatalf("Failed to setup test: %v", err)
+		}
+		if err := os.WriteFile(fileName, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to setup test: %v", err)
+		}
+	}
+	memory := driver.NewMemory()
+	memory.SetNamespace("kubeslice-system")
+	config := &action.Configuration{
+		Releases:     storage.Init(memory),
+		KubeClient:   &kubefake.PrintingKubeClient{Out: io.Discard},
+		Capabilities: chartutil.DefaultCapabilities,
+		Log:          func(string, ...interface{}) {},
+	}
+	return &helmSDKClient{out: util.ClusterOutput("kind-worker"), config: config}, chart
+}
+
+func TestHelmSDKUpgrade(t *testing.T) {
+	t.Parallel()
+
+	client, chart := sdkClient(t)
+	ctx := context.Background()
+	spec := HelmReleaseSpec{Name: "demo", Namespace: "kubeslice-system", Chart: chart, Install: true, Set: []string{"replicas=2", "color=red"}}
+	if err := client.Upgrade(ctx, spec); err != nil {
+		t.Fatalf("Upgrade() returned unexpected error %v", err)
+	}
+	r, err := client.config.Releases.Last("demo")
+	if err != nil || r.Version != 1 || r.Info.Description != "Install complete" {
+		t.Fatalf("Upgrade() of a missing release left %v, %v, want it installed", r, err)
+	}
+
+	// upgrading keeps the values set before only with ReuseValues
+	tests := []struct {
+		name        string
+		reuseValues bool
+		want        map[string]interface{}
+	}{
+		{name: "reusing values", reuseValues: true, want: map[string]interface{}{"replicas": int64(3), "color": "red"}},
+		{name: "resetting values", want: map[string]interface{}{"replicas": int64(3)}},
+	}
+	for _, tc := range tests {
+		spec.Set, spec.ReuseValues = []string{"replicas=3"}, tc.reuseValues
+		if err := client.Upgrade(ctx, spec); err != nil {
+			t.Fatalf("Upgrade() %s returned unexpected error %v", tc.name, err)
+		}
+		r, err := client.config.Releases.Last("demo")
+		if err != nil || r.Info.Description != "Upgrade complete" {
+			t.Errorf("Upgrade() %s of an installed release left %v, %v, want it upgraded", tc.name, r, err)
+		}
+		values, err := client.GetValues(ctx, "demo", "kubeslice-system")
+		if err != nil {
+			t.Fatalf("GetValues() returned unexpected error %v", err)
+		}
+		if !reflect.DeepEqual(values, tc.want) {
+			t.Errorf("Upgrade() %s deployed values %v, want %v", tc.name, values, tc.want)
+		}
+	}
+}
+
+func TestHelmSDKRollback(t *testing.T) {
+	t.Parallel()
+
+	client, chart := sdkClient(t)
+	ctx := context.Background()
+	for _, replicas := range []string{"replicas=2", "replicas=3"} {
+		spec := HelmReleaseSpec{Name: "demo", Namespace: "kubeslice-system", Chart: chart, Install: true, Set: []string{replicas}}
+		if err := client.Upgrade(ctx, spec); err != nil {
+			t.Fatalf("Failed to setup test: %v", err)
+		}
+	}
+
+	if err := client.Rollback(ctx, "demo", "kubeslice-system", "1"); err != nil {
+		t.Fatalf("Rollback() returned unexpected error %v", err)
+	}
+	release, err := client.Status(ctx, "demo", "kubeslice-system")
+	if err != nil || release.Revision != "3" {
+		t.Errorf("Status() after Rollback() = %v, %v, want revision 3", release, err)
+	}
+	values, err := client.GetValues(ctx, "demo", "kubeslice-system")
+	if want := map[string]interface{}{"replicas": int64(2)}; err != nil || !reflect.DeepEqual(values, want) {
+		t.Errorf("Rollback() to revision 1 deployed values %v, %v, want %v", values, err, want)
+	}
+
+	if err := client.Rollback(ctx, "demo", "kubeslice-system", "previous"); util.ExitCode(err) != util.ExitValidation {
+		t.Errorf("Rollback() to revision previous = %v, want a ValidationError", err)
+	}
+	if err := client.Rollback(ctx, "missing", "kubeslice-system", "1"); util.ExitCode(err) != util.ExitNotFound {
+		t.Errorf("Rollback() of a missing release = %v, want a NotFoundError", err)
+	}
+}
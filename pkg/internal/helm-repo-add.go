@@ -1,9 +1,8 @@
 package internal
 
 import (
+	"context"
 	"fmt"
-	"log"
-	"time"
 
 	"github.com/kubeslice/kubeslice-cli/util"
 )
@@ -18,7 +17,7 @@ imagePullSecrets:
 
 `
 
-func AddHelmCharts(ApplicationConfiguration *ConfigurationSpecs) {
+func AddHelmCharts(ctx context.Context, ApplicationConfiguration *ConfigurationSpecs) error {
 	hc := ApplicationConfiguration.Configuration.HelmChartConfiguration
 	// helm repo add avesha https://kubeslice.github.io/kubeslice/
 	if hc.UseLocal {
@@ -26,36 +25,36 @@ func AddHelmCharts(ApplicationConfiguration *ConfigurationSpecs) {
 	} else {
 		util.Printf("\nAdding KubeSlice Helm Charts...")
 
-		addHelmChart(ApplicationConfiguration)
+		if err := addHelmChart(ctx, ApplicationConfiguration); err != nil {
+			return err
+		}
 		util.Printf("%s Successfully added helm repo %s : %s", util.Tick, hc.RepoAlias, hc.RepoUrl)
-		time.Sleep(200 * time.Millisecond)
 
-		updateHelmChart()
+		if err := updateHelmChart(ctx); err != nil {
+			return err
+		}
 		util.Printf("%s Successfully updated helm repo", util.Tick)
-		time.Sleep(200 * time.Millisecond)
 
 		util.Printf("%s Successfully added helm charts.\n", util.Tick)
 	}
+	return nil
 }
 
-func addHelmChart(ApplicationConfiguration *ConfigurationSpecs) {
+func addHelmChart(ctx context.Context, ApplicationConfiguration *ConfigurationSpecs) error {
 	hc := ApplicationConfiguration.Configuration.HelmChartConfiguration
-	repoAddCommands := make([]string, 0)
-	repoAddCommands = append(repoAddCommands, "repo", "add", hc.RepoAlias, hc.RepoUrl, "--force-update")
-	if hc.HelmUsername != "" && hc.HelmPassword != "" {
-		repoAddCommands = append(repoAddCommands, "--pass-credentials", "--username", hc.HelmUsername, "--password", hc.HelmPassword)
-	}
-	err := util.RunCommand("helm", repoAddCommands...)
+	client, err := NewHelmClient(nil, nil)
 	if err != nil {
-		log.Fatalf("Process failed %v", err)
+		return err
 	}
+	return client.AddRepository(ctx, hc.RepoAlias, hc.RepoUrl, hc.HelmUsername, hc.HelmPassword)
 }
 
-func updateHelmChart() {
-	err := util.RunCommand("helm", "repo", "update")
+func updateHelmChart(ctx context.Context) error {
+	client, err := NewHelmClient(nil, nil)
 	if err != nil {
-		log.Fatalf("Process failed %v", err)
+		return err
 	}
+	return client.UpdateRepositories(ctx)
 }
 
 func generateImagePullSecretsValue(ImagePullSecret ImagePullSecrets) string {
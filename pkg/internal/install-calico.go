@@ -2,59 +2,82 @@ package internal
 
 import (
 	"bytes"
-	"log"
+	"context"
+	"fmt"
 	"strings"
-	"time"
 
 	"github.com/kubeslice/kubeslice-cli/util"
 )
 
-func InstallCalico(clusterConfig *ClusterConfiguration) {
+func InstallCalico(ctx context.Context, clusterConfig *ClusterConfiguration) error {
 	util.Printf("\nInstalling Calico Networking...")
 
-	clusters := getAllClusters(clusterConfig)
-	for _, cluster := range clusters {
-		if !calicoAlreadyInstalled(cluster) {
-			util.Printf("Installing on Cluster %s", cluster.Name)
-			installCalicoOperatorPrerequisites(cluster)
-			util.Printf("%s Successfully applied Calico Operator Prerequisites on Cluster %s", util.Tick, cluster.Name)
-			time.Sleep(200 * time.Millisecond)
+	err := forEachCluster(ctx, getAllClusters(clusterConfig), installCalico)
+	if err := clusterErrors("Installing Calico Networking", err); err != nil {
+		return err
+	}
 
-			createCalicoOperator(cluster)
-			util.Printf("%s Successfully installed Calico Operator on Cluster %s", util.Tick, cluster.Name)
-			time.Sleep(200 * time.Millisecond)
+	util.Printf("%s Successfully installed Calico Networking", util.Tick)
+	return nil
+}
 
-			util.Printf("%s Waiting for Calico Pods to be Healthy on Cluster %s...", util.Wait, cluster.Name)
-			PodVerification("Waiting for Calico Pods to be Healthy", *cluster, "calico-system")
-		}
+func installCalico(ctx context.Context, out *util.Output, cluster *Cluster) error {
+	installed, err := calicoAlreadyInstalled(ctx, out, cluster)
+	if err != nil || installed {
+		return err
+	}
+	out.Printf("Installing on Cluster %s", cluster.Name)
+	if err := installCalicoOperatorPrerequisites(ctx, out, cluster); err != nil {
+		return err
 	}
+	out.Printf("%s Successfully applied Calico Operator Prerequisites on Cluster %s", util.Tick, cluster.Name)
 
-	util.Printf("%s Successfully installed Calico Networking", util.Tick)
+	if err := createCalicoOperator(ctx, out, cluster); err != nil {
+		return err
+	}
+	out.Printf("%s Successfully installed Calico Operator on Cluster %s", util.Tick, cluster.Name)
+
+	out.Printf("%s Waiting for Calico Pods to be Healthy on Cluster %s...", util.Wait, cluster.Name)
+	return podVerification(ctx, out, "Waiting for Calico Pods to be Healthy", *cluster, "calico-system")
 }
 
-func calicoAlreadyInstalled(cluster *Cluster) bool {
+func calicoAlreadyInstalled(ctx context.Context, out *util.Output, cluster *Cluster) (bool, error) {
+	if util.DryRun {
+		return false, nil
+	}
 	var outB, errB bytes.Buffer
-	err := util.RunCommandCustomIO("kubectl", &outB, &errB, true, "--context="+cluster.ContextName, "--kubeconfig="+cluster.KubeConfigPath, "get", "namespace", "calico-system")
+	err := util.RunCommandCustomIO(ctx, "kubectl", &outB, &errB, true, "--context="+cluster.ContextName, "--kubeconfig="+cluster.KubeConfigPath, "get", "namespace", "calico-system")
 	if err != nil {
 		if strings.Contains(errB.String(), "NotFound") {
-			return false
+			return false, nil
 		}
 	}
-	PodVerification("Waiting for Calico Pods to be Healthy", *cluster, "calico-system")
-	util.Printf("%s Calico Networking already present on cluster %s", util.Tick, cluster.Name)
-	return true
+	if err := podVerification(ctx, out, "Waiting for Calico Pods to be Healthy", *cluster, "calico-system"); err != nil {
+		return false, err
+	}
+	out.Printf("%s Calico Networking already present on cluster %s", util.Tick, cluster.Name)
+	return true, nil
 }
 
-func installCalicoOperatorPrerequisites(cluster *Cluster) {
-	err := util.RunCommand("kubectl", "--context="+cluster.ContextName, "--kubeconfig="+cluster.KubeConfigPath, "create", "-f", "https://raw.githubusercontent.com/projectcalico/calico/v3.24.0/manifests/tigera-operator.yaml")
+const (
+	calicoOperatorManifest        = "https://raw.githubusercontent.com/projectcalico/calico/v3.24.0/manifests/tigera-operator.yaml"
+	calicoCustomResourcesManifest = "https://raw.githubusercontent.com/projectcalico/calico/v3.24.0/manifests/custom-resources.yaml"
+)
+
+func installCalicoOperatorPrerequisites(ctx context.Context, out *util.Output, cluster *Cluster) error {
+	recordManifestURL(calicoOperatorManifest, cluster)
+	err := out.RunCommand(ctx, "kubectl", "--context="+cluster.ContextName, "--kubeconfig="+cluster.KubeConfigPath, "create", "-f", calicoOperatorManifest)
 	if err != nil {
-		log.Fatalf("Process failed %v", err)
+		return fmt.Errorf("Process failed %w", err)
 	}
+	return nil
 }
 
-func createCalicoOperator(cluster *Cluster) {
-	err := util.RunCommand("kubectl", "--context="+cluster.ContextName, "--kubeconfig="+cluster.KubeConfigPath, "create", "-f", "https://raw.githubusercontent.com/projectcalico/calico/v3.24.0/manifests/custom-resources.yaml")
+func createCalicoOperator(ctx context.Context, out *util.Output, cluster *Cluster) error {
+	recordManifestURL(calicoCustomResourcesManifest, cluster)
+	err := out.RunCommand(ctx, "kubectl", "--context="+cluster.ContextName, "--kubeconfig="+cluster.KubeConfigPath, "create", "-f", calicoCustomResourcesManifest)
 	if err != nil {
-		log.Fatalf("Process failed %v", err)
+		return fmt.Errorf("Process failed %w", err)
 	}
+	return nil
 }
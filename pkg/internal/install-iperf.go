@@ -1,8 +1,8 @@
 package internal
 
 import (
-	"log"
-	"time"
+	"context"
+	"fmt"
 
 	"github.com/kubeslice/kubeslice-cli/util"
 )
@@ -118,7 +118,7 @@ spec:
           privileged: true
 `
 
-func InstallIPerf(ApplicationConfiguration *ConfigurationSpecs) {
+func InstallIPerf(ctx context.Context, ApplicationConfiguration *ConfigurationSpecs) error {
 	util.Printf("\nInstalling iPerf Application...")
 
 	clientFileName := iPerfClientFileName
@@ -126,60 +126,77 @@ func InstallIPerf(ApplicationConfiguration *ConfigurationSpecs) {
 	cc := ApplicationConfiguration.Configuration.ClusterConfiguration
 	wc := cc.WorkerClusters
 
-	ApplyKubectlManifest(kubesliceDirectory+"/"+serverFileName, "iperf", &wc[0])
+	if err := ApplyKubectlManifest(ctx, kubesliceDirectory+"/"+serverFileName, "iperf", &wc[0]); err != nil {
+		return err
+	}
 	util.Printf("%s Applied %s to %s", util.Tick, serverFileName, wc[0].Name)
-	time.Sleep(200 * time.Millisecond)
 
 	util.Printf("%s Waiting for iPerf Server pod to be running...", util.Wait)
-	PodVerification("Waiting for iPerf Server pod to be running", wc[0], "iperf")
+	if err := PodVerification(ctx, "Waiting for iPerf Server pod to be running", wc[0], "iperf"); err != nil {
+		return err
+	}
 	util.Printf("%s Successfully installed iPerf Server on %s...", util.Tick, wc[0].Name)
 
 	for i := 1; i < len(wc); i++ {
-		ApplyKubectlManifest(kubesliceDirectory+"/"+clientFileName, "iperf", &wc[i])
+		if err := ApplyKubectlManifest(ctx, kubesliceDirectory+"/"+clientFileName, "iperf", &wc[i]); err != nil {
+			return err
+		}
 		util.Printf("%s Applied %s to %s", util.Tick, clientFileName, wc[i].Name)
-		time.Sleep(200 * time.Millisecond)
 
 		util.Printf("%s Waiting for iPerf Client pod to be running...", util.Wait)
-		PodVerification("Waiting for iPerf Client pod to be running", wc[i], "iperf")
+		if err := PodVerification(ctx, "Waiting for iPerf Client pod to be running", wc[i], "iperf"); err != nil {
+			return err
+		}
 		util.Printf("%s Successfully installed iPerf Client on %s...", util.Tick, wc[i].Name)
 	}
 
 	util.Printf("Installed IPerf Applications")
+	return nil
 }
 
-func GenerateIPerfManifests() {
+func GenerateIPerfManifests() error {
 	// --- Client Manifests
-	util.DumpFile(iPerfClientTemplate, kubesliceDirectory+"/"+iPerfClientFileName)
+	if err := util.DumpFile(iPerfClientTemplate, kubesliceDirectory+"/"+iPerfClientFileName); err != nil {
+		return err
+	}
 	util.Printf("%s Generated iPerf Client manifest %s", util.Tick, iPerfClientFileName)
-	time.Sleep(200 * time.Millisecond)
 
 	// --- Server Manifests
-	util.DumpFile(iPerfServerTemplate, kubesliceDirectory+"/"+iPerfServerFileName)
+	if err := util.DumpFile(iPerfServerTemplate, kubesliceDirectory+"/"+iPerfServerFileName); err != nil {
+		return err
+	}
 	util.Printf("%s Generated iPerf Server manifest %s", util.Tick, iPerfServerFileName)
-	time.Sleep(200 * time.Millisecond)
+	return nil
 }
 
-func GenerateIPerfServiceExportManifest(ApplicationConfiguration *ConfigurationSpecs) {
-	util.DumpFile(iPerfServiceExportTemplate, kubesliceDirectory+"/"+iPerfServerServiceExportFileName)
+func GenerateIPerfServiceExportManifest(ApplicationConfiguration *ConfigurationSpecs) error {
+	if err := util.DumpFile(iPerfServiceExportTemplate, kubesliceDirectory+"/"+iPerfServerServiceExportFileName); err != nil {
+		return err
+	}
 	util.Printf("%s Generated iPerf Server Service Export manifest %s for cluster %s", util.Tick, iPerfServerServiceExportFileName, ApplicationConfiguration.Configuration.ClusterConfiguration.WorkerClusters[0].Name)
-	time.Sleep(200 * time.Millisecond)
+	return nil
 }
 
-func ApplyIPerfServiceExportManifest(ApplicationConfiguration *ConfigurationSpecs) {
-	ApplyKubectlManifest(kubesliceDirectory+"/"+iPerfServerServiceExportFileName, "iperf", &ApplicationConfiguration.Configuration.ClusterConfiguration.WorkerClusters[0])
+func ApplyIPerfServiceExportManifest(ctx context.Context, ApplicationConfiguration *ConfigurationSpecs) error {
+	return ApplyKubectlManifest(ctx, kubesliceDirectory+"/"+iPerfServerServiceExportFileName, "iperf", &ApplicationConfiguration.Configuration.ClusterConfiguration.WorkerClusters[0])
 }
 
-func RolloutRestartIPerf(ApplicationConfiguration *ConfigurationSpecs) {
+// RolloutRestartIPerf restarts the iPerf pods so that they join the slice,
+// and waits for them to roll out.
+func RolloutRestartIPerf(ctx context.Context, ApplicationConfiguration *ConfigurationSpecs) error {
 	clusters := getAllClusters(&ApplicationConfiguration.Configuration.ClusterConfiguration)[1:]
-	err := util.RunCommand("kubectl", "rollout", "restart", "deployment/iperf-server", "-n", "iperf", "--context="+clusters[0].ContextName, "--kubeconfig="+clusters[0].KubeConfigPath)
-	if err != nil {
-		log.Fatalf("Process failed %v", err)
-	}
-	for i := 1; i < len(clusters); i++ {
-		err = util.RunCommand("kubectl", "rollout", "restart", "deployment/iperf-sleep", "-n", "iperf", "--context="+clusters[i].ContextName, "--kubeconfig="+clusters[i].KubeConfigPath)
+	for i, cluster := range clusters {
+		deployment := "iperf-sleep"
+		if i == 0 {
+			deployment = "iperf-server"
+		}
+		err := util.RunCommand(ctx, "kubectl", "rollout", "restart", "deployment/"+deployment, "-n", "iperf", "--context="+cluster.ContextName, "--kubeconfig="+cluster.KubeConfigPath)
 		if err != nil {
-			log.Fatalf("Process failed %v", err)
+			return fmt.Errorf("Process failed %w", err)
+		}
+		if err := waitForRollout(ctx, nil, cluster, "iperf", deployment); err != nil {
+			return err
 		}
 	}
-
+	return nil
 }
@@ -0,0 +1,256 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/kubeslice/kubeslice-cli/util"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// InstallJournal records the kind clusters, helm releases and objects an
+// atomic install created or changed, so that they can be undone when the
+// install fails.
+type InstallJournal struct {
+	lock    sync.Mutex
+	steps   []string
+	changes []installChange
+}
+
+type installChange struct {
+	description string
+	undo        func(ctx context.Context) error
+}
+
+// journal is the journal of the running atomic install, nil when the install
+// is not atomic.
+var journal *InstallJournal
+
+// StartInstallJournal records what the install does from now on.
+func StartInstallJournal() *InstallJournal {
+	journal = &InstallJournal{}
+	return journal
+}
+
+// StopInstallJournal stops recording, once the install succeeded.
+func StopInstallJournal() {
+	journal = nil
+}
+
+// BeginStep records that step started, its changes are recorded after it.
+func (j *InstallJournal) BeginStep(step string) {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	j.steps = append(j.steps, step)
+}
+
+// Steps returns the install steps that started.
+func (j *InstallJournal) Steps() []string {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	return append([]string{}, j.steps...)
+}
+
+func (j *InstallJournal) record(description string, undo func(ctx context.Context) error) {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	j.changes = append(j.changes, installChange{description: description, undo: undo})
+}
+
+// Undo undoes the recorded changes, the last one first. It keeps going when
+// a change cannot be undone, and returns what was undone and what failed.
+// The context of the install may be done already, ctx is the one to undo in.
+func (j *InstallJournal) Undo(ctx context.Context) (undone []string, failed []string) {
+	j.lock.Lock()
+	changes := j.changes
+	j.changes = nil
+	j.lock.Unlock()
+	for i := len(changes) - 1; i >= 0; i-- {
+		if err := changes[i].undo(ctx); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", changes[i].description, err))
+			continue
+		}
+		undone = append(undone, changes[i].description)
+	}
+	return undone, failed
+}
+
+// recordKindCluster records that the kind cluster is about to be created.
+func recordKindCluster(name string) {
+	if journal == nil {
+		return
+	}
+	journal.record("delete kind cluster "+name, func(ctx context.Context) error {
+		if err := util.RunCommand(ctx, "kind", "delete", "cluster", "--name", name); err != nil {
+			return fmt.Errorf("Process failed %w", err)
+		}
+		return nil
+	})
+}
+
+// recordRelease records that the release is about to be installed or
+// upgraded on cluster. Undoing it rolls an existing release back to the
+// revision it had, and uninstalls a new one.
+func recordRelease(ctx context.Context, cluster Cluster, name, namespace string) {
+	if journal == nil {
+		return
+	}
+	releases, err := ListHelmReleases(ctx, &cluster)
+	if err != nil {
+		journal.record(fmt.Sprintf("restore release %s/%s on %s", namespace, name, cluster.Name), func(ctx context.Context) error {
+			return fmt.Errorf("its revision before the install is unknown, %v", err)
+		})
+		return
+	}
+	if release, ok := releases[namespace+"/"+name]; ok {
+		journal.record(fmt.Sprintf("roll release %s/%s on %s back to revision %s", namespace, name, cluster.Name, release.Revision), func(ctx context.Context) error {
+			client, err := NewHelmClient(nil, &cluster)
+			if err != nil {
+				return err
+			}
+			return client.Rollback(ctx, name, namespace, release.Revision)
+		})
+		return
+	}
+	journal.record(fmt.Sprintf("uninstall release %s/%s from %s", namespace, name, cluster.Name), func(ctx context.Context) error {
+		releases, err := ListHelmReleases(ctx, &cluster)
+		if err != nil {
+			return err
+		}
+		// the install may have failed before creating it
+		if _, ok := releases[namespace+"/"+name]; !ok && !util.DryRun {
+			return nil
+		}
+		client, err := NewHelmClient(nil, &cluster)
+		if err != nil {
+			return err
+		}
+		return client.Uninstall(ctx, name, namespace)
+	})
+}
+
+// recordManifest records that the manifest is about to be applied on
+// cluster. Undoing it deletes the objects of the manifest that did not exist
+// before, the objects it changed are left as they are.
+func recordManifest(ctx context.Context, fileName, namespace string, cluster *Cluster) {
+	if journal == nil || cluster == nil {
+		return
+	}
+	description := fmt.Sprintf("delete the objects of %s from %s", fileName, cluster.Name)
+	objects, err := readManifestObjects(fileName, namespace)
+	if err != nil {
+		journal.record(description, func(ctx context.Context) error {
+			return fmt.Errorf("the objects of the manifest are unknown, %v", err)
+		})
+		return
+	}
+	existing, err := existingObjects(ctx, objects, cluster)
+	if err != nil {
+		journal.record(description, func(ctx context.Context) error {
+			return fmt.Errorf("the objects existing before the install are unknown, %v", err)
+		})
+		return
+	}
+	journal.record(description, func(ctx context.Context) error {
+		client, err := NewKubeClient(cluster)
+		if err != nil {
+			return err
+		}
+		// the objects are deleted in reverse order, dependents first
+		for i := len(objects) - 1; i >= 0; i-- {
+			object := objects[i]
+			if existing[object] {
+				continue
+			}
+			err := client.Delete(ctx, object.resource, object.namespace, object.name)
+			// the install may have failed before creating it
+			if err != nil && util.ExitCode(err) != util.ExitNotFound {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// manifestObject is an object of a manifest, resource being how KubeClient
+// and kubectl name its kind.
+type manifestObject struct {
+	resource  string
+	namespace string
+	name      string
+}
+
+// readManifestObjects returns the objects of the manifest file, the ones
+// without a namespace being in namespace.
+func readManifestObjects(fileName, namespace string) ([]manifestObject, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	objects := make([]manifestObject, 0)
+	decoder := yaml.NewYAMLOrJSONDecoder(file, 4096)
+	for {
+		obj := &unstructured.Unstructured{}
+		if err := decoder.Decode(&obj.Object); err != nil {
+			if errors.Is(err, io.EOF) {
+				return objects, nil
+			}
+			return nil, fmt.Errorf("failed to read %s: %w", fileName, err)
+		}
+		if len(obj.Object) == 0 {
+			continue
+		}
+		gvk := obj.GroupVersionKind()
+		resource := strings.ToLower(gvk.Kind)
+		if gvk.Group != "" {
+			resource += "." + gvk.Group
+		}
+		objNamespace := obj.GetNamespace()
+		if objNamespace == "" {
+			objNamespace = namespace
+		}
+		objects = append(objects, manifestObject{resource: resource, namespace: objNamespace, name: obj.GetName()})
+	}
+}
+
+// existingObjects tells which of objects exist on cluster.
+func existingObjects(ctx context.Context, objects []manifestObject, cluster *Cluster) (map[manifestObject]bool, error) {
+	client, err := NewKubeClient(cluster)
+	if err != nil {
+		return nil, err
+	}
+	existing := make(map[manifestObject]bool)
+	for _, object := range objects {
+		_, err := client.Get(ctx, object.resource, object.namespace, object.name)
+		if util.ExitCode(err) == util.ExitNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		existing[object] = true
+	}
+	return existing, nil
+}
+
+// recordManifestURL records that the objects of the manifest at url are
+// about to be created on cluster with kubectl create, which is only done when
+// none of them are installed. Undoing it deletes them with kubectl too.
+func recordManifestURL(url string, cluster *Cluster) {
+	if journal == nil {
+		return
+	}
+	journal.record(fmt.Sprintf("delete the objects of %s from %s", url, cluster.Name), func(ctx context.Context) error {
+		if err := util.RunCommand(ctx, "kubectl", "--context="+cluster.ContextName, "--kubeconfig="+cluster.KubeConfigPath, "delete", "-f", url, "--ignore-not-found"); err != nil {
+			return fmt.Errorf("Process failed %w", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,102 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/kubeslice/kubeslice-cli/util"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// not parallel, as it sets the journal, the executor and the cached client
+// of the cluster
+func TestRecordManifest(t *testing.T) {
+	existing := object("controller.kubeslice.io/v1alpha1", "Project", "kubeslice-demo", "existing", nil, nil)
+	client, dynamicClient := fakeClientGoClient(existing)
+	cluster := &Cluster{Name: "ks-ctrl", ContextName: "ks-ctrl", KubeConfigPath: "kubeconfig"}
+	key := cluster.KubeConfigPath + "/" + cluster.ContextName
+	kubeClientsLock.Lock()
+	kubeClients[key] = client
+	kubeClientsLock.Unlock()
+	fake := &util.FakeExecutor{}
+	defer util.SetExecutor(util.SetExecutor(fake))
+	t.Cleanup(func() {
+		StopInstallJournal()
+		kubeClientsLock.Lock()
+		delete(kubeClients, key)
+		kubeClientsLock.Unlock()
+	})
+
+	manifest := filepath.Join(t.TempDir(), "project.yaml")
+	if err := os.WriteFile(manifest, []byte(`apiVersion: controller.kubeslice.io/v1alpha1
+kind: Project
+metadata:
+  name: existing
+---
+apiVersion: controller.kubeslice.io/v1alpha1
+kind: Project
+metadata:
+  name: demo
+`), 0644); err != nil {
+		t.Fatalf("Failed to setup test: %v", err)
+	}
+	ctx := context.Background()
+	journal := StartInstallJournal()
+	if err := ApplyKubectlManifest(ctx, manifest, "kubeslice-demo", cluster); err != nil {
+		t.Fatalf("ApplyKubectlManifest() returned unexpected error %v", err)
+	}
+	undone, failed := journal.Undo(ctx)
+	if len(undone) != 1 || len(failed) != 0 {
+		t.Errorf("Undo() undid %v and failed %v, want the manifest undone", undone, failed)
+	}
+
+	projects := dynamicClient.Resource(projectsResource).Namespace("kubeslice-demo")
+	if _, err := projects.Get(ctx, "demo", metav1.GetOptions{}); err == nil {
+		t.Errorf("Undo() left the project the install created")
+	}
+	if _, err := projects.Get(ctx, "existing", metav1.GetOptions{}); err != nil {
+		t.Errorf("Undo() deleted the project existing before the install: %v", err)
+	}
+	if commands := fake.Commands(); len(commands) > 0 {
+		t.Errorf("ApplyKubectlManifest() and Undo() ran %q, want the client of the cluster used", commands)
+	}
+}
+
+// not parallel, as it sets the journal, the executor, the output and
+// ExecutablePaths
+func TestRecordCalico(t *testing.T) {
+	util.SetOutput(&bytes.Buffer{})
+	util.ExecutablePaths = map[string]string{"kubectl": "kubectl"}
+	fake := &util.FakeExecutor{}
+	defer util.SetExecutor(util.SetExecutor(fake))
+	t.Cleanup(func() {
+		StopInstallJournal()
+		util.SetOutput(os.Stdout)
+		util.ExecutablePaths = nil
+	})
+	cluster := &Cluster{Name: "ks-w-1", ContextName: "kind-ks-w-1", KubeConfigPath: "kubeconfig"}
+
+	ctx := context.Background()
+	journal := StartInstallJournal()
+	if err := installCalicoOperatorPrerequisites(ctx, nil, cluster); err != nil {
+		t.Fatalf("installCalicoOperatorPrerequisites() returned unexpected error %v", err)
+	}
+	if err := createCalicoOperator(ctx, nil, cluster); err != nil {
+		t.Fatalf("createCalicoOperator() returned unexpected error %v", err)
+	}
+	if undone, failed := journal.Undo(ctx); len(undone) != 2 || len(failed) != 0 {
+		t.Errorf("Undo() undid %v and failed %v, want both Calico manifests undone", undone, failed)
+	}
+	commands := fake.Commands()
+	want := []string{
+		"kubectl --context=kind-ks-w-1 --kubeconfig=kubeconfig delete -f " + calicoCustomResourcesManifest + " --ignore-not-found",
+		"kubectl --context=kind-ks-w-1 --kubeconfig=kubeconfig delete -f " + calicoOperatorManifest + " --ignore-not-found",
+	}
+	if len(commands) != 4 || !reflect.DeepEqual(commands[2:], want) {
+		t.Errorf("Undo() ran %q, want the Calico manifests deleted, last created first %q", commands, want)
+	}
+}
@@ -0,0 +1,200 @@
+package internal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/kubeslice/kubeslice-cli/util"
+	"gopkg.in/yaml.v2"
+)
+
+// InstallStatePath is where install records its progress, so that an
+// interrupted install can be resumed.
+const InstallStatePath = kubesliceDirectory + "/install-state.yaml"
+
+// InstallState is the progress of an install: the steps it finished and what
+// it discovered or generated along the way.
+type InstallState struct {
+	Steps    map[string]*InstallStepState `yaml:"steps"`
+	Clusters map[string]*ClusterState     `yaml:"clusters,omitempty"`
+	// Charts are the charts installed by the steps, by step
+	Charts map[string]InstalledChart `yaml:"charts,omitempty"`
+}
+
+type InstallStepState struct {
+	// InputHash fingerprints the part of the topology the step was run with
+	InputHash string    `yaml:"input_hash"`
+	Finished  time.Time `yaml:"finished"`
+	// Files are the files the step generated in the working directory
+	Files []string `yaml:"files,omitempty"`
+}
+
+// InstalledChart is a chart as it was installed, an empty version being the
+// latest one at the time.
+type InstalledChart struct {
+	ChartName string `yaml:"chart_name"`
+	Version   string `yaml:"version,omitempty"`
+}
+
+// ClusterState is the network information discovered for a cluster.
+type ClusterState struct {
+	ControlPlaneAddress string `yaml:"control_plane_address"`
+	NodeIP              string `yaml:"node_ip"`
+}
+
+func NewInstallState() *InstallState {
+	return &InstallState{
+		Steps:    make(map[string]*InstallStepState),
+		Clusters: make(map[string]*ClusterState),
+		Charts:   make(map[string]InstalledChart),
+	}
+}
+
+// LoadInstallState reads the state of the last install, or returns an empty
+// state if there is none.
+func LoadInstallState() (*InstallState, error) {
+	state := NewInstallState()
+	data, err := ioutil.ReadFile(InstallStatePath)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to read %s %v", InstallStatePath, err)
+	}
+	if state.Steps == nil {
+		state.Steps = make(map[string]*InstallStepState)
+	}
+	if state.Clusters == nil {
+		state.Clusters = make(map[string]*ClusterState)
+	}
+	if state.Charts == nil {
+		state.Charts = make(map[string]InstalledChart)
+	}
+	return state, nil
+}
+
+// ForgetInstallSteps drops uninstalled steps from the install state, so that
+// a resumed install runs them again.
+func ForgetInstallSteps(steps ...string) {
+	state, err := LoadInstallState()
+	if err != nil || len(state.Steps) == 0 {
+		return
+	}
+	for _, step := range steps {
+		delete(state.Steps, step)
+		delete(state.Charts, step)
+	}
+	if err := state.Save(); err != nil {
+		util.Printf("%s Failed to save the install state to %s %v", util.Warn, InstallStatePath, err)
+	}
+}
+
+// RemoveInstallState forgets every step, once the clusters are gone.
+func RemoveInstallState() {
+	if util.DryRun {
+		return
+	}
+	if err := os.Remove(InstallStatePath); err != nil && !os.IsNotExist(err) {
+		util.Printf("%s Failed to remove the install state %s %v", util.Warn, InstallStatePath, err)
+	}
+}
+
+// Save writes the state, replacing the file at once so that an install killed
+// while saving does not leave a truncated state behind. A dry run changes
+// nothing, so it keeps the state of the last install.
+func (s *InstallState) Save() error {
+	if util.DryRun {
+		return nil
+	}
+	data, err := yaml.Marshal(s)
+	if err != nil {
+		return err
+	}
+	tmp := InstallStatePath + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, InstallStatePath)
+}
+
+// Finished tells whether step completed with the same inputs.
+func (s *InstallState) Finished(step, inputHash string) bool {
+	stepState, ok := s.Steps[step]
+	return ok && stepState.InputHash == inputHash
+}
+
+func (s *InstallState) Finish(step, inputHash string, files []string) {
+	s.Steps[step] = &InstallStepState{InputHash: inputHash, Finished: time.Now().UTC(), Files: files}
+}
+
+func (s *InstallState) RecordChart(step string, chart HelmChart) {
+	s.Charts[step] = InstalledChart{ChartName: chart.ChartName, Version: chart.Version}
+}
+
+// RecordNetworkInformation keeps the network information discovered for the
+// clusters.
+func (s *InstallState) RecordNetworkInformation(clusterConfig *ClusterConfiguration) {
+	for _, cluster := range getAllClusters(clusterConfig) {
+		s.Clusters[cluster.Name] = &ClusterState{ControlPlaneAddress: cluster.ControlPlaneAddress, NodeIP: cluster.NodeIP}
+	}
+}
+
+// RestoreNetworkInformation sets the network information recorded for the
+// clusters, and returns false if some cluster has none.
+func (s *InstallState) RestoreNetworkInformation(clusterConfig *ClusterConfiguration) bool {
+	clusters := getAllClusters(clusterConfig)
+	for _, cluster := range clusters {
+		if _, ok := s.Clusters[cluster.Name]; !ok {
+			return false
+		}
+	}
+	for _, cluster := range clusters {
+		cluster.ControlPlaneAddress = s.Clusters[cluster.Name].ControlPlaneAddress
+		cluster.NodeIP = s.Clusters[cluster.Name].NodeIP
+	}
+	return true
+}
+
+// InputHash fingerprints the values a step is run with.
+func InputHash(inputs ...interface{}) (string, error) {
+	data, err := yaml.Marshal(inputs)
+	if err != nil {
+		return "", fmt.Errorf("Failed to fingerprint the topology %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:16], nil
+}
+
+// WorkingDirectoryFiles returns the modification time of the files in the
+// working directory, to find out which files a step generated.
+func WorkingDirectoryFiles() map[string]time.Time {
+	files := make(map[string]time.Time)
+	filepath.Walk(kubesliceDirectory, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() && path != InstallStatePath {
+			files[path] = info.ModTime()
+		}
+		return nil
+	})
+	return files
+}
+
+// ChangedFiles returns the files created or modified since before was taken.
+func ChangedFiles(before map[string]time.Time) []string {
+	changed := make([]string, 0)
+	for path, modTime := range WorkingDirectoryFiles() {
+		if previous, ok := before[path]; !ok || !previous.Equal(modTime) {
+			changed = append(changed, path)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
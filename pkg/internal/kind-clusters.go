@@ -2,57 +2,70 @@ package internal
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
-	"log"
 	"os"
 	"strings"
-	"time"
 
 	"github.com/kubeslice/kubeslice-cli/util"
 )
 
 const KubeconfigPath = kubesliceDirectory + "/kubeconfig.yaml"
 
-func CreateKindClusters(ApplicationConfiguration *ConfigurationSpecs) {
+func CreateKindClusters(ctx context.Context, ApplicationConfiguration *ConfigurationSpecs) error {
 
 	clusters := getAllClusters(&ApplicationConfiguration.Configuration.ClusterConfiguration)
-	existingClusters := getExistingClusters(clusters)
-	created := false
+	existingClusters, err := getExistingClusters(ctx, clusters)
+	if err != nil {
+		return err
+	}
+	missing := make([]*Cluster, 0)
 	util.Printf("\nCreating Kind Clusters...")
 	for i, cluster := range clusters {
 		if !existingClusters[i] {
-			created = true
-			createKindCluster(cluster.Name + ".yaml")
-			util.Printf("%s Created Kind Cluster : %s", util.Tick, cluster.Name)
-			time.Sleep(200 * time.Millisecond)
+			missing = append(missing, cluster)
 		}
 	}
-	if !created {
+	if len(missing) == 0 {
 		util.Printf("\nKind clusters already exist... Skipping\n")
-	} else {
-		util.Printf("Created required kind clusters")
+		return nil
+	}
+	err = forEachCluster(ctx, missing, func(ctx context.Context, out *util.Output, cluster *Cluster) error {
+		recordKindCluster(cluster.Name)
+		if err := createKindCluster(ctx, out, cluster.Name+".yaml"); err != nil {
+			return err
+		}
+		out.Printf("%s Created Kind Cluster : %s", util.Tick, cluster.Name)
+		return nil
+	})
+	if err := clusterErrors("Creating Kind Clusters", err); err != nil {
+		return err
 	}
+	util.Printf("Created required kind clusters")
+	return nil
 }
 
 func SetKubeConfigPath() {
 	os.Setenv("KUBECONFIG", KubeconfigPath)
 }
 
-func CreateKubeConfig() {
+func CreateKubeConfig() error {
 	if _, err := os.Stat(KubeconfigPath); errors.Is(err, os.ErrNotExist) {
-		util.DumpFile("", KubeconfigPath)
+		if err := util.DumpFile("", KubeconfigPath); err != nil {
+			return err
+		}
 		util.Printf("%s Created Empty KubeConfig file : %s", util.Tick, KubeconfigPath)
-		time.Sleep(200 * time.Millisecond)
 	}
+	return nil
 }
 
-func getExistingClusters(clusters []*Cluster) []bool {
+func getExistingClusters(ctx context.Context, clusters []*Cluster) ([]bool, error) {
 	result := make([]bool, len(clusters), len(clusters))
 	var outB, errB bytes.Buffer
-	err := util.RunCommandCustomIO("kind", &outB, &errB, true, "get", "clusters")
+	err := util.RunCommandCustomIO(ctx, "kind", &outB, &errB, true, "get", "clusters")
 	if err != nil {
-		log.Fatalf("Process failed %v", err)
+		return nil, fmt.Errorf("Process failed %w", err)
 	}
 	for i, cluster := range clusters {
 		for _, line := range strings.Split(outB.String(), "\n") {
@@ -62,36 +75,42 @@ func getExistingClusters(clusters []*Cluster) []bool {
 		}
 	}
 
-	return result
+	return result, nil
 }
 
-func createKindCluster(configFile string) {
-	err := util.RunCommandOnStdIO("kind", "create", "cluster", fmt.Sprintf("--config=%s/%s/%s", kubesliceDirectory, kindSubDirectory, configFile))
+func createKindCluster(ctx context.Context, out *util.Output, configFile string) error {
+	err := out.RunCommandOnStdIO(ctx, "kind", "create", "cluster", fmt.Sprintf("--config=%s/%s/%s", kubesliceDirectory, kindSubDirectory, configFile))
 	if err != nil {
-		log.Fatalf("Process failed %v", err)
+		return fmt.Errorf("Process failed %w", err)
 	}
+	return nil
 }
 
-func DeleteKindClusters(ApplicationConfiguration *ConfigurationSpecs) {
+func DeleteKindClusters(ctx context.Context, ApplicationConfiguration *ConfigurationSpecs) error {
 	clusters := getAllClusters(&ApplicationConfiguration.Configuration.ClusterConfiguration)
-	existingClusters := getExistingClusters(clusters)
+	existingClusters, err := getExistingClusters(ctx, clusters)
+	if err != nil {
+		return err
+	}
 	args := make([]string, 0, 0)
 	args = append(args, "delete", "clusters")
 	cNames := make([]string, 0)
 	for i, cluster := range clusters {
-		if existingClusters[i] {
+		// a dry run cannot tell which clusters exist, so it deletes them all
+		if existingClusters[i] || util.DryRun {
 			cNames = append(cNames, cluster.Name)
 		}
 	}
 	if len(cNames) == 0 {
 		util.Printf("No Kind Clusters found for deletion")
-		return
+		return nil
 	}
 	args = append(args, cNames...)
-	err := util.RunCommand("kind", args...)
+	err = util.RunCommand(ctx, "kind", args...)
 	if err != nil {
-		log.Fatalf("Process failed %v", err)
+		return fmt.Errorf("Process failed %w", err)
 	}
+	return nil
 }
 
 func getAllClusters(clusterConfig *ClusterConfiguration) []*Cluster {
@@ -104,6 +123,14 @@ func getAllClusters(clusterConfig *ClusterConfiguration) []*Cluster {
 	return clusters
 }
 
+func getWorkerClusters(clusterConfig *ClusterConfiguration) []*Cluster {
+	clusters := make([]*Cluster, 0, len(clusterConfig.WorkerClusters))
+	for i := range clusterConfig.WorkerClusters {
+		clusters = append(clusters, &clusterConfig.WorkerClusters[i])
+	}
+	return clusters
+}
+
 func getControllerCluster(clusterConfig ClusterConfiguration) *Cluster {
 	cc := clusterConfig
 	return &cc.ControllerCluster
@@ -0,0 +1,471 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/kubeslice/kubeslice-cli/util"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/jsonmergepatch"
+	"k8s.io/apimachinery/pkg/util/mergepatch"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// Backends of KubeBackend
+const (
+	KubeBackendAuto    = "auto"
+	KubeBackendNative  = "client-go"
+	KubeBackendKubectl = "kubectl"
+)
+
+// KubeBackend selects the KubeClient of the clusters: client-go, kubectl, or
+// auto for client-go falling back to kubectl when the kubeconfig cannot be
+// loaded. Dry runs always use kubectl, whose commands they print.
+var KubeBackend = KubeBackendAuto
+
+// fieldManager owns the fields written by the client-go backend
+const fieldManager = "kubeslice-cli"
+
+// KubeClient works on the objects of a cluster. Resources are named as
+// kubectl names them, e.g. secrets, sa or clusters.controller.kubeslice.io.
+// An empty namespace is the namespace of the context, objects that do not
+// exist are NotFoundErrors.
+type KubeClient interface {
+	// Get returns the object name of resource.
+	Get(ctx context.Context, resource, namespace, name string) (*unstructured.Unstructured, error)
+	// List returns the objects of resource matching the label selector, all
+	// of them if selector is empty.
+	List(ctx context.Context, resource, namespace, selector string) ([]unstructured.Unstructured, error)
+	// Apply creates or updates the objects of the manifest file.
+	Apply(ctx context.Context, fileName, namespace string) error
+	// Patch merges patch into the object name of resource.
+	Patch(ctx context.Context, resource, namespace, name string, patch []byte) error
+	// Delete deletes the object name of resource.
+	Delete(ctx context.Context, resource, namespace, name string) error
+}
+
+var (
+	kubeClientsLock sync.Mutex
+	kubeClients     = make(map[string]KubeClient)
+)
+
+// NewKubeClient returns the KubeClient of cluster, or of the current context
+// of the default kubeconfig if cluster is nil.
+func NewKubeClient(cluster *Cluster) (KubeClient, error) {
+	if util.DryRun || KubeBackend == KubeBackendKubectl {
+		return &kubectlClient{cluster: cluster}, nil
+	}
+	key := ""
+	if cluster != nil {
+		key = cluster.KubeConfigPath + "/" + cluster.ContextName
+	}
+	kubeClientsLock.Lock()
+	defer kubeClientsLock.Unlock()
+	if client, ok := kubeClients[key]; ok {
+		return client, nil
+	}
+	var client KubeClient
+	if native, err := newClientGoClient(cluster); err == nil {
+		client = native
+	} else if KubeBackend == KubeBackendNative {
+		return nil, util.ValidationErrorf("Failed to load the kubeconfig: %w", err)
+	} else {
+		client = &kubectlClient{cluster: cluster}
+	}
+	kubeClients[key] = client
+	return client, nil
+}
+
+// fromJSON reads obj into v, a typed object such as a corev1.Secret or a
+// struct with the fields needed, through its JSON.
+func fromJSON(obj json.Marshaler, v interface{}) error {
+	data, err := obj.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// clientGoClient is the KubeClient talking to the API server of the cluster
+// with client-go.
+type clientGoClient struct {
+	dynamic   dynamic.Interface
+	discovery discovery.CachedDiscoveryInterface
+	mapper    meta.RESTMapper
+	namespace string // of the context
+}
+
+// CurrentContext returns the current context of the kubeconfig at path, or of
+// the default kubeconfig ($KUBECONFIG or ~/.kube/config) if path is empty.
+func CurrentContext(path string) (string, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = path
+	config, err := rules.Load()
+	if err != nil {
+		return "", err
+	}
+	if config.CurrentContext == "" {
+		return "", util.NotFoundErrorf("the kubeconfig has no current context")
+	}
+	return config.CurrentContext, nil
+}
+
+func newClientGoClient(cluster *Cluster) (*clientGoClient, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	overrides := &clientcmd.ConfigOverrides{}
+	if cluster != nil {
+		if cluster.KubeConfigPath != "" {
+			rules.ExplicitPath = cluster.KubeConfigPath
+		}
+		overrides.CurrentContext = cluster.ContextName
+	}
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides)
+	config, err := clientConfig.ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	namespace, _, err := clientConfig.Namespace()
+	if err != nil {
+		return nil, err
+	}
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return newClientGoClientFor(dynamicClient, discoveryClient, namespace), nil
+}
+
+// newClientGoClientFor returns the clientGoClient working on the objects
+// through dynamicClient, and finding their resources through discoveryClient.
+func newClientGoClientFor(dynamicClient dynamic.Interface, discoveryClient discovery.DiscoveryInterface, namespace string) *clientGoClient {
+	cached := memory.NewMemCacheClient(discoveryClient)
+	return &clientGoClient{
+		dynamic:   dynamicClient,
+		discovery: cached,
+		mapper:    restmapper.NewShortcutExpander(restmapper.NewDeferredDiscoveryRESTMapper(cached), cached),
+		namespace: namespace,
+	}
+}
+
+// mapping returns how to reach the resource of the kubectl name or the kind
+// of gvk. The resources are discovered again when they are not found, as the
+// install creates CRDs while it goes.
+func (c *clientGoClient) mapping(resource string, gvk *schema.GroupVersionKind) (*meta.RESTMapping, error) {
+	find := func() (*meta.RESTMapping, error) {
+		if gvk != nil {
+			return c.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		}
+		gvr, err := c.mapper.ResourceFor(schema.ParseGroupResource(resource).WithVersion(""))
+		if err != nil {
+			return nil, err
+		}
+		kind, err := c.mapper.KindFor(gvr)
+		if err != nil {
+			return nil, err
+		}
+		return c.mapper.RESTMapping(kind.GroupKind(), kind.Version)
+	}
+	mapping, err := find()
+	if meta.IsNoMatchError(err) {
+		c.discovery.Invalidate()
+		mapping, err = find()
+	}
+	if meta.IsNoMatchError(err) {
+		return nil, util.NotFoundErrorf("the server doesn't have a resource type %s", resource)
+	}
+	return mapping, err
+}
+
+func (c *clientGoClient) resource(resource, namespace string, gvk *schema.GroupVersionKind) (dynamic.ResourceInterface, error) {
+	mapping, err := c.mapping(resource, gvk)
+	if err != nil {
+		return nil, err
+	}
+	if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+		return c.dynamic.Resource(mapping.Resource), nil
+	}
+	if namespace == "" {
+		namespace = c.namespace
+	}
+	return c.dynamic.Resource(mapping.Resource).Namespace(namespace), nil
+}
+
+// apiError types the error of a request on the object name of resource.
+func apiError(err error, resource, name string) error {
+	if apierrors.IsNotFound(err) {
+		return util.NotFoundErrorf("%s %s not found: %w", resource, name, err)
+	}
+	return fmt.Errorf("%s %s: %w", resource, name, err)
+}
+
+func (c *clientGoClient) Get(ctx context.Context, resource, namespace, name string) (*unstructured.Unstructured, error) {
+	client, err := c.resource(resource, namespace, nil)
+	if err != nil {
+		return nil, err
+	}
+	obj, err := client.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, apiError(err, resource, name)
+	}
+	return obj, nil
+}
+
+func (c *clientGoClient) List(ctx context.Context, resource, namespace, selector string) ([]unstructured.Unstructured, error) {
+	client, err := c.resource(resource, namespace, nil)
+	if err != nil {
+		return nil, err
+	}
+	list, err := client.List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", resource, err)
+	}
+	return list.Items, nil
+}
+
+// Apply applies the objects as kubectl apply does, client-side: the applied
+// configuration is kept in the last-applied-configuration annotation, and an
+// existing object gets a three-way patch between it, the configuration last
+// applied and the new one. Fields set by others and never applied are left
+// alone, the same objects can be applied with either backend.
+func (c *clientGoClient) Apply(ctx context.Context, fileName, namespace string) error {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	decoder := yaml.NewYAMLOrJSONDecoder(file, 4096)
+	for {
+		obj := &unstructured.Unstructured{}
+		if err := decoder.Decode(&obj.Object); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("failed to read %s: %w", fileName, err)
+		}
+		if len(obj.Object) == 0 {
+			continue
+		}
+		gvk := obj.GroupVersionKind()
+		objNamespace := obj.GetNamespace()
+		if objNamespace == "" {
+			objNamespace = namespace
+		}
+		client, err := c.resource(gvk.Kind, objNamespace, &gvk)
+		if err != nil {
+			return err
+		}
+		if err := applyObject(ctx, client, obj); err != nil {
+			return apiError(err, strings.ToLower(gvk.Kind), obj.GetName())
+		}
+	}
+}
+
+// applyObject creates obj, or patches it from the configuration last applied.
+func applyObject(ctx context.Context, client dynamic.ResourceInterface, obj *unstructured.Unstructured) error {
+	modified, err := withLastApplied(obj)
+	if err != nil {
+		return err
+	}
+	current, err := client.Get(ctx, obj.GetName(), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = client.Create(ctx, modified, metav1.CreateOptions{FieldManager: fieldManager})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	original := []byte(current.GetAnnotations()[corev1.LastAppliedConfigAnnotation])
+	modifiedJSON, err := modified.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	currentJSON, err := current.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	patchType, patch, err := threeWayPatch(obj.GroupVersionKind(), original, modifiedJSON, currentJSON)
+	if err != nil {
+		return err
+	}
+	if string(patch) == "{}" {
+		return nil
+	}
+	_, err = client.Patch(ctx, obj.GetName(), patchType, patch, metav1.PatchOptions{FieldManager: fieldManager})
+	return err
+}
+
+// withLastApplied returns a copy of obj annotated with its configuration, as
+// kubectl apply annotates it.
+func withLastApplied(obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	modified := obj.DeepCopy()
+	annotations := modified.GetAnnotations()
+	delete(annotations, corev1.LastAppliedConfigAnnotation)
+	modified.SetAnnotations(annotations)
+	configuration, err := modified.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[corev1.LastAppliedConfigAnnotation] = strings.TrimSpace(string(configuration))
+	modified.SetAnnotations(annotations)
+	return modified, nil
+}
+
+// threeWayPatch returns the patch kubectl apply sends: a strategic merge
+// patch for the built-in kinds, a JSON merge patch for the others.
+func threeWayPatch(gvk schema.GroupVersionKind, original, modified, current []byte) (types.PatchType, []byte, error) {
+	if typed, err := scheme.Scheme.New(gvk); err == nil {
+		patchMeta, err := strategicpatch.NewPatchMetaFromStruct(typed)
+		if err != nil {
+			return "", nil, err
+		}
+		patch, err := strategicpatch.CreateThreeWayMergePatch(original, modified, current, patchMeta, true)
+		return types.StrategicMergePatchType, patch, err
+	}
+	preconditions := []mergepatch.PreconditionFunc{
+		mergepatch.RequireKeyUnchanged("apiVersion"),
+		mergepatch.RequireKeyUnchanged("kind"),
+		mergepatch.RequireMetadataKeyUnchanged("name"),
+	}
+	patch, err := jsonmergepatch.CreateThreeWayJSONMergePatch(original, modified, current, preconditions...)
+	return types.MergePatchType, patch, err
+}
+
+func (c *clientGoClient) Patch(ctx context.Context, resource, namespace, name string, patch []byte) error {
+	client, err := c.resource(resource, namespace, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := client.Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{FieldManager: fieldManager}); err != nil {
+		return apiError(err, resource, name)
+	}
+	return nil
+}
+
+func (c *clientGoClient) Delete(ctx context.Context, resource, namespace, name string) error {
+	client, err := c.resource(resource, namespace, nil)
+	if err != nil {
+		return err
+	}
+	if err := client.Delete(ctx, name, metav1.DeleteOptions{}); err != nil {
+		return apiError(err, resource, name)
+	}
+	return nil
+}
+
+// kubectlClient is the KubeClient running kubectl, printing the commands it
+// changes the cluster with. In dry-run mode the objects read are empty.
+type kubectlClient struct {
+	cluster *Cluster
+}
+
+func (c *kubectlClient) args(namespace string, args ...string) []string {
+	if namespace != "" {
+		args = append(args, "-n", namespace)
+	}
+	if c.cluster == nil {
+		return args
+	}
+	return append([]string{"--context=" + c.cluster.ContextName, "--kubeconfig=" + c.cluster.KubeConfigPath}, args...)
+}
+
+// kubectlError types the error of kubectl from what it printed to stderr.
+func kubectlError(err error, errB *bytes.Buffer) error {
+	message := strings.TrimSpace(errB.String())
+	if strings.Contains(message, "NotFound") || strings.Contains(message, "doesn't have a resource type") {
+		return util.NotFoundErrorf("%s: %w", message, err)
+	}
+	if message == "" {
+		return fmt.Errorf("Process failed %w", err)
+	}
+	return fmt.Errorf("Process failed %w\n%s", err, message)
+}
+
+func (c *kubectlClient) Get(ctx context.Context, resource, namespace, name string) (*unstructured.Unstructured, error) {
+	var outB, errB bytes.Buffer
+	if err := util.RunCommandCustomIO(ctx, "kubectl", &outB, &errB, true, c.args(namespace, "get", resource, name, "-o", "json")...); err != nil {
+		return nil, kubectlError(err, &errB)
+	}
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	if util.DryRun {
+		return obj, nil
+	}
+	if err := json.Unmarshal(outB.Bytes(), &obj.Object); err != nil {
+		return nil, fmt.Errorf("failed to read %s %s: %w", resource, name, err)
+	}
+	return obj, nil
+}
+
+func (c *kubectlClient) List(ctx context.Context, resource, namespace, selector string) ([]unstructured.Unstructured, error) {
+	var outB, errB bytes.Buffer
+	args := []string{"get", resource, "-o", "json"}
+	if selector != "" {
+		args = append(args, "-l", selector)
+	}
+	if err := util.RunCommandCustomIO(ctx, "kubectl", &outB, &errB, true, c.args(namespace, args...)...); err != nil {
+		return nil, kubectlError(err, &errB)
+	}
+	if util.DryRun {
+		return nil, nil
+	}
+	var list struct {
+		Items []map[string]interface{} `json:"items"`
+	}
+	if err := json.Unmarshal(outB.Bytes(), &list); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", resource, err)
+	}
+	items := make([]unstructured.Unstructured, 0, len(list.Items))
+	for _, item := range list.Items {
+		items = append(items, unstructured.Unstructured{Object: item})
+	}
+	return items, nil
+}
+
+func (c *kubectlClient) Apply(ctx context.Context, fileName, namespace string) error {
+	if err := util.RunCommand(ctx, "kubectl", c.args(namespace, "apply", "-f", fileName)...); err != nil {
+		return fmt.Errorf("Process failed %w", err)
+	}
+	return nil
+}
+
+func (c *kubectlClient) Patch(ctx context.Context, resource, namespace, name string, patch []byte) error {
+	var outB, errB bytes.Buffer
+	if err := util.RunCommandCustomIO(ctx, "kubectl", &outB, &errB, false, c.args(namespace, "patch", resource, name, "--type", "merge", "-p", string(patch))...); err != nil {
+		return kubectlError(err, &errB)
+	}
+	return nil
+}
+
+func (c *kubectlClient) Delete(ctx context.Context, resource, namespace, name string) error {
+	var outB, errB bytes.Buffer
+	if err := util.RunCommandCustomIO(ctx, "kubectl", &outB, &errB, false, c.args(namespace, "delete", resource, name)...); err != nil {
+		return kubectlError(err, &errB)
+	}
+	return nil
+}
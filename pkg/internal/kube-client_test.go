@@ -0,0 +1,193 @@
+package internal
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/kubeslice/kubeslice-cli/util"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	discoveryfake "k8s.io/client-go/discovery/fake"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+var projectsResource = schema.GroupVersionResource{Group: "controller.kubeslice.io", Version: "v1alpha1", Resource: "projects"}
+
+// fakeClientGoClient returns a clientGoClient on a fake cluster holding
+// objects, which knows secrets, configmaps and projects.
+func fakeClientGoClient(objects ...runtime.Object) (*clientGoClient, *dynamicfake.FakeDynamicClient) {
+	listKinds := map[schema.GroupVersionResource]string{
+		{Version: "v1", Resource: "secrets"}:    "SecretList",
+		{Version: "v1", Resource: "configmaps"}: "ConfigMapList",
+		projectsResource:                        "ProjectList",
+	}
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds, objects...)
+	discoveryClient := &discoveryfake.FakeDiscovery{Fake: &clienttesting.Fake{Resources: []*metav1.APIResourceList{
+		{GroupVersion: "v1", APIResources: []metav1.APIResource{
+			{Name: "secrets", Namespaced: true, Kind: "Secret"},
+			{Name: "configmaps", ShortNames: []string{"cm"}, Namespaced: true, Kind: "ConfigMap"},
+		}},
+		{GroupVersion: "controller.kubeslice.io/v1alpha1", APIResources: []metav1.APIResource{
+			{Name: "projects", Namespaced: true, Kind: "Project"},
+		}},
+	}}}
+	return newClientGoClientFor(dynamicClient, discoveryClient, "default"), dynamicClient
+}
+
+func object(apiVersion, kind, namespace, name string, labels map[string]string, fields map[string]interface{}) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: fields}
+	if obj.Object == nil {
+		obj.Object = make(map[string]interface{})
+	}
+	obj.SetAPIVersion(apiVersion)
+	obj.SetKind(kind)
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	obj.SetLabels(labels)
+	return obj
+}
+
+func TestClientGoGetList(t *testing.T) {
+	t.Parallel()
+
+	client, _ := fakeClientGoClient(
+		object("v1", "Secret", "kubeslice-demo", "worker-1", map[string]string{"worker": "1"}, nil),
+		object("v1", "Secret", "kubeslice-demo", "worker-2", map[string]string{"worker": "2"}, nil),
+		object("v1", "ConfigMap", "default", "settings", nil, map[string]interface{}{"data": map[string]interface{}{"a": "1"}}),
+	)
+	ctx := context.Background()
+
+	secret, err := client.Get(ctx, "secrets", "kubeslice-demo", "worker-1")
+	if err != nil || secret.GetName() != "worker-1" {
+		t.Errorf("Get(secrets) = %v, %v, want secret worker-1", secret, err)
+	}
+	configMap, err := client.Get(ctx, "cm", "", "settings")
+	if err != nil || configMap.GetName() != "settings" {
+		t.Errorf("Get(cm) in the namespace of the context = %v, %v, want configmap settings", configMap, err)
+	}
+	if _, err := client.Get(ctx, "secrets", "kubeslice-demo", "worker-3"); util.ExitCode(err) != util.ExitNotFound {
+		t.Errorf("Get(secrets) of a missing secret = %v, want a NotFoundError", err)
+	}
+	if _, err := client.Get(ctx, "sliceconfigs.controller.kubeslice.io", "kubeslice-demo", "red"); util.ExitCode(err) != util.ExitNotFound {
+		t.Errorf("Get(sliceconfigs) of a missing resource type = %v, want a NotFoundError", err)
+	}
+
+	items, err := client.List(ctx, "secrets", "kubeslice-demo", "worker=2")
+	if err != nil || len(items) != 1 || items[0].GetName() != "worker-2" {
+		t.Errorf("List(secrets, worker=2) = %v, %v, want secret worker-2", items, err)
+	}
+	items, err = client.List(ctx, "secrets", "kubeslice-demo", "")
+	if err != nil || len(items) != 2 {
+		t.Errorf("List(secrets) = %v, %v, want both secrets", items, err)
+	}
+	if _, err := client.List(ctx, "sliceconfigs.controller.kubeslice.io", "kubeslice-demo", ""); util.ExitCode(err) != util.ExitNotFound {
+		t.Errorf("List(sliceconfigs) of a missing resource type = %v, want a NotFoundError", err)
+	}
+
+	if err := client.Delete(ctx, "secrets", "kubeslice-demo", "worker-3"); util.ExitCode(err) != util.ExitNotFound {
+		t.Errorf("Delete(secrets) of a missing secret = %v, want a NotFoundError", err)
+	}
+}
+
+func TestClientGoApply(t *testing.T) {
+	t.Parallel()
+
+	client, dynamicClient := fakeClientGoClient()
+	ctx := context.Background()
+	dir := t.TempDir()
+	apply := func(manifest string) {
+		t.Helper()
+		fileName := filepath.Join(dir, "manifest.yaml")
+		if err := os.WriteFile(fileName, []byte(manifest), 0644); err != nil {
+			t.Fatalf("Failed to setup test: %v", err)
+		}
+		dynamicClient.ClearActions()
+		if err := client.Apply(ctx, fileName, "kubeslice-demo"); err != nil {
+			t.Fatalf("Apply() returned unexpected error %v", err)
+		}
+	}
+	project := func() *unstructured.Unstructured {
+		t.Helper()
+		obj, err := dynamicClient.Resource(projectsResource).Namespace("kubeslice-demo").Get(ctx, "demo", metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("the project was not applied: %v", err)
+		}
+		return obj
+	}
+
+	apply(`apiVersion: controller.kubeslice.io/v1alpha1
+kind: Project
+metadata:
+  name: demo
+spec:
+  serviceAccount:
+    readWrite: [alice]
+    readOnly: [bob]
+`)
+	if _, ok := project().GetAnnotations()[corev1.LastAppliedConfigAnnotation]; !ok {
+		t.Errorf("Apply() created %v, want the configuration annotated", project())
+	}
+
+	// another manager sets a field the manifest does not have
+	edited := project()
+	unstructured.SetNestedField(edited.Object, "audit", "spec", "defaultSliceCreation")
+	if _, err := dynamicClient.Resource(projectsResource).Namespace("kubeslice-demo").Update(ctx, edited, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("Failed to setup test: %v", err)
+	}
+
+	apply(`apiVersion: controller.kubeslice.io/v1alpha1
+kind: Project
+metadata:
+  name: demo
+spec:
+  serviceAccount:
+    readWrite: [alice, carol]
+`)
+	actions := dynamicClient.Actions()
+	spec, _, _ := unstructured.NestedMap(project().Object, "spec")
+	want := map[string]interface{}{
+		"serviceAccount":       map[string]interface{}{"readWrite": []interface{}{"alice", "carol"}},
+		"defaultSliceCreation": "audit",
+	}
+	if !reflect.DeepEqual(spec, want) {
+		t.Errorf("Apply() left spec %v, want %v: the applied changes, the field removed from the manifest gone and the other field kept", spec, want)
+	}
+	if len(actions) != 2 || actions[1].(clienttesting.PatchAction).GetPatchType() != types.MergePatchType {
+		t.Errorf("Apply() ran %v, want a get and a JSON merge patch of the project", actions)
+	}
+
+	apply(`apiVersion: controller.kubeslice.io/v1alpha1
+kind: Project
+metadata:
+  name: demo
+spec:
+  serviceAccount:
+    readWrite: [alice, carol]
+`)
+	if actions := dynamicClient.Actions(); len(actions) != 1 || actions[0].GetVerb() != "get" {
+		t.Errorf("Apply() of an unchanged manifest ran %v, want a get only", actions)
+	}
+}
+
+func TestThreeWayPatch(t *testing.T) {
+	t.Parallel()
+
+	original := []byte(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"settings"},"data":{"a":"1","b":"2"}}`)
+	modified := []byte(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"settings"},"data":{"a":"3"}}`)
+	current := []byte(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"settings"},"data":{"a":"1","b":"2","c":"4"}}`)
+	patchType, patch, err := threeWayPatch(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, original, modified, current)
+	if err != nil {
+		t.Fatalf("threeWayPatch() returned unexpected error %v", err)
+	}
+	if want := `{"data":{"a":"3","b":null}}`; patchType != types.StrategicMergePatchType || string(patch) != want {
+		t.Errorf("threeWayPatch(ConfigMap) = %s %s, want %s %s", patchType, patch, types.StrategicMergePatchType, want)
+	}
+}
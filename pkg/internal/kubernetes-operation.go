@@ -1,16 +1,20 @@
 package internal
 
 import (
-	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"strconv"
 	"strings"
+	"text/tabwriter"
 	"time"
 
 	"github.com/kubeslice/kubeslice-cli/util"
 	"github.com/tidwall/sjson"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/duration"
 	YAML "sigs.k8s.io/yaml"
 )
 
@@ -22,166 +26,230 @@ const (
 	PodVerificationStatusFailed
 )
 
-func PodVerification(message string, cluster Cluster, namespace string) {
-	var i = 0
-	var backoffCount = 0
-	var backoffLimit = 20
+func PodVerification(ctx context.Context, message string, cluster Cluster, namespace string) error {
+	return podVerification(ctx, nil, message, cluster, namespace)
+}
+
+// podVerification waits for the pods, deployments and daemonsets of namespace
+// to be ready, printing its progress to out. It checks every WaitInterval, and
+// gives up once the pods were found failing more than PodBackoffLimit times,
+// listing the failing pods along with the reasons of their last states.
+func podVerification(ctx context.Context, out *util.Output, message string, cluster Cluster, namespace string) error {
+	if util.DryRun {
+		out.Printf("%s %s... would wait for the pods in %s on %s", util.Wait, message, namespace, cluster.Name)
+		return nil
+	}
+	start := time.Now()
+	backoffCount := 0
 	for {
-		i = i + 1
-		time.Sleep(5 * time.Second)
-		status, output := verifyPods(cluster, namespace)
-		if status == PodVerificationStatusSuccess {
-			break
-		} else if status == PodVerificationStatusFailed {
+		if err := util.Sleep(ctx, WaitInterval); err != nil {
+			return fmt.Errorf("%s stopped: %w", message, err)
+		}
+		status, problems, err := namespaceReadiness(ctx, cluster, namespace)
+		if err != nil {
+			return err
+		}
+		elapsed := int(time.Since(start).Seconds())
+		switch status {
+		case PodVerificationStatusSuccess:
+			return nil
+		case PodVerificationStatusFailed:
 			backoffCount = backoffCount + 1
-			util.Printf("%s %s... Pod(s) in error state, waiting to recover... %d seconds elapsed", util.Wait, message, i*5)
-			if backoffCount > backoffLimit {
-				log.Fatalf("Pod(s) in error state,\n%s", output)
+			out.Printf("%s %s... Pod(s) in error state, waiting to recover... %d seconds elapsed", util.Wait, message, elapsed)
+			if backoffCount > PodBackoffLimit {
+				return util.TimeoutErrorf("Pod(s) in %s on %s in error state:\n  %s", namespace, cluster.Name, strings.Join(problems, "\n  "))
 			}
-		} else {
-			util.Printf("%s %s... %d seconds elapsed", util.Wait, message, i*5)
+		default:
+			out.Printf("%s %s... %d seconds elapsed", util.Wait, message, elapsed)
 		}
 	}
 }
 
-func LicenseVerification(message string, cluster Cluster, namespace string) {
-	err := Retry(5, 1*time.Second, func() (err error) {
+func LicenseVerification(ctx context.Context, message string, cluster Cluster, namespace string) error {
+	err := Retry(ctx, 5, 1*time.Second, func() (err error) {
 		util.Printf("%s %s...", util.Wait, message)
-		return fetchLicenseSecret(LicenseFileName, cluster, namespace)
+		return fetchLicenseSecret(ctx, LicenseFileName, cluster, namespace)
 	})
 	if err != nil {
-		log.Fatalf("Unable to fetch License\n%s", err)
+		return util.TimeoutErrorf("Unable to fetch License\n%w", err)
 	}
+	return nil
 }
 
-func fetchLicenseSecret(secretName string, cc Cluster, namespace string) error {
-	var outB, errB bytes.Buffer
-	err := util.RunCommandCustomIO("kubectl", &outB, &errB, true, "--context="+cc.ContextName, "--kubeconfig="+cc.KubeConfigPath, "get", "secret", secretName, "-n", namespace)
+func fetchLicenseSecret(ctx context.Context, secretName string, cc Cluster, namespace string) error {
+	client, err := NewKubeClient(&cc)
 	if err != nil {
 		return err
 	}
-	for _, line := range strings.Split(outB.String(), "\n") {
-		if strings.Contains(line, secretName) {
-			return nil
+	if _, err := client.Get(ctx, SecretObject, namespace, secretName); err != nil {
+		if util.ExitCode(err) == util.ExitNotFound {
+			return util.NotFoundErrorf("license not found")
 		}
+		return err
 	}
-	return fmt.Errorf("license not found")
+	return nil
 }
 
-func ApplyKubectlManifest(fileName, namespace string, cluster *Cluster) {
-	cmdArgs := []string{}
-	if cluster != nil {
-		cmdArgs = append(cmdArgs, "--context="+cluster.ContextName, "--kubeconfig="+cluster.KubeConfigPath)
+// findServiceAccountSecret returns the name of the secret of the service
+// account of namespace whose name contains part, "" if there is none. The
+// secrets are named after their service accounts.
+func findServiceAccountSecret(ctx context.Context, cluster *Cluster, namespace, part string) (string, error) {
+	client, err := NewKubeClient(cluster)
+	if err != nil {
+		return "", err
 	}
-	cmdArgs = append(cmdArgs, "apply", "-f", fileName, "-n", namespace)
-	err := util.RunCommand("kubectl", cmdArgs...)
+	accounts, err := client.List(ctx, "serviceaccounts", namespace, "")
 	if err != nil {
-		log.Fatalf("Process failed %v", err)
+		return "", err
 	}
+	for _, account := range accounts {
+		if strings.Contains(account.GetName(), part) {
+			return account.GetName(), nil
+		}
+	}
+	return "", nil
 }
 
-func GetKubectlResources(resourceType string, resourceName string, namespace string, cluster *Cluster, outputFormat string) {
-	cmdArgs := []string{}
-	if cluster != nil {
-		cmdArgs = append(cmdArgs, "--context="+cluster.ContextName, "--kubeconfig="+cluster.KubeConfigPath)
+func ApplyKubectlManifest(ctx context.Context, fileName, namespace string, cluster *Cluster) error {
+	recordManifest(ctx, fileName, namespace, cluster)
+	client, err := NewKubeClient(cluster)
+	if err != nil {
+		return err
+	}
+	return client.Apply(ctx, fileName, namespace)
+}
+
+// GetKubectlResources prints resourceName, or every object of resourceType if
+// it is empty, as json, yaml or a table of their names and ages. Other output
+// formats are left to kubectl.
+func GetKubectlResources(ctx context.Context, resourceType string, resourceName string, namespace string, cluster *Cluster, outputFormat string) error {
+	if outputFormat != "" && outputFormat != "json" && outputFormat != "yaml" {
+		cmdArgs := []string{}
+		if cluster != nil {
+			cmdArgs = append(cmdArgs, "--context="+cluster.ContextName, "--kubeconfig="+cluster.KubeConfigPath)
+		}
+		cmdArgs = append(cmdArgs, "get", resourceType)
+		if resourceName != "" {
+			cmdArgs = append(cmdArgs, resourceName)
+		}
+		cmdArgs = append(cmdArgs, "-n", namespace, "-o", outputFormat)
+		err := util.RunCommandOnStdIO(ctx, "kubectl", cmdArgs...)
+		if err != nil {
+			return fmt.Errorf("Process failed %w", err)
+		}
+		return nil
+	}
+	client, err := NewKubeClient(cluster)
+	if err != nil {
+		return err
 	}
+	var objects []unstructured.Unstructured
 	if resourceName == "" {
-		cmdArgs = append(cmdArgs, "get", resourceType, "-n", namespace)
+		objects, err = client.List(ctx, resourceType, namespace, "")
 	} else {
-		cmdArgs = append(cmdArgs, "get", resourceType, resourceName, "-n", namespace)
+		var obj *unstructured.Unstructured
+		if obj, err = client.Get(ctx, resourceType, namespace, resourceName); err == nil {
+			objects = append(objects, *obj)
+		}
+	}
+	if err != nil || util.DryRun {
+		return err
 	}
-	if outputFormat != "" {
+	return printObjects(objects, resourceName == "", outputFormat)
+}
 
-		cmdArgs = append(cmdArgs, "-o", outputFormat)
+// printObjects prints objects the way kubectl get does, as a List if they
+// were listed.
+func printObjects(objects []unstructured.Unstructured, listed bool, outputFormat string) error {
+	out := util.CommandOutput()
+	if outputFormat == "" {
+		if len(objects) == 0 {
+			util.Printf("No resources found")
+			return nil
+		}
+		w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w, "NAME\tAGE")
+		for _, obj := range objects {
+			age := duration.HumanDuration(time.Since(obj.GetCreationTimestamp().Time))
+			fmt.Fprintf(w, "%s\t%s\n", obj.GetName(), age)
+		}
+		return w.Flush()
+	}
+	var v interface{} = objects[0].Object
+	if listed {
+		items := make([]interface{}, 0, len(objects))
+		for _, obj := range objects {
+			items = append(items, obj.Object)
+		}
+		v = map[string]interface{}{"apiVersion": "v1", "kind": "List", "items": items, "metadata": map[string]interface{}{"resourceVersion": ""}}
+	}
+	var data []byte
+	var err error
+	if outputFormat == "json" {
+		data, err = json.MarshalIndent(v, "", "    ")
+		data = append(data, '\n')
+	} else {
+		data, err = YAML.Marshal(v)
 	}
-	err := util.RunCommandOnStdIO("kubectl", cmdArgs...)
 	if err != nil {
-		log.Fatalf("Process failed %v", err)
+		return err
 	}
+	_, err = out.Write(data)
+	return err
 }
 
-func DeleteKubectlResources(resourceType string, resourceName string, namespace string, cluster *Cluster) {
-	cmdArgs := []string{}
-	if cluster != nil {
-		cmdArgs = append(cmdArgs, "--context="+cluster.ContextName, "--kubeconfig="+cluster.KubeConfigPath)
-	}
-	cmdArgs = append(cmdArgs, "delete", resourceType, resourceName, "-n", namespace)
-	err := util.RunCommandOnStdIO("kubectl", cmdArgs...)
+func DeleteKubectlResources(ctx context.Context, resourceType string, resourceName string, namespace string, cluster *Cluster) error {
+	client, err := NewKubeClient(cluster)
 	if err != nil {
-		log.Fatalf("Process failed %v", err)
+		return err
+	}
+	if err := client.Delete(ctx, resourceType, namespace, resourceName); err != nil || util.DryRun {
+		return err
 	}
+	fmt.Fprintf(util.CommandOutput(), "%s/%s deleted\n", resourceType, resourceName)
+	return nil
 }
 
-func EditKubectlResources(resourceType string, resourceName string, namespace string, cluster *Cluster) {
+func EditKubectlResources(ctx context.Context, resourceType string, resourceName string, namespace string, cluster *Cluster) error {
 	cmdArgs := []string{}
 	if cluster != nil {
 		cmdArgs = append(cmdArgs, "--context="+cluster.ContextName, "--kubeconfig="+cluster.KubeConfigPath)
 	}
 	cmdArgs = append(cmdArgs, "edit", resourceType, resourceName, "-n", namespace)
-	err := util.RunCommandOnStdIO("kubectl", cmdArgs...)
+	err := util.RunCommandOnStdIO(ctx, "kubectl", cmdArgs...)
 	if err != nil {
-		log.Fatalf("Process failed %v", err)
+		return fmt.Errorf("Process failed %w", err)
 	}
+	return nil
 }
 
-func DescribeKubectlResources(resourceType string, resourceName string, namespace string, cluster *Cluster) {
+func DescribeKubectlResources(ctx context.Context, resourceType string, resourceName string, namespace string, cluster *Cluster) error {
 	cmdArgs := []string{}
 	if cluster != nil {
 		cmdArgs = append(cmdArgs, "--context="+cluster.ContextName, "--kubeconfig="+cluster.KubeConfigPath)
 	}
 	cmdArgs = append(cmdArgs, "describe", resourceType, resourceName, "-n", namespace)
-	err := util.RunCommandOnStdIO("kubectl", cmdArgs...)
+	err := util.RunCommandOnStdIO(ctx, "kubectl", cmdArgs...)
 	if err != nil {
-		log.Fatalf("Process failed %v", err)
+		return fmt.Errorf("Process failed %w", err)
 	}
+	return nil
 }
 
-func verifyPods(cluster Cluster, namespace string) (PodVerificationStatus, string) {
-	var outB, errB bytes.Buffer
-	err := util.RunCommandCustomIO("kubectl", &outB, &errB, true, "--context="+cluster.ContextName, "--kubeconfig="+cluster.KubeConfigPath, "get", "pods", "-n", namespace)
+func ApplyFile(ctx context.Context, fileName, namespace string, cluster *Cluster) error {
+	client, err := NewKubeClient(cluster)
 	if err != nil {
-		log.Fatalf("Process failed %v", err)
-	}
-	var count = 0
-	var lines = 0
-	for _, line := range strings.Split(outB.String(), "\n") {
-		if strings.Contains(line, "Error") || strings.Contains(line, "ImagePullBackOff") || strings.Contains(line, "CrashLoopBackOff") {
-			return PodVerificationStatusFailed, outB.String()
-		}
-		if strings.Contains(line, "Completed") {
-			continue
-		}
-		if strings.Contains(line, "/") {
-			index := strings.Index(line, "/")
-			upper, _ := strconv.Atoi(string(line[index+1]))
-			lower, _ := strconv.Atoi(string(line[index-1]))
-			if upper == lower {
-				count = count + 1
-			}
-			lines = lines + 1
-		}
-	}
-	if count == lines {
-		return PodVerificationStatusSuccess, outB.String()
+		return err
 	}
-	return PodVerificationStatusInProgress, outB.String()
+	return client.Apply(ctx, fileName, namespace)
 }
 
-func ApplyFile(fileName, namespace string, cluster *Cluster) {
-	cmdArgs := []string{}
-	if cluster != nil {
-		cmdArgs = append(cmdArgs, "--context="+cluster.ContextName, "--kubeconfig="+cluster.KubeConfigPath)
-	}
-	cmdArgs = append(cmdArgs, "apply", "-f", fileName, "-n", namespace)
-	err := util.RunCommandOnStdIO("kubectl", cmdArgs...)
+func SetWorker(worker []string, filename string) error {
+	//controllerv1alpha1
+	jsonByte, err := getConf(filename)
 	if err != nil {
-		log.Fatalf("Process failed %v", err)
+		return err
 	}
-}
-
-func SetWorker(worker []string, filename string) {
-	//controllerv1alpha1
-	jsonByte := getConf(filename)
 	var value string
 	value = string(jsonByte)
 	log.Println("roshani", len(worker))
@@ -190,20 +258,20 @@ func SetWorker(worker []string, filename string) {
 			value, _ = sjson.Set(value, "spec.clusters."+strconv.Itoa(i), worker[i])
 		}
 	}
-	err := ioutil.WriteFile(filename, []byte(value), 0644)
+	err = ioutil.WriteFile(filename, []byte(value), 0644)
 	if err != nil {
-		log.Fatalf("file writing error #%v ", err)
+		return fmt.Errorf("file writing error #%w ", err)
 	}
+	return nil
 }
 
 // func SetKeys(filename string){
 
 // }
-func getConf(filename string) []byte {
+func getConf(filename string) ([]byte, error) {
 	yamlFile, err := ioutil.ReadFile(filename)
 	if err != nil {
-		log.Fatalf("yamlFile.Get err   #%v ", err)
+		return nil, fmt.Errorf("yamlFile.Get err   #%w ", err)
 	}
-	jsonByte, err := YAML.YAMLToJSON(yamlFile)
-	return jsonByte
+	return YAML.YAMLToJSON(yamlFile)
 }
@@ -0,0 +1,124 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/kubeslice/kubeslice-cli/util"
+)
+
+// Parallelism is the number of clusters worked on at the same time.
+var Parallelism = 1
+
+// ClusterErrors are the errors of the clusters some work failed on, by
+// cluster name.
+type ClusterErrors map[string]error
+
+func (e ClusterErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, name := range e.clusterNames() {
+		messages = append(messages, fmt.Sprintf("%s: %v", name, e[name]))
+	}
+	return strings.Join(messages, "\n")
+}
+
+func (e ClusterErrors) clusterNames() []string {
+	names := make([]string, 0, len(e))
+	for name := range e {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// forEachCluster runs f for every cluster, Parallelism clusters at a time.
+// A failing cluster does not stop the others, the errors are returned as
+// ClusterErrors once every cluster is done. No cluster is started once ctx is
+// done.
+func forEachCluster(ctx context.Context, clusters []*Cluster, f func(ctx context.Context, out *util.Output, cluster *Cluster) error) error {
+	parallelism := Parallelism
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	errors := make(ClusterErrors)
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, parallelism)
+	for _, cluster := range clusters {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(cluster *Cluster) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			var err error
+			if ctx.Err() != nil {
+				err = fmt.Errorf("not started: %w", ctx.Err())
+			} else {
+				err = util.RunClusterStep(ctx, cluster.Name, func() error {
+					return f(ctx, util.ClusterOutput(cluster.Name), cluster)
+				})
+			}
+			if err != nil {
+				lock.Lock()
+				errors[cluster.Name] = err
+				lock.Unlock()
+			}
+		}(cluster)
+	}
+	wg.Wait()
+	if len(errors) > 0 {
+		return errors
+	}
+	return nil
+}
+
+// ExitCode is the exit code of the errors when they all have the same, the
+// one of errors of no other class otherwise.
+func (e ClusterErrors) ExitCode() int {
+	code := 0
+	for _, err := range e {
+		switch c := util.ExitCode(err); {
+		case code == 0:
+			code = c
+		case code != c:
+			return util.ExitError
+		}
+	}
+	return code
+}
+
+// clusterErrors reports the clusters the work failed on, and returns the
+// error of the work.
+func clusterErrors(work string, err error) error {
+	if err == nil {
+		return nil
+	}
+	clusterErrors, ok := err.(ClusterErrors)
+	if !ok {
+		return fmt.Errorf("%s failed: %w", work, err)
+	}
+	for _, name := range clusterErrors.clusterNames() {
+		util.Printf("%s [%s] %v", util.Cross, name, clusterErrors[name])
+	}
+	return &workError{work: work, errors: clusterErrors}
+}
+
+// workError is some work failing on several clusters, whose errors were
+// already reported.
+type workError struct {
+	work   string
+	errors ClusterErrors
+}
+
+func (e *workError) Error() string {
+	return fmt.Sprintf("%s failed on %d cluster(s): %s", e.work, len(e.errors), strings.Join(e.errors.clusterNames(), ", "))
+}
+
+func (e *workError) Unwrap() error {
+	return e.errors
+}
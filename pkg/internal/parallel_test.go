@@ -0,0 +1,123 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kubeslice/kubeslice-cli/util"
+)
+
+func testClusters(n int) []*Cluster {
+	clusters := make([]*Cluster, 0, n)
+	for i := 1; i <= n; i++ {
+		clusters = append(clusters, &Cluster{Name: fmt.Sprintf("c-%d", i)})
+	}
+	return clusters
+}
+
+// not parallel, as it sets Parallelism and the output
+func TestForEachCluster(t *testing.T) {
+	parallelism := Parallelism
+	Parallelism = 3
+	var b bytes.Buffer
+	util.SetOutput(&b)
+	t.Cleanup(func() {
+		Parallelism = parallelism
+		util.SetOutput(os.Stdout)
+	})
+
+	var lock sync.Mutex
+	running, maxRunning := 0, 0
+	err := forEachCluster(context.Background(), testClusters(8), func(ctx context.Context, out *util.Output, cluster *Cluster) error {
+		lock.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		lock.Unlock()
+		out.Printf("working on %s", cluster.Name)
+		time.Sleep(10 * time.Millisecond)
+		lock.Lock()
+		running--
+		lock.Unlock()
+		if cluster.Name == "c-2" || cluster.Name == "c-7" {
+			return util.NotFoundErrorf("%s failed", cluster.Name)
+		}
+		return nil
+	})
+
+	clusterErrors, ok := err.(ClusterErrors)
+	if !ok {
+		t.Fatalf("forEachCluster() = %v, want ClusterErrors", err)
+	}
+	names := clusterErrors.clusterNames()
+	if want := []string{"c-2", "c-7"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("forEachCluster() failed on %v, want %v", names, want)
+	}
+	for _, name := range names {
+		if got := clusterErrors[name].Error(); got != name+" failed" {
+			t.Errorf("forEachCluster() returned %q for %s, want the error of the cluster", got, name)
+		}
+	}
+	if got := clusterErrors.ExitCode(); got != util.ExitNotFound {
+		t.Errorf("ClusterErrors.ExitCode() = %d, want %d", got, util.ExitNotFound)
+	}
+	if maxRunning < 2 || maxRunning > 3 {
+		t.Errorf("forEachCluster() ran %d clusters at a time, want up to Parallelism 3", maxRunning)
+	}
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	sort.Strings(lines)
+	if len(lines) != 8 || lines[0] != "[c-1] working on c-1" || lines[7] != "[c-8] working on c-8" {
+		t.Errorf("forEachCluster() printed\n%s\nwant a line per cluster prefixed with its name", b.String())
+	}
+}
+
+// not parallel, as it sets Parallelism
+func TestForEachClusterCancelled(t *testing.T) {
+	parallelism := Parallelism
+	Parallelism = 2
+	t.Cleanup(func() { Parallelism = parallelism })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var lock sync.Mutex
+	started := make([]string, 0)
+	err := forEachCluster(ctx, testClusters(6), func(ctx context.Context, out *util.Output, cluster *Cluster) error {
+		lock.Lock()
+		started = append(started, cluster.Name)
+		lock.Unlock()
+		if cluster.Name == "c-1" {
+			cancel()
+			return nil
+		}
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	sort.Strings(started)
+	if len(started) == 0 || started[0] != "c-1" || len(started) > 2 {
+		t.Errorf("forEachCluster() started %v, want no cluster started after c-1 cancelled", started)
+	}
+	clusterErrors, ok := err.(ClusterErrors)
+	if !ok {
+		t.Fatalf("forEachCluster() = %v, want ClusterErrors", err)
+	}
+	if _, ok := clusterErrors["c-1"]; ok {
+		t.Errorf("forEachCluster() returned %v for c-1, which succeeded", clusterErrors["c-1"])
+	}
+	for _, cluster := range testClusters(6)[2:] {
+		err := clusterErrors[cluster.Name]
+		if !errors.Is(err, context.Canceled) || !strings.HasPrefix(err.Error(), "not started") {
+			t.Errorf("forEachCluster() returned %v for %s, want it not started", err, cluster.Name)
+		}
+	}
+}
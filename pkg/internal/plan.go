@@ -0,0 +1,590 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/kubeslice/kubeslice-cli/util"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	ActionAdd    = "add"
+	ActionChange = "change"
+	ActionRemove = "remove"
+)
+
+// Plan is what an install would change on the clusters.
+type Plan struct {
+	Clusters []*ClusterPlan `json:"clusters"`
+}
+
+// ClusterPlan is what an install would change on a single cluster.
+type ClusterPlan struct {
+	Cluster string        `json:"cluster"`
+	Changes []*PlanChange `json:"changes"`
+}
+
+// PlanChange is a difference between the topology and a cluster. Field is the
+// chart field, helm value or spec field which differs, empty when the whole
+// resource would be added or removed.
+type PlanChange struct {
+	Action   string      `json:"action"`
+	Resource string      `json:"resource"`
+	Field    string      `json:"field,omitempty"`
+	Desired  interface{} `json:"desired,omitempty"`
+	Running  interface{} `json:"running,omitempty"`
+}
+
+// desiredRelease is a helm release as install would deploy it.
+type desiredRelease struct {
+	name      string
+	namespace string
+	chart     HelmChart
+	values    map[interface{}]interface{}
+	// ignored are values generated on the clusters, which are not compared
+	ignored []string
+}
+
+// desiredObject is a KubeSlice object as install would apply it. Only the
+// fields set by install are compared, the others are defaulted on the cluster.
+type desiredObject struct {
+	resource string
+	name     string
+	spec     map[interface{}]interface{}
+}
+
+// clusterDesiredState is what install deploys on a cluster.
+type clusterDesiredState struct {
+	releases []*desiredRelease
+	// managedReleases are the releases install can deploy on the cluster, by
+	// namespace, which are reported for removal when not desired
+	managedReleases map[string]string
+	objects         []*desiredObject
+	namespace       string
+	// managedObjects tells, by resource, which running objects are reported
+	// for removal when not desired
+	managedObjects map[string]func(object map[string]interface{}) bool
+}
+
+// HelmRelease is a release as listed by helm.
+type HelmRelease struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Revision  string `json:"revision"`
+	Chart     string `json:"chart"`
+	Status    string `json:"status"`
+}
+
+// ChartVersion returns the version of chartName the release was deployed
+// with, or "" if it was deployed from another chart.
+func (r HelmRelease) ChartVersion(chartName string) string {
+	if !strings.HasPrefix(r.Chart, chartName+"-") {
+		return ""
+	}
+	return strings.TrimPrefix(r.Chart, chartName+"-")
+}
+
+// MakePlan compares what the install components would deploy with what is
+// running on the clusters.
+func MakePlan(ctx context.Context, ApplicationConfiguration *ConfigurationSpecs, components []string) (*Plan, error) {
+	states, err := desiredStates(ApplicationConfiguration, components)
+	if err != nil {
+		return nil, err
+	}
+	clusters := getAllClusters(&ApplicationConfiguration.Configuration.ClusterConfiguration)
+	plans := make(map[string]*ClusterPlan)
+	for _, cluster := range clusters {
+		plans[cluster.Name] = &ClusterPlan{Cluster: cluster.Name, Changes: make([]*PlanChange, 0)}
+	}
+	err = forEachCluster(ctx, clusters, func(ctx context.Context, out *util.Output, cluster *Cluster) error {
+		changes, err := planCluster(ctx, cluster, states[cluster.Name])
+		if err != nil {
+			return err
+		}
+		plans[cluster.Name].Changes = changes
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	plan := &Plan{}
+	for _, cluster := range clusters {
+		plan.Clusters = append(plan.Clusters, plans[cluster.Name])
+	}
+	return plan, nil
+}
+
+// desiredStates renders the releases and objects of the components for each
+// cluster, the way install generates them.
+func desiredStates(ApplicationConfiguration *ConfigurationSpecs, components []string) (map[string]*clusterDesiredState, error) {
+	config := ApplicationConfiguration.Configuration
+	cc := config.ClusterConfiguration
+	hc := config.HelmChartConfiguration
+	projectNamespace := "kubeslice-" + config.KubeSliceConfiguration.ProjectName
+	selected := func(component string) bool { return containsString(components, component) }
+
+	states := make(map[string]*clusterDesiredState)
+	controller := &clusterDesiredState{
+		managedReleases: make(map[string]string),
+		namespace:       projectNamespace,
+		managedObjects:  make(map[string]func(object map[string]interface{}) bool),
+	}
+	states[cc.ControllerCluster.Name] = controller
+	// the releases of skipped components are left as they are
+	for component, release := range map[string][2]string{
+		CertManager_Component: {"cert-manager", "cert-manager"},
+		Controller_Component:  {KUBESLICE_CONTROLLER_NAMESPACE, KUBESLICE_CONTROLLER_NAMESPACE},
+		UI_install_Component:  {"kubeslice-ui", KUBESLICE_CONTROLLER_NAMESPACE},
+	} {
+		if selected(component) {
+			controller.managedReleases[release[0]] = release[1]
+		}
+	}
+	if selected(CertManager_Component) {
+		// cert-manager is installed with --set installCRDs=true only
+		controller.releases = append(controller.releases, &desiredRelease{name: "cert-manager", namespace: "cert-manager", chart: hc.CertManagerChart,
+			values: map[interface{}]interface{}{"installCRDs": true}})
+	}
+	if selected(Controller_Component) {
+		values, err := renderValues(&hc.ControllerChart, controllerValuesDefaults(cc.ControllerCluster, hc))
+		if err != nil {
+			return nil, err
+		}
+		controller.releases = append(controller.releases, &desiredRelease{name: KUBESLICE_CONTROLLER_NAMESPACE, namespace: KUBESLICE_CONTROLLER_NAMESPACE, chart: hc.ControllerChart, values: values})
+	}
+	if selected(UI_install_Component) && hc.UIChart.ChartName != "" {
+		values, err := renderValues(&hc.UIChart, uiValuesDefaults(cc.ClusterType, hc))
+		if err != nil {
+			return nil, err
+		}
+		controller.releases = append(controller.releases, &desiredRelease{name: "kubeslice-ui", namespace: KUBESLICE_CONTROLLER_NAMESPACE, chart: hc.UIChart, values: values})
+	}
+	if selected(Project_Component) {
+		objects, err := manifestObjects(renderProjectManifest(config.KubeSliceConfiguration.ProjectName, config.KubeSliceConfiguration.ProjectUsers))
+		if err != nil {
+			return nil, err
+		}
+		controller.objects = append(controller.objects, objects...)
+	}
+	if selected(Worker_registration_Component) {
+		objects, err := manifestObjects(renderClusterRegistrationManifest(ApplicationConfiguration, projectNamespace))
+		if err != nil {
+			return nil, err
+		}
+		controller.objects = append(controller.objects, objects...)
+		controller.managedObjects[ClusterObject] = func(map[string]interface{}) bool { return true }
+	}
+	if selected(Prometheus_Component) {
+		for _, cluster := range cc.WorkerClusters {
+			telemetry := map[interface{}]interface{}{"clusterProperty": map[interface{}]interface{}{"telemetry": map[interface{}]interface{}{
+				"enabled": true, "endpoint": fmt.Sprintf("http://%s:32700", cluster.NodeIP), "telemetryProvider": "prometheus",
+			}}}
+			if object := controller.object(ClusterObject, cluster.Name); object != nil {
+				object.spec = mergeMaps(object.spec, telemetry)
+			} else {
+				controller.objects = append(controller.objects, &desiredObject{resource: ClusterObject, name: cluster.Name, spec: telemetry})
+			}
+		}
+	}
+	if selected(Slice_Component) {
+		for _, slice := range ConfiguredSlices(ApplicationConfiguration) {
+			manifest, _ := renderSliceManifest(slice, projectNamespace)
+			objects, err := manifestObjects(manifest)
+			if err != nil {
+				return nil, err
+			}
+			controller.objects = append(controller.objects, objects...)
+		}
+		// slices created by other means than the topology are left alone
+		controller.managedObjects[SliceConfigObject] = func(object map[string]interface{}) bool {
+			metadata, _ := object["metadata"].(map[string]interface{})
+			annotations, _ := metadata["annotations"].(map[string]interface{})
+			_, ok := annotations[sliceHashAnnotation]
+			return ok
+		}
+	}
+
+	for _, cluster := range cc.WorkerClusters {
+		worker := &clusterDesiredState{managedReleases: make(map[string]string)}
+		if selected(Worker_Component) {
+			worker.managedReleases["kubeslice-worker"] = "kubeslice-system"
+		}
+		if selected(Prometheus_Component) {
+			worker.managedReleases["prometheus"] = PrometheusNamespace
+			if hc.PrometheusChart.ChartName != "" {
+				worker.managedReleases[hc.PrometheusChart.ChartName] = PrometheusNamespace
+			}
+		}
+		states[cluster.Name] = worker
+		if selected(Worker_Component) {
+			overrides, err := ClusterValues(cluster)
+			if err != nil {
+				return nil, fmt.Errorf("Unable to read values files of %s: %s", cluster.Name, err)
+			}
+			// the controller secret is generated when the worker is registered
+			secrets := map[string]string{
+				"namespace":          dryRunValue("namespace", cluster.Name),
+				"controllerEndpoint": dryRunValue("controllerEndpoint", cluster.Name),
+				"ca.crt":             dryRunValue("ca.crt", cluster.Name),
+				"token":              dryRunValue("token", cluster.Name),
+			}
+			values, err := renderValues(&hc.WorkerChart, workerValuesDefaults(cluster, config, secrets, cc.ClusterType == Kind_Component), overrides...)
+			if err != nil {
+				return nil, err
+			}
+			worker.releases = append(worker.releases, &desiredRelease{name: "kubeslice-worker", namespace: "kubeslice-system", chart: hc.WorkerChart, values: values,
+				ignored: []string{"controllerSecret"}})
+		}
+		if selected(Prometheus_Component) {
+			values, err := renderValues(&hc.PrometheusChart, "")
+			if err != nil {
+				return nil, err
+			}
+			worker.releases = append(worker.releases, &desiredRelease{name: hc.PrometheusChart.ChartName, namespace: PrometheusNamespace, chart: hc.PrometheusChart, values: values})
+		}
+	}
+	return states, nil
+}
+
+func (s *clusterDesiredState) object(resource, name string) *desiredObject {
+	for _, object := range s.objects {
+		if object.resource == resource && object.name == name {
+			return object
+		}
+	}
+	return nil
+}
+
+// manifestObjects returns the objects of a rendered manifest.
+func manifestObjects(manifest string) ([]*desiredObject, error) {
+	objects := make([]*desiredObject, 0)
+	decoder := yaml.NewDecoder(strings.NewReader(manifest))
+	for {
+		var object struct {
+			Kind     string `yaml:"kind"`
+			Metadata struct {
+				Name string `yaml:"name"`
+			} `yaml:"metadata"`
+			Spec map[interface{}]interface{} `yaml:"spec"`
+		}
+		err := decoder.Decode(&object)
+		if err == io.EOF {
+			return objects, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read manifest %v", err)
+		}
+		if object.Kind == "" {
+			continue
+		}
+		if object.Spec == nil {
+			object.Spec = make(map[interface{}]interface{})
+		}
+		objects = append(objects, &desiredObject{resource: kindResources[object.Kind], name: object.Metadata.Name, spec: object.Spec})
+	}
+}
+
+var kindResources = map[string]string{
+	"Project":     ProjectObject,
+	"Cluster":     ClusterObject,
+	"SliceConfig": SliceConfigObject,
+}
+
+// planCluster compares the desired state of cluster with what is running on it.
+func planCluster(ctx context.Context, cluster *Cluster, desired *clusterDesiredState) ([]*PlanChange, error) {
+	changes := make([]*PlanChange, 0)
+	releases, err := ListHelmReleases(ctx, cluster)
+	if err != nil {
+		return nil, err
+	}
+	desiredNames := make(map[string]bool)
+	for _, release := range desired.releases {
+		desiredNames[release.name] = true
+		running, ok := releases[release.namespace+"/"+release.name]
+		if !ok {
+			changes = append(changes, &PlanChange{Action: ActionAdd, Resource: "releases/" + release.name, Desired: chartDescription(release.chart)})
+			continue
+		}
+		values, err := getHelmValues(ctx, cluster, release.name, release.namespace)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, diffRelease(release, running, values)...)
+	}
+	managed := make([]string, 0)
+	for name := range desired.managedReleases {
+		managed = append(managed, name)
+	}
+	sort.Strings(managed)
+	for _, name := range managed {
+		if running, ok := releases[desired.managedReleases[name]+"/"+name]; ok && !desiredNames[name] {
+			changes = append(changes, &PlanChange{Action: ActionRemove, Resource: "releases/" + name, Running: running.Chart})
+		}
+	}
+
+	resources := make([]string, 0)
+	for _, object := range desired.objects {
+		if !containsString(resources, object.resource) {
+			resources = append(resources, object.resource)
+		}
+	}
+	for resource := range desired.managedObjects {
+		if !containsString(resources, resource) {
+			resources = append(resources, resource)
+		}
+	}
+	for _, resource := range resources {
+		namespace := desired.namespace
+		if resource == ProjectObject {
+			namespace = KUBESLICE_CONTROLLER_NAMESPACE
+		}
+		running, err := listObjects(ctx, cluster, resource, namespace)
+		if err != nil {
+			return nil, err
+		}
+		for _, object := range desired.objects {
+			if object.resource == resource {
+				changes = append(changes, diffObject(object, running[object.name])...)
+			}
+		}
+		if managed := desired.managedObjects[resource]; managed != nil {
+			names := make([]string, 0)
+			for name, object := range running {
+				if desired.object(resource, name) == nil && managed(object) {
+					names = append(names, name)
+				}
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				changes = append(changes, &PlanChange{Action: ActionRemove, Resource: resourceName(resource, name)})
+			}
+		}
+	}
+	return changes, nil
+}
+
+func diffRelease(release *desiredRelease, running HelmRelease, runningValues map[string]interface{}) []*PlanChange {
+	changes := make([]*PlanChange, 0)
+	resource := "releases/" + release.name
+	version := running.ChartVersion(release.chart.ChartName)
+	if version == "" {
+		changes = append(changes, &PlanChange{Action: ActionChange, Resource: resource, Field: "chart", Desired: release.chart.ChartName, Running: running.Chart})
+	} else if release.chart.Version != "" && version != strings.TrimPrefix(release.chart.Version, "v") {
+		changes = append(changes, &PlanChange{Action: ActionChange, Resource: resource, Field: "version", Desired: release.chart.Version, Running: version})
+	}
+	if running.Status != "deployed" {
+		changes = append(changes, &PlanChange{Action: ActionChange, Resource: resource, Field: "status", Desired: "deployed", Running: running.Status})
+	}
+	desired := flattenValues(normalizeValue(release.values).(map[string]interface{}))
+	current := flattenValues(runningValues)
+	ignored := func(path string) bool {
+		for _, prefix := range release.ignored {
+			if path == prefix || strings.HasPrefix(path, prefix+".") {
+				return true
+			}
+		}
+		return false
+	}
+	for _, path := range unionKeys(desired, current) {
+		if ignored(path) {
+			continue
+		}
+		change := diffValue(path, desired, current)
+		if change != nil {
+			change.Resource = resource
+			change.Field = "values." + path
+			changes = append(changes, change)
+		}
+	}
+	return changes
+}
+
+// diffObject compares the fields install sets on an object with the running
+// object, which is nil if it does not exist.
+func diffObject(object *desiredObject, running map[string]interface{}) []*PlanChange {
+	resource := resourceName(object.resource, object.name)
+	desiredSpec := normalizeValue(object.spec)
+	if running == nil {
+		return []*PlanChange{{Action: ActionAdd, Resource: resource, Desired: desiredSpec}}
+	}
+	runningSpec, _ := running["spec"].(map[string]interface{})
+	desired := flattenValues(desiredSpec.(map[string]interface{}))
+	current := flattenValues(runningSpec)
+	changes := make([]*PlanChange, 0)
+	for _, path := range unionKeys(desired, nil) {
+		if change := diffValue(path, desired, current); change != nil {
+			change.Resource = resource
+			change.Field = "spec." + path
+			changes = append(changes, change)
+		}
+	}
+	return changes
+}
+
+func diffValue(path string, desired, running map[string]interface{}) *PlanChange {
+	desiredValue, inDesired := desired[path]
+	runningValue, inRunning := running[path]
+	switch {
+	case inDesired && !inRunning:
+		return &PlanChange{Action: ActionAdd, Desired: maskValue(path, desiredValue)}
+	case !inDesired && inRunning:
+		return &PlanChange{Action: ActionRemove, Running: maskValue(path, runningValue)}
+	case !sameValue(desiredValue, runningValue):
+		return &PlanChange{Action: ActionChange, Desired: maskValue(path, desiredValue), Running: maskValue(path, runningValue)}
+	}
+	return nil
+}
+
+// maskValue keeps credentials out of the plan.
+func maskValue(path string, value interface{}) interface{} {
+	key := path[strings.LastIndex(path, ".")+1:]
+	if key == "password" || key == "token" {
+		return "(sensitive)"
+	}
+	return value
+}
+
+// sameValue compares values read from yaml and json, in which numbers are
+// decoded differently.
+func sameValue(a, b interface{}) bool {
+	aJSON, _ := json.Marshal(a)
+	bJSON, _ := json.Marshal(b)
+	return bytes.Equal(aJSON, bJSON)
+}
+
+// normalizeValue turns the maps decoded from yaml into maps keyed by strings,
+// as decoded from json.
+func normalizeValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			m[fmt.Sprint(key)] = normalizeValue(item)
+		}
+		return m
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			list[i] = normalizeValue(item)
+		}
+		return list
+	}
+	return value
+}
+
+// flattenValues returns the leaves of values by dotted path, lists being
+// compared as a whole.
+func flattenValues(values map[string]interface{}) map[string]interface{} {
+	flat := make(map[string]interface{})
+	var flatten func(prefix string, value interface{})
+	flatten = func(prefix string, value interface{}) {
+		if m, ok := value.(map[string]interface{}); ok && (len(m) > 0 || prefix == "") {
+			for key, item := range m {
+				flatten(joinPath(prefix, key), item)
+			}
+			return
+		}
+		flat[prefix] = value
+	}
+	flatten("", values)
+	return flat
+}
+
+func unionKeys(a, b map[string]interface{}) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for key := range a {
+		keys = append(keys, key)
+	}
+	for key := range b {
+		if _, ok := a[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func resourceName(resource, name string) string {
+	return strings.Split(resource, ".")[0] + "/" + name
+}
+
+func chartDescription(chart HelmChart) string {
+	if chart.Version == "" {
+		return chart.ChartName + " latest"
+	}
+	return chart.ChartName + " " + chart.Version
+}
+
+// ListHelmReleases returns the releases deployed on cluster by namespace/name.
+func ListHelmReleases(ctx context.Context, cluster *Cluster) (map[string]HelmRelease, error) {
+	client, err := NewHelmClient(nil, cluster)
+	if err != nil {
+		return nil, err
+	}
+	list, err := client.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	releases := make(map[string]HelmRelease)
+	for _, release := range list {
+		releases[release.Namespace+"/"+release.Name] = release
+	}
+	return releases, nil
+}
+
+// getHelmValues returns the values a release was deployed with.
+func getHelmValues(ctx context.Context, cluster *Cluster, name, namespace string) (map[string]interface{}, error) {
+	client, err := NewHelmClient(nil, cluster)
+	if err != nil {
+		return nil, err
+	}
+	values, err := client.GetValues(ctx, name, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the values of %s %w", name, err)
+	}
+	return values, nil
+}
+
+// listObjects returns the objects of resource in namespace by name, none if
+// the resource is not installed on the cluster.
+func listObjects(ctx context.Context, cluster *Cluster, resource, namespace string) (map[string]map[string]interface{}, error) {
+	objects := make(map[string]map[string]interface{})
+	client, err := NewKubeClient(cluster)
+	if err != nil {
+		return nil, err
+	}
+	items, err := client.List(ctx, resource, namespace, "")
+	if err != nil {
+		if util.ExitCode(err) == util.ExitNotFound {
+			return objects, nil
+		}
+		return nil, fmt.Errorf("failed to list %s %w", resource, err)
+	}
+	for _, item := range items {
+		objects[item.GetName()] = item.Object
+	}
+	return objects, nil
+}
+
+// Summary counts the changes by action.
+func (p *Plan) Summary() (add, change, remove int) {
+	for _, cluster := range p.Clusters {
+		for _, c := range cluster.Changes {
+			switch c.Action {
+			case ActionAdd:
+				add++
+			case ActionChange:
+				change++
+			case ActionRemove:
+				remove++
+			}
+		}
+	}
+	return add, change, remove
+}
@@ -0,0 +1,218 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// PodBackoffLimit is how many times the pods of a namespace may be found
+// failing before the wait for them gives up.
+var PodBackoffLimit = 20
+
+// failingReasons are the reasons a container waits for that it does not get
+// out of without the pod being fixed or restarted.
+var failingReasons = map[string]bool{
+	"CrashLoopBackOff":           true,
+	"ImagePullBackOff":           true,
+	"ErrImagePull":               true,
+	"InvalidImageName":           true,
+	"CreateContainerConfigError": true,
+	"CreateContainerError":       true,
+	"RunContainerError":          true,
+}
+
+// workloadObject is the part of a Pod, Deployment or DaemonSet the readiness
+// checks read.
+type workloadObject struct {
+	Kind     string `json:"kind"`
+	Metadata struct {
+		Name            string `json:"name"`
+		Generation      int64  `json:"generation"`
+		OwnerReferences []struct {
+			Kind string `json:"kind"`
+		} `json:"ownerReferences"`
+	} `json:"metadata"`
+	Spec struct {
+		Replicas *int32 `json:"replicas"`
+	} `json:"spec"`
+	Status struct {
+		// Pod
+		Phase                 string            `json:"phase"`
+		Reason                string            `json:"reason"`
+		Message               string            `json:"message"`
+		Conditions            []podCondition    `json:"conditions"`
+		InitContainerStatuses []containerStatus `json:"initContainerStatuses"`
+		ContainerStatuses     []containerStatus `json:"containerStatuses"`
+		// Deployment and DaemonSet
+		ObservedGeneration     int64 `json:"observedGeneration"`
+		Replicas               int32 `json:"replicas"`
+		UpdatedReplicas        int32 `json:"updatedReplicas"`
+		AvailableReplicas      int32 `json:"availableReplicas"`
+		DesiredNumberScheduled int32 `json:"desiredNumberScheduled"`
+		UpdatedNumberScheduled int32 `json:"updatedNumberScheduled"`
+		NumberAvailable        int32 `json:"numberAvailable"`
+	} `json:"status"`
+}
+
+type podCondition struct {
+	Type   string `json:"type"`
+	Status string `json:"status"`
+}
+
+type containerStatus struct {
+	Name         string         `json:"name"`
+	Ready        bool           `json:"ready"`
+	RestartCount int            `json:"restartCount"`
+	State        containerState `json:"state"`
+	LastState    containerState `json:"lastState"`
+}
+
+type containerState struct {
+	Waiting *struct {
+		Reason  string `json:"reason"`
+		Message string `json:"message"`
+	} `json:"waiting"`
+	Terminated *struct {
+		Reason   string `json:"reason"`
+		Message  string `json:"message"`
+		ExitCode int    `json:"exitCode"`
+	} `json:"terminated"`
+}
+
+// describe tells why the container is not running, with its last state.
+func (s containerStatus) describe() string {
+	var description string
+	switch {
+	case s.State.Waiting != nil:
+		description = fmt.Sprintf("container %s waiting: %s%s", s.Name, s.State.Waiting.Reason, detail(s.State.Waiting.Message))
+	case s.State.Terminated != nil:
+		t := s.State.Terminated
+		description = fmt.Sprintf("container %s terminated: %s (exit code %d)%s", s.Name, t.Reason, t.ExitCode, detail(t.Message))
+	default:
+		description = fmt.Sprintf("container %s not ready", s.Name)
+	}
+	if t := s.LastState.Terminated; t != nil {
+		description += fmt.Sprintf(", last terminated: %s (exit code %d)%s", t.Reason, t.ExitCode, detail(t.Message))
+	}
+	if s.RestartCount > 0 {
+		description += fmt.Sprintf(", restarted %d times", s.RestartCount)
+	}
+	return description
+}
+
+func detail(message string) string {
+	if message = strings.TrimSpace(message); message == "" {
+		return ""
+	}
+	return ": " + strings.ReplaceAll(message, "\n", " ")
+}
+
+// readiness tells whether the object is ready, and why not if it is not.
+func (o *workloadObject) readiness() (PodVerificationStatus, string) {
+	switch o.Kind {
+	case "Pod":
+		return o.podReadiness()
+	case "Deployment":
+		replicas := int32(1)
+		if o.Spec.Replicas != nil {
+			replicas = *o.Spec.Replicas
+		}
+		s := o.Status
+		if s.ObservedGeneration >= o.Metadata.Generation && s.UpdatedReplicas == replicas && s.Replicas == replicas && s.AvailableReplicas == replicas {
+			return PodVerificationStatusSuccess, ""
+		}
+		return PodVerificationStatusInProgress, fmt.Sprintf("deployment/%s: %d of %d replicas updated, %d available", o.Metadata.Name, s.UpdatedReplicas, replicas, s.AvailableReplicas)
+	case "DaemonSet":
+		s := o.Status
+		if s.ObservedGeneration >= o.Metadata.Generation && s.UpdatedNumberScheduled == s.DesiredNumberScheduled && s.NumberAvailable == s.DesiredNumberScheduled {
+			return PodVerificationStatusSuccess, ""
+		}
+		return PodVerificationStatusInProgress, fmt.Sprintf("daemonset/%s: %d of %d pods updated, %d available", o.Metadata.Name, s.UpdatedNumberScheduled, s.DesiredNumberScheduled, s.NumberAvailable)
+	}
+	return PodVerificationStatusSuccess, ""
+}
+
+func (o *workloadObject) podReadiness() (PodVerificationStatus, string) {
+	name := "pod/" + o.Metadata.Name
+	switch o.Status.Phase {
+	case "Succeeded":
+		return PodVerificationStatusSuccess, ""
+	case "Failed":
+		// the pods of a job are left behind when they terminate, the job
+		// runs new ones in their place
+		if o.ownedBy("Job") {
+			return PodVerificationStatusSuccess, ""
+		}
+		return PodVerificationStatusFailed, fmt.Sprintf("%s failed: %s%s", name, o.Status.Reason, detail(o.Status.Message))
+	}
+	containers := append(append([]containerStatus{}, o.Status.InitContainerStatuses...), o.Status.ContainerStatuses...)
+	for _, c := range containers {
+		failing := c.State.Waiting != nil && failingReasons[c.State.Waiting.Reason] ||
+			c.State.Terminated != nil && c.State.Terminated.ExitCode != 0
+		if failing {
+			return PodVerificationStatusFailed, name + " " + c.describe()
+		}
+	}
+	for _, condition := range o.Status.Conditions {
+		if condition.Type == "Ready" && condition.Status == "True" {
+			return PodVerificationStatusSuccess, ""
+		}
+	}
+	for _, c := range o.Status.ContainerStatuses {
+		if !c.Ready {
+			return PodVerificationStatusInProgress, name + " " + c.describe()
+		}
+	}
+	return PodVerificationStatusInProgress, fmt.Sprintf("%s %s", name, strings.ToLower(o.Status.Phase))
+}
+
+func (o *workloadObject) ownedBy(kind string) bool {
+	for _, owner := range o.Metadata.OwnerReferences {
+		if owner.Kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// namespaceReadiness tells whether the pods, deployments and daemonsets of
+// namespace are ready, and lists the ones that are not. Failing pods make the
+// namespace failed, the others make it in progress.
+func namespaceReadiness(ctx context.Context, cluster Cluster, namespace string) (PodVerificationStatus, []string, error) {
+	client, err := NewKubeClient(&cluster)
+	if err != nil {
+		return PodVerificationStatusFailed, nil, err
+	}
+	objects := make([]workloadObject, 0)
+	for _, kind := range []string{"Pod", "Deployment", "DaemonSet"} {
+		items, err := client.List(ctx, strings.ToLower(kind)+"s", namespace, "")
+		if err != nil {
+			if ctx.Err() != nil {
+				return PodVerificationStatusFailed, nil, err
+			}
+			return PodVerificationStatusFailed, []string{err.Error()}, nil
+		}
+		for i := range items {
+			var object workloadObject
+			if err := fromJSON(&items[i], &object); err != nil {
+				return PodVerificationStatusFailed, nil, fmt.Errorf("failed to read the pods of %s %v", namespace, err)
+			}
+			object.Kind = kind
+			objects = append(objects, object)
+		}
+	}
+	status := PodVerificationStatusSuccess
+	problems := make([]string, 0)
+	for i := range objects {
+		s, problem := objects[i].readiness()
+		if s == PodVerificationStatusSuccess {
+			continue
+		}
+		problems = append(problems, problem)
+		if status != PodVerificationStatusFailed {
+			status = s
+		}
+	}
+	return status, problems, nil
+}
@@ -0,0 +1,181 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/kubeslice/kubeslice-cli/util"
+)
+
+// containers returns the statuses of n ready containers, as JSON
+func containers(n int, ready bool) string {
+	statuses := make([]string, n)
+	for i := range statuses {
+		statuses[i] = fmt.Sprintf(`{"name":"c%d","ready":%t,"state":{"running":{}}}`, i, ready)
+	}
+	return "[" + strings.Join(statuses, ",") + "]"
+}
+
+func TestReadiness(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		object      string
+		wantStatus  PodVerificationStatus
+		wantProblem string
+	}{
+		{
+			name:       "pod with 11 ready containers",
+			object:     `{"kind":"Pod","metadata":{"name":"p"},"status":{"phase":"Running","conditions":[{"type":"Ready","status":"True"}],"containerStatuses":` + containers(11, true) + `}}`,
+			wantStatus: PodVerificationStatusSuccess,
+		},
+		{
+			name:        "pod with 11 containers, not ready",
+			object:      `{"kind":"Pod","metadata":{"name":"p"},"status":{"phase":"Running","conditions":[{"type":"Ready","status":"False"}],"containerStatuses":` + containers(11, false) + `}}`,
+			wantStatus:  PodVerificationStatusInProgress,
+			wantProblem: "pod/p container c0 not ready",
+		},
+		{
+			name:       "ready pod named after an error",
+			object:     `{"kind":"Pod","metadata":{"name":"Error-reporter"},"status":{"phase":"Running","message":"Error","conditions":[{"type":"Ready","status":"True"}],"containerStatuses":` + containers(1, true) + `}}`,
+			wantStatus: PodVerificationStatusSuccess,
+		},
+		{
+			name: "pod crashing",
+			object: `{"kind":"Pod","metadata":{"name":"p"},"status":{"phase":"Running","containerStatuses":[{"name":"app","restartCount":3,
+				"state":{"waiting":{"reason":"CrashLoopBackOff","message":"back-off 40s"}},
+				"lastState":{"terminated":{"reason":"Error","exitCode":1,"message":"config missing"}}}]}}`,
+			wantStatus:  PodVerificationStatusFailed,
+			wantProblem: "pod/p container app waiting: CrashLoopBackOff: back-off 40s, last terminated: Error (exit code 1): config missing, restarted 3 times",
+		},
+		{
+			name: "pod restarted after being killed",
+			object: `{"kind":"Pod","metadata":{"name":"p"},"status":{"phase":"Running","containerStatuses":[{"name":"app","restartCount":1,"state":{"running":{}},
+				"lastState":{"terminated":{"reason":"OOMKilled","exitCode":137}}}]}}`,
+			wantStatus:  PodVerificationStatusInProgress,
+			wantProblem: "pod/p container app not ready, last terminated: OOMKilled (exit code 137), restarted 1 times",
+		},
+		{
+			name:        "init container failed",
+			object:      `{"kind":"Pod","metadata":{"name":"p"},"status":{"phase":"Pending","initContainerStatuses":[{"name":"init","state":{"terminated":{"reason":"Error","exitCode":2}}}]}}`,
+			wantStatus:  PodVerificationStatusFailed,
+			wantProblem: "pod/p container init terminated: Error (exit code 2)",
+		},
+		{
+			name:        "pod pending",
+			object:      `{"kind":"Pod","metadata":{"name":"p"},"status":{"phase":"Pending"}}`,
+			wantStatus:  PodVerificationStatusInProgress,
+			wantProblem: "pod/p pending",
+		},
+		{
+			name:        "pod failed",
+			object:      `{"kind":"Pod","metadata":{"name":"p"},"status":{"phase":"Failed","reason":"Evicted","message":"The node was low on resource: memory."}}`,
+			wantStatus:  PodVerificationStatusFailed,
+			wantProblem: "pod/p failed: Evicted: The node was low on resource: memory.",
+		},
+		{
+			name:       "job pod failed",
+			object:     `{"kind":"Pod","metadata":{"name":"p","ownerReferences":[{"kind":"Job","name":"migrate"}]},"status":{"phase":"Failed","containerStatuses":[{"name":"job","state":{"terminated":{"reason":"Error","exitCode":1}}}]}}`,
+			wantStatus: PodVerificationStatusSuccess,
+		},
+		{
+			name:       "job pod succeeded",
+			object:     `{"kind":"Pod","metadata":{"name":"p","ownerReferences":[{"kind":"Job","name":"migrate"}]},"status":{"phase":"Succeeded"}}`,
+			wantStatus: PodVerificationStatusSuccess,
+		},
+		{
+			name:       "deployment rolled out",
+			object:     `{"kind":"Deployment","metadata":{"name":"d","generation":2},"spec":{"replicas":3},"status":{"observedGeneration":2,"replicas":3,"updatedReplicas":3,"availableReplicas":3}}`,
+			wantStatus: PodVerificationStatusSuccess,
+		},
+		{
+			name:        "deployment rolling out",
+			object:      `{"kind":"Deployment","metadata":{"name":"d","generation":2},"spec":{"replicas":3},"status":{"observedGeneration":2,"replicas":4,"updatedReplicas":2,"availableReplicas":3}}`,
+			wantStatus:  PodVerificationStatusInProgress,
+			wantProblem: "deployment/d: 2 of 3 replicas updated, 3 available",
+		},
+		{
+			name:        "deployment not observed",
+			object:      `{"kind":"Deployment","metadata":{"name":"d","generation":3},"status":{"observedGeneration":2,"replicas":1,"updatedReplicas":1,"availableReplicas":1}}`,
+			wantStatus:  PodVerificationStatusInProgress,
+			wantProblem: "deployment/d: 1 of 1 replicas updated, 1 available",
+		},
+		{
+			name:       "daemonset rolled out",
+			object:     `{"kind":"DaemonSet","metadata":{"name":"ds","generation":1},"status":{"observedGeneration":1,"desiredNumberScheduled":12,"updatedNumberScheduled":12,"numberAvailable":12}}`,
+			wantStatus: PodVerificationStatusSuccess,
+		},
+		{
+			name:        "daemonset rolling out",
+			object:      `{"kind":"DaemonSet","metadata":{"name":"ds","generation":1},"status":{"observedGeneration":1,"desiredNumberScheduled":12,"updatedNumberScheduled":12,"numberAvailable":10}}`,
+			wantStatus:  PodVerificationStatusInProgress,
+			wantProblem: "daemonset/ds: 12 of 12 pods updated, 10 available",
+		},
+	}
+	for _, tc := range tests {
+		tc := tc // Capture range variable for parallel execution
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			var object workloadObject
+			if err := json.Unmarshal([]byte(tc.object), &object); err != nil {
+				t.Fatalf("Failed to setup test: %v", err)
+			}
+			status, problem := object.readiness()
+			if status != tc.wantStatus || problem != tc.wantProblem {
+				t.Errorf("readiness() = %d, %q, want %d, %q", status, problem, tc.wantStatus, tc.wantProblem)
+			}
+		})
+	}
+}
+
+// not parallel, as it sets the executor, KubeBackend and ExecutablePaths
+func TestNamespaceReadiness(t *testing.T) {
+	backend := KubeBackend
+	KubeBackend, util.ExecutablePaths = KubeBackendKubectl, map[string]string{"kubectl": "kubectl"}
+	t.Cleanup(func() { KubeBackend, util.ExecutablePaths = backend, nil })
+
+	running := `{"metadata":{"name":"worker"},"status":{"phase":"Running","conditions":[{"type":"Ready","status":"True"}],"containerStatuses":` + containers(12, true) + `}}`
+	oldJob := `{"metadata":{"name":"install-crds","ownerReferences":[{"kind":"Job"}]},"status":{"phase":"Failed"}}`
+	crashing := `{"metadata":{"name":"router"},"status":{"phase":"Running","containerStatuses":[{"name":"router","restartCount":5,
+		"state":{"waiting":{"reason":"CrashLoopBackOff"}},"lastState":{"terminated":{"reason":"Error","exitCode":1}}}]}}`
+	rollingOut := `{"metadata":{"name":"operator","generation":1},"spec":{"replicas":1},"status":{"observedGeneration":1,"replicas":1,"updatedReplicas":1,"availableReplicas":0}}`
+	rolledOut := `{"metadata":{"name":"operator","generation":1},"spec":{"replicas":1},"status":{"observedGeneration":1,"replicas":1,"updatedReplicas":1,"availableReplicas":1}}`
+	list := func(items ...string) util.FakeResponse {
+		return util.FakeResponse{Stdout: `{"items":[` + strings.Join(items, ",") + `]}`}
+	}
+
+	tests := []struct {
+		name         string
+		pods         []string
+		deployments  []string
+		wantStatus   PodVerificationStatus
+		wantProblems []string
+	}{
+		{name: "ready", pods: []string{running, oldJob}, deployments: []string{rolledOut}, wantStatus: PodVerificationStatusSuccess, wantProblems: []string{}},
+		{name: "rolling out", pods: []string{running}, deployments: []string{rollingOut}, wantStatus: PodVerificationStatusInProgress,
+			wantProblems: []string{"deployment/operator: 1 of 1 replicas updated, 0 available"}},
+		{name: "crashing", pods: []string{crashing, running}, deployments: []string{rollingOut}, wantStatus: PodVerificationStatusFailed,
+			wantProblems: []string{"pod/router container router waiting: CrashLoopBackOff, last terminated: Error (exit code 1), restarted 5 times", "deployment/operator: 1 of 1 replicas updated, 0 available"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fake := &util.FakeExecutor{}
+			fake.Respond(" get pods ", list(tc.pods...)).
+				Respond(" get deployments ", list(tc.deployments...)).
+				Respond(" get daemonsets ", list())
+			defer util.SetExecutor(util.SetExecutor(fake))
+
+			status, problems, err := namespaceReadiness(context.Background(), Cluster{ContextName: "ctx"}, "kubeslice-system")
+			if err != nil {
+				t.Fatalf("namespaceReadiness() returned unexpected error %v", err)
+			}
+			if status != tc.wantStatus || strings.Join(problems, "\n") != strings.Join(tc.wantProblems, "\n") {
+				t.Errorf("namespaceReadiness() = %d, %q, want %d, %q", status, problems, tc.wantStatus, tc.wantProblems)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"context"
 	"fmt"
 	"os/exec"
 
@@ -102,26 +103,38 @@ Note: The DNS propagation may take a minute or two.
 %s %s
 `
 
-func PrintNextSteps(verificationOnly bool, ApplicationConfiguration *ConfigurationSpecs) {
+func PrintNextSteps(ctx context.Context, verificationOnly bool, ApplicationConfiguration *ConfigurationSpecs) error {
+	if profile := ApplicationConfiguration.InstallProfile; profile != nil && profile.NextSteps != "" {
+		nextSteps, err := renderNextSteps(profile, ApplicationConfiguration)
+		if err != nil {
+			util.Printf("%s Unable to print the next steps of profile %s: %v", util.Cross, profile.Name, err)
+			return nil
+		}
+		util.Printf(nextSteps)
+		return nil
+	}
 	if verificationOnly {
-		printVerificationSteps(ApplicationConfiguration)
-	} else {
-		printNamespaceIsolationSteps(ApplicationConfiguration)
+		return printVerificationSteps(ctx, ApplicationConfiguration)
 	}
+	printNamespaceIsolationSteps(ApplicationConfiguration)
+	return nil
 }
 
-func printVerificationSteps(ApplicationConfiguration *ConfigurationSpecs) {
+func printVerificationSteps(ctx context.Context, ApplicationConfiguration *ConfigurationSpecs) error {
 	var template string
 	username := "admin"
 	clusters := ApplicationConfiguration.Configuration.ClusterConfiguration.WorkerClusters
 	iperfCommand := exec.Command(util.ExecutablePaths["kubectl"], "--context="+clusters[1].ContextName, "--kubeconfig="+clusters[1].KubeConfigPath, "exec", "-it", "deploy/iperf-sleep", "-c", "iperf", "-n", "iperf", "--", "iperf", "-c", "iperf-server.iperf.svc.slice.local", "-p", "5201", "-i", "1", "-b", "10Mb;")
 
-	if ApplicationConfiguration.Configuration.ClusterConfiguration.Profile == ProfileEntDemo {
-		token := GetUIAdminToken(
+	if ApplicationConfiguration.Enterprise() {
+		token, err := GetUIAdminToken(ctx,
 			&ApplicationConfiguration.Configuration.ClusterConfiguration.ControllerCluster,
 			username,
 			ApplicationConfiguration.Configuration.KubeSliceConfiguration.ProjectName)
-		endpoint := GetUIEndpoint(&ApplicationConfiguration.Configuration.ClusterConfiguration.ControllerCluster, ProfileEntDemo)
+		if err != nil {
+			return err
+		}
+		endpoint := GetUIEndpoint(ctx, &ApplicationConfiguration.Configuration.ClusterConfiguration.ControllerCluster, true)
 		template = fmt.Sprintf(printEntVerificationStepsTemplate,
 			util.Globe, endpoint,
 			util.Lock, token,
@@ -134,13 +147,14 @@ func printVerificationSteps(ApplicationConfiguration *ConfigurationSpecs) {
 		)
 	}
 	util.Printf(template)
+	return nil
 }
 
 func printNamespaceIsolationSteps(ApplicationConfiguration *ConfigurationSpecs) {
 	cc := ApplicationConfiguration.Configuration.ClusterConfiguration.ControllerCluster
 	wc := ApplicationConfiguration.Configuration.ClusterConfiguration.WorkerClusters
 	iperfCommand := exec.Command(util.ExecutablePaths["kubectl"], "--context="+wc[1].ContextName, "--kubeconfig="+wc[1].KubeConfigPath, "exec", "-it", "deploy/iperf-sleep", "-c", "iperf", "-n", "iperf", "--", "iperf", "-c", "iperf-server.iperf.svc.slice.local", "-p", "5201", "-i", "1", "-b", "10Mb;")
-	sliceApplyCommand := exec.Command(util.ExecutablePaths["kubectl"], "--context="+cc.ContextName, "--kubeconfig="+cc.KubeConfigPath, "apply", "-f", kubesliceDirectory+"/"+sliceTemplateFileName)
+	sliceApplyCommand := exec.Command(util.ExecutablePaths["kubectl"], "--context="+cc.ContextName, "--kubeconfig="+cc.KubeConfigPath, "apply", "-f", kubesliceDirectory+"/"+sliceFileName(DemoSlice(ApplicationConfiguration).Name))
 	sliceVerifyCommandWorker1 := exec.Command(util.ExecutablePaths["kubectl"], "--context="+wc[0].ContextName, "--kubeconfig="+wc[0].KubeConfigPath, "get", "slice", "-n", "kubeslice-system")
 	sliceVerifyCommandWorker2 := exec.Command(util.ExecutablePaths["kubectl"], "--context="+wc[1].ContextName, "--kubeconfig="+wc[1].KubeConfigPath, "get", "slice", "-n", "kubeslice-system")
 	applyIPerfWorker1 := exec.Command(util.ExecutablePaths["kubectl"], "rollout ", "restart", "deployment/iperf-server", "-n", "iperf", "--context="+wc[0].ContextName, "--kubeconfig="+wc[0].KubeConfigPath)
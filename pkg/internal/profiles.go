@@ -0,0 +1,225 @@
+package internal
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/kubeslice/kubeslice-cli/util"
+	"gopkg.in/yaml.v2"
+)
+
+// profiles shipped with the CLI, a profile of the same name in the profiles
+// directory takes precedence
+//
+//go:embed profiles/*.yaml
+var builtinProfiles embed.FS
+
+// DemoAppIPerf is the iPerf client and server demo application
+const DemoAppIPerf = "iperf"
+
+// steps a profile can skip
+var installSteps = []string{Kind_Component, Calico_Component, NetworkInfo_Component, HelmRepo_Component, CertManager_Component,
+	Controller_Component, Project_Component, UI_install_Component, Worker_registration_Component, Worker_Component,
+	Prometheus_Component, Slice_Component, Demo_Component, ServiceExport_Component}
+
+// InstallProfile bundles a kind demo topology with the way it is installed.
+type InstallProfile struct {
+	Name string `yaml:"-"`
+	// Source is the file the profile was read from, empty for built-in profiles
+	Source      string      `yaml:"-"`
+	Data        []byte      `yaml:"-"`
+	Description string      `yaml:"description"`
+	Enterprise  bool        `yaml:"enterprise"`
+	SkipSteps   []string    `yaml:"skip_steps"`
+	Demo        ProfileDemo `yaml:"demo"`
+	// NextSteps is a text/template printed once the install completes, the
+	// steps of the demo apps are printed when empty
+	NextSteps string             `yaml:"next_steps"`
+	Topology  ConfigurationSpecs `yaml:"topology"`
+}
+
+type ProfileDemo struct {
+	Apps []string `yaml:"apps"`
+	// ApplySlice applies the demo slice and service exports, otherwise they
+	// are generated for the user to apply by following the next steps
+	ApplySlice bool `yaml:"apply_slice"`
+}
+
+// ProfilesDirectory returns the directory user-defined profiles are read
+// from: $KUBESLICE_PROFILES_DIR if it is set, ~/.kubeslice/profiles otherwise.
+func ProfilesDirectory() string {
+	if dir := os.Getenv("KUBESLICE_PROFILES_DIR"); dir != "" {
+		return dir
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".kubeslice", "profiles")
+}
+
+// ListProfiles returns the built-in profiles and the ones found in dir,
+// sorted by name.
+func ListProfiles(dir string) ([]*InstallProfile, []ConfigError) {
+	profiles := make(map[string]*InstallProfile)
+	errors := make([]ConfigError, 0)
+	entries, _ := builtinProfiles.ReadDir("profiles")
+	for _, entry := range entries {
+		data, err := builtinProfiles.ReadFile("profiles/" + entry.Name())
+		if err != nil {
+			return nil, []ConfigError{{File: entry.Name(), Message: err.Error()}}
+		}
+		profile, profileErrors := parseProfile(entry.Name(), data)
+		errors = append(errors, profileErrors...)
+		if profile != nil {
+			profiles[profile.Name] = profile
+		}
+	}
+	if dir != "" {
+		files, err := ioutil.ReadDir(dir)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, []ConfigError{{File: dir, Message: fmt.Sprintf("failed to read profiles directory %v", err)}}
+		}
+		for _, file := range files {
+			if file.IsDir() || (filepath.Ext(file.Name()) != ".yaml" && filepath.Ext(file.Name()) != ".yml") {
+				continue
+			}
+			fileName := filepath.Join(dir, file.Name())
+			data, err := ioutil.ReadFile(fileName)
+			if err != nil {
+				errors = append(errors, ConfigError{File: fileName, Message: fmt.Sprintf("failed to read profile %v", err)})
+				continue
+			}
+			profile, profileErrors := parseProfile(fileName, data)
+			errors = append(errors, profileErrors...)
+			if profile != nil {
+				profile.Source = fileName
+				profiles[profile.Name] = profile
+			}
+		}
+	}
+	list := make([]*InstallProfile, 0, len(profiles))
+	for _, profile := range profiles {
+		list = append(list, profile)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list, errors
+}
+
+// LoadProfile returns the profile called name.
+func LoadProfile(dir, name string) (*InstallProfile, []ConfigError) {
+	profiles, errors := ListProfiles(dir)
+	// a broken profile file only prevents using that profile
+	profileErrors := make([]ConfigError, 0)
+	for _, e := range errors {
+		if strings.TrimSuffix(filepath.Base(e.File), filepath.Ext(e.File)) == name {
+			profileErrors = append(profileErrors, e)
+		}
+	}
+	if len(profileErrors) > 0 {
+		return nil, profileErrors
+	}
+	names := make([]string, 0, len(profiles))
+	for _, profile := range profiles {
+		if profile.Name == name {
+			return profile, nil
+		}
+		names = append(names, profile.Name)
+	}
+	return nil, []ConfigError{{Message: fmt.Sprintf("unknown profile: %s. Possible values %s", name, names)}}
+}
+
+func parseProfile(fileName string, data []byte) (*InstallProfile, []ConfigError) {
+	doc, err := ParseConfigDocument(fileName, data)
+	if err != nil {
+		return nil, []ConfigError{err.(ConfigError)}
+	}
+	if errors := doc.CheckSchema(&InstallProfile{}); len(errors) > 0 {
+		return nil, errors
+	}
+	profile := &InstallProfile{}
+	if err := yaml.Unmarshal(data, profile); err != nil {
+		return nil, []ConfigError{{File: fileName, Message: err.Error()}}
+	}
+	profile.Name = strings.TrimSuffix(filepath.Base(fileName), filepath.Ext(fileName))
+	profile.Data = data
+	errors := make([]ConfigError, 0)
+	errorAt := func(path, format string, a ...interface{}) {
+		node, _ := doc.Lookup(path)
+		errors = append(errors, doc.errorAt(node, path, format, a...))
+	}
+	for i, step := range profile.SkipSteps {
+		if !containsString(installSteps, step) {
+			errorAt(fmt.Sprintf("skip_steps[%d]", i), "unknown step: %s. Possible values %s", step, installSteps)
+		}
+	}
+	for i, app := range profile.Demo.Apps {
+		if app != DemoAppIPerf {
+			errorAt(fmt.Sprintf("demo.apps[%d]", i), "unknown demo app: %s. Possible values %s", app, []string{DemoAppIPerf})
+		}
+	}
+	if _, err := profile.nextStepsTemplate(); err != nil {
+		errorAt("next_steps", "invalid template: %v", err)
+	}
+	if len(errors) > 0 {
+		return nil, errors
+	}
+	return profile, nil
+}
+
+// DefaultTopology returns a copy of the default topology of the profile, set
+// up for a kind demo.
+func (p *InstallProfile) DefaultTopology() *ConfigurationSpecs {
+	specs := &ConfigurationSpecs{}
+	// round trip through yaml to deep-copy the topology
+	data, _ := yaml.Marshal(p.Topology)
+	yaml.Unmarshal(data, specs)
+	specs.Configuration.ClusterConfiguration.Profile = p.Name
+	specs.Configuration.ClusterConfiguration.ClusterType = Kind_Component
+	specs.InstallProfile = p
+	return specs
+}
+
+// HasDemoApp tells whether the profile installs the demo application app.
+func (p *InstallProfile) HasDemoApp(app string) bool {
+	return containsString(p.Demo.Apps, app)
+}
+
+func (p *InstallProfile) nextStepsTemplate() (*template.Template, error) {
+	return template.New(p.Name).Funcs(template.FuncMap{
+		// kubectl returns the kubectl command line targeting cluster
+		"kubectl": func(cluster Cluster) string {
+			return fmt.Sprintf("%s --context=%s --kubeconfig=%s", util.ExecutablePaths["kubectl"], cluster.ContextName, cluster.KubeConfigPath)
+		},
+	}).Parse(p.NextSteps)
+}
+
+// renderNextSteps renders the next steps of the profile, with the
+// configuration as data.
+func renderNextSteps(p *InstallProfile, ApplicationConfiguration *ConfigurationSpecs) (string, error) {
+	tmpl, err := p.nextStepsTemplate()
+	if err != nil {
+		return "", err
+	}
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, ApplicationConfiguration.Configuration); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -1,8 +1,8 @@
 package internal
 
 import (
+	"context"
 	"fmt"
-	"time"
 
 	"github.com/kubeslice/kubeslice-cli/util"
 )
@@ -22,31 +22,48 @@ spec:
     readWrite: %s
 `
 
-func CreateKubeSliceProject(ApplicationConfiguration *ConfigurationSpecs, cliOptions *CliOptionsStruct) {
+func CreateKubeSliceProject(ctx context.Context, ApplicationConfiguration *ConfigurationSpecs, cliOptions *CliOptionsStruct) error {
 	util.Printf("\nCreating KubeSlice Project...")
 
-	generateKubeSliceProjectManifest(ApplicationConfiguration.Configuration.KubeSliceConfiguration.ProjectName, ApplicationConfiguration.Configuration.KubeSliceConfiguration.ProjectUsers)
+	if err := generateKubeSliceProjectManifest(ApplicationConfiguration.Configuration.KubeSliceConfiguration.ProjectName, ApplicationConfiguration.Configuration.KubeSliceConfiguration.ProjectUsers); err != nil {
+		return err
+	}
 	util.Printf("%s Generated project manifest %s", util.Tick, projectFileName)
-	time.Sleep(200 * time.Millisecond)
 	if cliOptions != nil {
 		if cliOptions.FileName == "" {
 			cliOptions.FileName = kubesliceDirectory + "/" + projectFileName
 		}
-		ApplyKubectlManifest(cliOptions.FileName, cliOptions.Namespace, cliOptions.Cluster)
+		if err := ApplyKubectlManifest(ctx, cliOptions.FileName, cliOptions.Namespace, cliOptions.Cluster); err != nil {
+			return err
+		}
+		util.Printf("%s Applied %s", util.Tick, projectFileName)
 	} else {
-		ApplyKubectlManifest(kubesliceDirectory+"/"+projectFileName, KUBESLICE_CONTROLLER_NAMESPACE, &ApplicationConfiguration.Configuration.ClusterConfiguration.ControllerCluster)
+		cc := &ApplicationConfiguration.Configuration.ClusterConfiguration.ControllerCluster
+		if err := ApplyKubectlManifest(ctx, kubesliceDirectory+"/"+projectFileName, KUBESLICE_CONTROLLER_NAMESPACE, cc); err != nil {
+			return err
+		}
+		util.Printf("%s Applied %s", util.Tick, projectFileName)
+		// the workers are registered in the namespace of the project
+		if err := waitForNamespace(ctx, cc, "kubeslice-"+ApplicationConfiguration.Configuration.KubeSliceConfiguration.ProjectName); err != nil {
+			return err
+		}
 	}
-	util.Printf("%s Applied %s", util.Tick, projectFileName)
-	time.Sleep(3 * time.Second)
 	util.Printf("Created KubeSlice Project.")
+	return nil
 }
 
-func GetKubeSliceProject(projectName string, namespace string, controllerCluster *Cluster) {
+func GetKubeSliceProject(ctx context.Context, projectName string, namespace string, controllerCluster *Cluster) error {
 	util.Printf("\nFetching KubeSlice Project...")
-	GetKubectlResources(ProjectObject, projectName, namespace, controllerCluster, "")
-	time.Sleep(200 * time.Millisecond)
+	if err := GetKubectlResources(ctx, ProjectObject, projectName, namespace, controllerCluster, ""); err != nil {
+		return err
+	}
+	return nil
 }
-func generateKubeSliceProjectManifest(projectName string, users []string) {
+func generateKubeSliceProjectManifest(projectName string, users []string) error {
+	return util.DumpFile(renderProjectManifest(projectName, users), kubesliceDirectory+"/"+projectFileName)
+}
+
+func renderProjectManifest(projectName string, users []string) string {
 	if len(users) == 0 {
 		users = []string{"admin"}
 	}
@@ -54,23 +71,29 @@ func generateKubeSliceProjectManifest(projectName string, users []string) {
 	for _, user := range users {
 		userString = fmt.Sprintf(`%s      - %s%s`, userString, user, "\n")
 	}
-	util.DumpFile(fmt.Sprintf(kubesliceProjectTemplate, projectName, userString), kubesliceDirectory+"/"+projectFileName)
+	return fmt.Sprintf(kubesliceProjectTemplate, projectName, userString)
 }
 
-func DeleteKubeSliceProject(projectName string, namespace string, controllerCluster *Cluster) {
+func DeleteKubeSliceProject(ctx context.Context, projectName string, namespace string, controllerCluster *Cluster) error {
 	util.Printf("\nDeleting KubeSlice Project...")
-	DeleteKubectlResources(ProjectObject, projectName, namespace, controllerCluster)
-	time.Sleep(200 * time.Millisecond)
+	if err := DeleteKubectlResources(ctx, ProjectObject, projectName, namespace, controllerCluster); err != nil {
+		return err
+	}
+	return nil
 }
 
-func EditKubeSliceProject(projectName string, namespace string, controllerCluster *Cluster) {
+func EditKubeSliceProject(ctx context.Context, projectName string, namespace string, controllerCluster *Cluster) error {
 	util.Printf("\nEditing KubeSlice Project...")
-	EditKubectlResources(ProjectObject, projectName, namespace, controllerCluster)
-	time.Sleep(200 * time.Millisecond)
+	if err := EditKubectlResources(ctx, ProjectObject, projectName, namespace, controllerCluster); err != nil {
+		return err
+	}
+	return nil
 }
 
-func DescribeKubeSliceProject(projectName string, namespace string, controllerCluster *Cluster) {
+func DescribeKubeSliceProject(ctx context.Context, projectName string, namespace string, controllerCluster *Cluster) error {
 	util.Printf("\nDescribe KubeSlice Project...")
-	DescribeKubectlResources(ProjectObject, projectName, namespace, controllerCluster)
-	time.Sleep(200 * time.Millisecond)
+	if err := DescribeKubectlResources(ctx, ProjectObject, projectName, namespace, controllerCluster); err != nil {
+		return err
+	}
+	return nil
 }
@@ -1,9 +1,8 @@
 package internal
 
 import (
+	"context"
 	"fmt"
-	"log"
-	"time"
 
 	"github.com/kubeslice/kubeslice-cli/util"
 )
@@ -13,57 +12,68 @@ const (
 	PrometheusNamespace      = "monitoring"
 )
 
-func InstallPrometheus(ApplicationConfiguration *ConfigurationSpecs) {
+func InstallPrometheus(ctx context.Context, ApplicationConfiguration *ConfigurationSpecs) error {
 	util.Printf("\nInstalling Prometheus...")
 
-	wc := ApplicationConfiguration.Configuration.ClusterConfiguration.WorkerClusters
 	cc := ApplicationConfiguration.Configuration.ClusterConfiguration.ControllerCluster
 	hc := ApplicationConfiguration.Configuration.HelmChartConfiguration
-	generatePrometheusValuesFile(hc)
+	if err := generatePrometheusValuesFile(hc); err != nil {
+		return err
+	}
 	util.Printf("%s Generated Helm Values file for Prometheus Installation %s", util.Tick, PrometheusValuesFileName)
-	time.Sleep(200 * time.Millisecond)
-	installPrometheus(wc, &cc, hc, PrometheusValuesFileName)
-	util.Printf("%s Successfully installed Prometheus on Worker clusters.", util.Tick)
-	time.Sleep(200 * time.Millisecond)
-	util.Printf("%s Setting Prometheus endpoint in cluster objects...", util.Wait)
-	projectNamespce := fmt.Sprintf("kubeslice-%s", ApplicationConfiguration.Configuration.KubeSliceConfiguration.ProjectName)
-	patchClusterObjectInControllerCluster(wc, &cc, projectNamespce)
-}
-
-func patchClusterObjectInControllerCluster(wc []Cluster, cc *Cluster, projectNS string) {
-	for _, cluster := range wc {
-		// Patch cluster object in controller cluster
-		err := util.RunCommand("kubectl", "--context", cc.ContextName, "--kubeconfig", cc.KubeConfigPath, "patch", ClusterObject, cluster.Name, "-n", projectNS, "--type", "merge", "-p", fmt.Sprintf("{\"spec\":{\"clusterProperty\":{\"telemetry\":{\"enabled\":true,\"endpoint\":\"http://%s:32700\",\"telemetryProvider\":\"prometheus\"}}}}", cluster.NodeIP))
-		if err != nil {
-			log.Fatalf("Process failed %v", err)
+	projectNamespace := fmt.Sprintf("kubeslice-%s", ApplicationConfiguration.Configuration.KubeSliceConfiguration.ProjectName)
+	workers := getWorkerClusters(&ApplicationConfiguration.Configuration.ClusterConfiguration)
+	err := forEachCluster(ctx, workers, func(ctx context.Context, out *util.Output, cluster *Cluster) error {
+		if err := installPrometheus(ctx, out, *cluster, hc, PrometheusValuesFileName); err != nil {
+			return err
 		}
-		util.Printf("%s Successfully set prometheus endpoint in %s", util.Tick, cluster.Name)
+		out.Printf("%s Setting Prometheus endpoint in cluster object...", util.Wait)
+		return patchClusterObjectInControllerCluster(ctx, out, *cluster, &cc, projectNamespace)
+	})
+	if err := clusterErrors("Installing Prometheus", err); err != nil {
+		return err
 	}
+	util.Printf("%s Successfully installed Prometheus on Worker clusters.", util.Tick)
+	return nil
 }
 
-func generatePrometheusValuesFile(hcConfig HelmChartConfiguration) {
-	err := generateValuesFile(kubesliceDirectory+"/"+PrometheusValuesFileName, &hcConfig.PrometheusChart, "")
+func patchClusterObjectInControllerCluster(ctx context.Context, out *util.Output, cluster Cluster, cc *Cluster, projectNS string) error {
+	// Patch cluster object in controller cluster
+	client, err := NewKubeClient(cc)
 	if err != nil {
-		log.Fatalf("%s %s", util.Cross, err)
+		return err
+	}
+	patch := fmt.Sprintf("{\"spec\":{\"clusterProperty\":{\"telemetry\":{\"enabled\":true,\"endpoint\":\"http://%s:32700\",\"telemetryProvider\":\"prometheus\"}}}}", cluster.NodeIP)
+	if err := client.Patch(ctx, ClusterObject, projectNS, cluster.Name, []byte(patch)); err != nil {
+		return err
 	}
+	out.Printf("%s Successfully set prometheus endpoint in %s", util.Tick, cluster.Name)
+	return nil
 }
 
-func installPrometheus(clusters []Cluster, cc *Cluster, hc HelmChartConfiguration, filename string) {
-	for _, cluster := range clusters {
-		args := make([]string, 0)
-		args = append(args, "--kube-context", cluster.ContextName, "--kubeconfig", cluster.KubeConfigPath, "upgrade", "-i", hc.PrometheusChart.ChartName, fmt.Sprintf("%s/%s", hc.RepoAlias, hc.PrometheusChart.ChartName), "--namespace", PrometheusNamespace, "--create-namespace", "-f", kubesliceDirectory+"/"+filename)
-		if hc.ControllerChart.Version != "" {
-			args = append(args, "--version", hc.PrometheusChart.Version)
-		}
-		err := util.RunCommand("helm", args...)
-		if err != nil {
-			log.Fatalf("Process failed %v", err)
-		}
-		util.Printf("%s Successfully installed helm chart %s/%s on cluster %s", util.Tick, hc.RepoAlias, hc.PrometheusChart.ChartName, cluster.Name)
-		time.Sleep(200 * time.Millisecond)
-		util.Printf("%s Waiting for Prometheus Pods to be Healthy...", util.Wait)
-		PodVerification("Waiting for Prometheus Pods to be Healthy", cluster, PrometheusNamespace)
-		// Patch cluster object in controller cluster
-	}
+func generatePrometheusValuesFile(hcConfig HelmChartConfiguration) error {
+	return generateValuesFile(kubesliceDirectory+"/"+PrometheusValuesFileName, &hcConfig.PrometheusChart, "")
+}
 
+func installPrometheus(ctx context.Context, out *util.Output, cluster Cluster, hc HelmChartConfiguration, filename string) error {
+	recordRelease(ctx, cluster, hc.PrometheusChart.ChartName, PrometheusNamespace)
+	client, err := NewHelmClient(out, &cluster)
+	if err != nil {
+		return err
+	}
+	err = client.Upgrade(ctx, HelmReleaseSpec{
+		Name:            hc.PrometheusChart.ChartName,
+		Namespace:       PrometheusNamespace,
+		Chart:           fmt.Sprintf("%s/%s", hc.RepoAlias, hc.PrometheusChart.ChartName),
+		Version:         hc.PrometheusChart.Version,
+		ValuesFiles:     []string{kubesliceDirectory + "/" + filename},
+		Install:         true,
+		CreateNamespace: true,
+	})
+	if err != nil {
+		return err
+	}
+	out.Printf("%s Successfully installed helm chart %s/%s on cluster %s", util.Tick, hc.RepoAlias, hc.PrometheusChart.ChartName, cluster.Name)
+	out.Printf("%s Waiting for Prometheus Pods to be Healthy...", util.Wait)
+	return podVerification(ctx, out, "Waiting for Prometheus Pods to be Healthy", cluster, PrometheusNamespace)
 }
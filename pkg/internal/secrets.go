@@ -1,36 +1,42 @@
 package internal
 
 import (
-	"bytes"
-	"os/exec"
+	"context"
 	"strings"
-	"time"
 
 	"github.com/kubeslice/kubeslice-cli/util"
 )
 
-func GetSecrets(workerName string, namespace string, controllerCluster *Cluster, outputFormat string) {
+func GetSecrets(ctx context.Context, workerName string, namespace string, controllerCluster *Cluster, outputFormat string) error {
 	util.Printf("\nFetching KubeSlice secret...")
-	SecretName := GetSecretName(workerName, namespace, controllerCluster)
-	GetKubectlResources(SecretObject, SecretName, namespace, controllerCluster, outputFormat)
-	time.Sleep(200 * time.Millisecond)
+	SecretName, err := GetSecretName(ctx, workerName, namespace, controllerCluster)
+	if err != nil {
+		return err
+	}
+	if err := GetKubectlResources(ctx, SecretObject, SecretName, namespace, controllerCluster, outputFormat); err != nil {
+		return err
+	}
+	return nil
 }
 
-func GetSecretName(workerName string, namespace string, controllerCluster *Cluster) string {
-	cmdArgs := []string{}
-	cmdArgs = append(cmdArgs, "get", SecretObject, "-n", namespace)
-	var outB bytes.Buffer
-	c1 := exec.Command("/home/excellarate/.local/bin/kubectl", cmdArgs...)
-	c2 := exec.Command("grep", "worker-"+workerName)
-	c3 := exec.Command("awk", "{print $1}")
-	c2.Stdin, _ = c1.StdoutPipe()
-	c3.Stdin, _ = c2.StdoutPipe()
-	c3.Stdout = &outB
-	_ = c2.Start()
-	_ = c3.Start()
-	_ = c1.Run()
-	_ = c2.Wait()
-	_ = c3.Wait()
-	s := outB.String()
-	return strings.TrimSuffix(s, "\n")
+// GetSecretName returns the name of the secret of the worker workerName in
+// namespace.
+func GetSecretName(ctx context.Context, workerName string, namespace string, controllerCluster *Cluster) (string, error) {
+	client, err := NewKubeClient(controllerCluster)
+	if err != nil {
+		return "", err
+	}
+	secrets, err := client.List(ctx, SecretObject, namespace, "")
+	if err != nil {
+		return "", err
+	}
+	if util.DryRun {
+		return dryRunValue("worker-secret", workerName), nil
+	}
+	for _, secret := range secrets {
+		if strings.Contains(secret.GetName(), "worker-"+workerName) {
+			return secret.GetName(), nil
+		}
+	}
+	return "", util.NotFoundErrorf("secret of worker %s not found in %s", workerName, namespace)
 }
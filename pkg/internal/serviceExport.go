@@ -1,7 +1,9 @@
 package internal
 
 import (
-	"time"
+	"context"
+	"fmt"
+	"sort"
 
 	"github.com/kubeslice/kubeslice-cli/util"
 )
@@ -10,34 +12,123 @@ const (
 	serviceExportConfigFileName = "serviceExportConfig.yaml"
 )
 
-func CreateServiceExportConfig(namespace string, controllerCluster *Cluster, filename string) {
-	ApplyFile(filename, namespace, controllerCluster)
+const serviceExportTemplate = `
+---
+apiVersion: networking.kubeslice.io/v1beta1
+kind: ServiceExport
+metadata:
+  name: %s
+  namespace: %s
+spec:
+  slice: %s
+  selector:
+    matchLabels:%s
+  ingressEnabled: %t
+  ports:%s
+`
+
+func serviceExportFileName(se ServiceExport) string {
+	return fmt.Sprintf("service-export-%s-%s-%s.yaml", se.Worker, se.Namespace, se.Name)
+}
+
+func renderServiceExportManifest(se ServiceExport) string {
+	keys := make([]string, 0, len(se.Selector))
+	for key := range se.Selector {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	labels := ""
+	for _, key := range keys {
+		labels += "\n      " + yamlScalar(key) + ": " + yamlScalar(se.Selector[key])
+	}
+	ports := ""
+	for _, port := range se.Ports {
+		protocol := port.Protocol
+		if protocol == "" {
+			protocol = "TCP"
+		}
+		ports += fmt.Sprintf("\n  - name: %s\n    containerPort: %d\n    protocol: %s", yamlScalar(port.Name), port.ContainerPort, yamlScalar(protocol))
+	}
+	return fmt.Sprintf(serviceExportTemplate, se.Name, se.Namespace, se.Slice, labels, se.IngressEnabled, ports)
+}
+
+// GenerateServiceExportManifests writes the manifests of the ServiceExports
+// declared in the topology.
+func GenerateServiceExportManifests(ApplicationConfiguration *ConfigurationSpecs) error {
+	util.Printf("\nGenerating Service Export manifests to %s directory", kubesliceDirectory)
+	for _, se := range ApplicationConfiguration.Configuration.KubeSliceConfiguration.ServiceExports {
+		if err := util.DumpFile(renderServiceExportManifest(se), kubesliceDirectory+"/"+serviceExportFileName(se)); err != nil {
+			return err
+		}
+		util.Printf("%s Generated %s for cluster %s", util.Tick, serviceExportFileName(se), se.Worker)
+	}
+	return nil
+}
+
+// ApplyServiceExportManifests applies every declared ServiceExport to its
+// worker, once its slice has been set up on that worker.
+func ApplyServiceExportManifests(ctx context.Context, ApplicationConfiguration *ConfigurationSpecs) error {
+	workers := ApplicationConfiguration.Configuration.ClusterConfiguration.WorkerClusters
+	for _, se := range ApplicationConfiguration.Configuration.KubeSliceConfiguration.ServiceExports {
+		var worker *Cluster
+		for i := range workers {
+			if workers[i].Name == se.Worker {
+				worker = &workers[i]
+			}
+		}
+		if worker == nil {
+			return util.NotFoundErrorf("Worker %s of service export %s not found", se.Worker, se.Name)
+		}
+		if err := waitForSlice(ctx, nil, se.Slice, worker); err != nil {
+			return err
+		}
+		if err := ApplyKubectlManifest(ctx, kubesliceDirectory+"/"+serviceExportFileName(se), se.Namespace, worker); err != nil {
+			return err
+		}
+		util.Printf("%s Applied service export %s/%s to %s", util.Tick, se.Namespace, se.Name, worker.Name)
+	}
+	return nil
+}
+
+func CreateServiceExportConfig(ctx context.Context, namespace string, controllerCluster *Cluster, filename string) error {
+	if err := ApplyFile(ctx, filename, namespace, controllerCluster); err != nil {
+		return err
+	}
 	util.Printf("\nSuccessfully Applied Slice Configuration.")
+	return nil
 }
 
-func GetServiceExportConfig(serviceExportConfigName string, namespace string, controllerCluster *Cluster) {
+func GetServiceExportConfig(ctx context.Context, serviceExportConfigName string, namespace string, controllerCluster *Cluster) error {
 	util.Printf("\nFetching KubeSlice serviceExportConfig...")
-	GetKubectlResources(ServiceExportConfigObject, serviceExportConfigName, namespace, controllerCluster, "")
-	time.Sleep(200 * time.Millisecond)
+	if err := GetKubectlResources(ctx, ServiceExportConfigObject, serviceExportConfigName, namespace, controllerCluster, ""); err != nil {
+		return err
+	}
+	return nil
 }
 func generateServiceExportConfigManifest(serviceExportConfigName string) {
 	//util.DumpFile(fmt.Sprintf(ServiceExportConfigTemplate, serviceExportConfigName), kubesliceDirectory+"/"+serviceExportConfigFileName)
 }
 
-func DeleteServiceExportConfig(serviceExportConfigName string, namespace string, controllerCluster *Cluster) {
+func DeleteServiceExportConfig(ctx context.Context, serviceExportConfigName string, namespace string, controllerCluster *Cluster) error {
 	util.Printf("\nDeleting KubeSlice serviceExportConfig...")
-	DeleteKubectlResources(ServiceExportConfigObject, serviceExportConfigName, namespace, controllerCluster)
-	time.Sleep(200 * time.Millisecond)
+	if err := DeleteKubectlResources(ctx, ServiceExportConfigObject, serviceExportConfigName, namespace, controllerCluster); err != nil {
+		return err
+	}
+	return nil
 }
 
-func EditServiceExportConfig(serviceExportConfigName string, namespace string, controllerCluster *Cluster) {
+func EditServiceExportConfig(ctx context.Context, serviceExportConfigName string, namespace string, controllerCluster *Cluster) error {
 	util.Printf("\nEditing KubeSlice serviceExportConfig...")
-	EditKubectlResources(ServiceExportConfigObject, serviceExportConfigName, namespace, controllerCluster)
-	time.Sleep(200 * time.Millisecond)
+	if err := EditKubectlResources(ctx, ServiceExportConfigObject, serviceExportConfigName, namespace, controllerCluster); err != nil {
+		return err
+	}
+	return nil
 }
 
-func DescribeServiceExportConfig(serviceExportConfigName string, namespace string, controllerCluster *Cluster) {
+func DescribeServiceExportConfig(ctx context.Context, serviceExportConfigName string, namespace string, controllerCluster *Cluster) error {
 	util.Printf("\nDescribe KubeSlice serviceExportConfig...")
-	DescribeKubectlResources(ServiceExportConfigObject, serviceExportConfigName, namespace, controllerCluster)
-	time.Sleep(200 * time.Millisecond)
+	if err := DescribeKubectlResources(ctx, ServiceExportConfigObject, serviceExportConfigName, namespace, controllerCluster); err != nil {
+		return err
+	}
+	return nil
 }
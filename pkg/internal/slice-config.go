@@ -1,16 +1,18 @@
 package internal
 
 import (
-	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"strings"
-	"time"
 
 	"github.com/kubeslice/kubeslice-cli/util"
 )
 
 const (
-	sliceTemplateFileName = "slice-demo.yaml"
+	// sliceHashAnnotation records the definition a SliceConfig was applied from
+	sliceHashAnnotation = "cli.kubeslice.io/definition-hash"
 )
 
 const sliceTemplate = `
@@ -19,112 +21,308 @@ kind: SliceConfig
 metadata:
   name: %s
   namespace: %s
+  annotations:
+    ` + sliceHashAnnotation + `: %s
 spec:
-  sliceSubnet: 10.1.0.0/16
+  sliceSubnet: %s
   sliceType: Application
   sliceGatewayProvider:
-    sliceGatewayType: OpenVPN
+    sliceGatewayType: %s
     sliceCaType: Local
   sliceIpamType: Local
   clusters: [%s]
   qosProfileDetails:
-    queueType: HTB
-    priority: 1
-    tcType: BANDWIDTH_CONTROL
-    bandwidthCeilingKbps: 5120
-    bandwidthGuaranteedKbps: 2560
-    dscpClass: AF11
+    queueType: %s
+    priority: %d
+    tcType: %s
+    bandwidthCeilingKbps: %d
+    bandwidthGuaranteedKbps: %d
+    dscpClass: %s
   namespaceIsolationProfile:
-   applicationNamespaces:
-    - namespace: iperf
-      clusters:
-      - '*'
+   applicationNamespaces:%s
 `
 
-func GenerateSliceConfiguration(ApplicationConfiguration *ConfigurationSpecs, worker []string, sliceConfigName string, namespace string) {
-	util.Printf("\nGenerating Slice Configuration to %s directory", kubesliceDirectory)
-	clusters := make([]string, 0)
-	if len(worker) != 0 {
-		clusters = append(clusters, worker...)
-	} else {
-		wc := ApplicationConfiguration.Configuration.ClusterConfiguration.WorkerClusters
-		for _, cluster := range wc {
-			clusters = append(clusters, cluster.Name)
+// demoSlice is the slice used by the demo profiles and when a topology does
+// not declare any slice.
+func demoSlice(clusters []string) Slice {
+	return Slice{
+		Name:     "demo",
+		Clusters: clusters,
+		ApplicationNamespaces: []ApplicationNamespace{
+			{Namespace: "iperf", Clusters: []string{"*"}},
+		},
+	}
+}
+
+func workerNames(ApplicationConfiguration *ConfigurationSpecs) []string {
+	workers := make([]string, 0)
+	for _, cluster := range ApplicationConfiguration.Configuration.ClusterConfiguration.WorkerClusters {
+		workers = append(workers, cluster.Name)
+	}
+	return workers
+}
+
+// ConfiguredSlices returns the slices declared in the topology with their
+// defaults filled in, or the demo slice across every worker if none are.
+func ConfiguredSlices(ApplicationConfiguration *ConfigurationSpecs) []Slice {
+	workers := workerNames(ApplicationConfiguration)
+	declared := ApplicationConfiguration.Configuration.KubeSliceConfiguration.Slices
+	if len(declared) == 0 {
+		declared = []Slice{demoSlice(nil)}
+	}
+	slices := make([]Slice, 0, len(declared))
+	for _, slice := range declared {
+		if len(slice.Clusters) == 0 {
+			slice.Clusters = workers
+		}
+		slices = append(slices, withSliceDefaults(slice))
+	}
+	return slices
+}
+
+// DemoSlice returns the slice the demo profiles onboard their applications
+// on, whether or not the topology declares other slices. A slice named demo
+// declared in the topology takes its place.
+func DemoSlice(ApplicationConfiguration *ConfigurationSpecs) Slice {
+	for _, slice := range ApplicationConfiguration.Configuration.KubeSliceConfiguration.Slices {
+		if slice.Name == "demo" {
+			if len(slice.Clusters) == 0 {
+				slice.Clusters = workerNames(ApplicationConfiguration)
+			}
+			return withSliceDefaults(slice)
+		}
+	}
+	return withSliceDefaults(demoSlice(workerNames(ApplicationConfiguration)))
+}
+
+// InstalledSlices returns the slices an install sets up: the configured
+// slices, and the demo slice when a profile installs it.
+func InstalledSlices(ApplicationConfiguration *ConfigurationSpecs) []Slice {
+	slices := ConfiguredSlices(ApplicationConfiguration)
+	if ApplicationConfiguration.InstallProfile == nil {
+		return slices
+	}
+	demo := DemoSlice(ApplicationConfiguration)
+	for _, slice := range slices {
+		if slice.Name == demo.Name {
+			return slices
+		}
+	}
+	return append(slices, demo)
+}
+
+func withSliceDefaults(slice Slice) Slice {
+	if slice.SliceSubnet == "" {
+		slice.SliceSubnet = "10.1.0.0/16"
+	}
+	if slice.SliceGatewayType == "" {
+		slice.SliceGatewayType = "OpenVPN"
+	}
+	qos := &slice.QosProfile
+	if qos.QueueType == "" {
+		qos.QueueType = "HTB"
+	}
+	if qos.Priority == nil {
+		priority := 1
+		qos.Priority = &priority
+	}
+	if qos.TcType == "" {
+		qos.TcType = "BANDWIDTH_CONTROL"
+	}
+	if qos.BandwidthCeilingKbps == 0 {
+		qos.BandwidthCeilingKbps = 5120
+	}
+	if qos.BandwidthGuaranteedKbps == 0 {
+		qos.BandwidthGuaranteedKbps = 2560
+	}
+	if qos.DscpClass == "" {
+		qos.DscpClass = "AF11"
+	}
+	return slice
+}
+
+// renderSliceManifest returns the SliceConfig of slice along with the hash of
+// its definition, which is stored on the object to detect changes.
+func renderSliceManifest(slice Slice, namespace string) (string, string) {
+	namespaces := " []"
+	if len(slice.ApplicationNamespaces) > 0 {
+		namespaces = ""
+		for _, ns := range slice.ApplicationNamespaces {
+			clusters := ns.Clusters
+			if len(clusters) == 0 {
+				clusters = []string{"*"}
+			}
+			namespaces += "\n    - namespace: " + yamlScalar(ns.Namespace) + "\n      clusters:"
+			for _, cluster := range clusters {
+				namespaces += "\n      - " + yamlScalar(cluster)
+			}
 		}
 	}
-	clusterString := strings.Join(clusters, ",")
-	if len(sliceConfigName) == 0 {
-		sliceConfigName = "demo"
+	render := func(hash string) string {
+		qos := slice.QosProfile
+		return fmt.Sprintf(sliceTemplate, slice.Name, namespace, hash,
+			yamlScalar(slice.SliceSubnet), yamlScalar(slice.SliceGatewayType), strings.Join(slice.Clusters, ","),
+			yamlScalar(qos.QueueType), *qos.Priority, yamlScalar(qos.TcType), qos.BandwidthCeilingKbps, qos.BandwidthGuaranteedKbps, yamlScalar(qos.DscpClass),
+			namespaces)
+	}
+	sum := sha256.Sum256([]byte(render("")))
+	hash := hex.EncodeToString(sum[:])[:16]
+	return render(hash), hash
+}
+
+func sliceFileName(sliceName string) string {
+	return "slice-" + sliceName + ".yaml"
+}
+
+func GenerateSliceConfiguration(ApplicationConfiguration *ConfigurationSpecs, worker []string, sliceConfigName string, namespace string) error {
+	util.Printf("\nGenerating Slice Configuration to %s directory", kubesliceDirectory)
+	slices := ConfiguredSlices(ApplicationConfiguration)
+	if len(worker) != 0 || len(sliceConfigName) != 0 {
+		slice := withSliceDefaults(demoSlice(worker))
+		if len(worker) == 0 {
+			slice.Clusters = slices[0].Clusters
+		}
+		if len(sliceConfigName) != 0 {
+			slice.Name = sliceConfigName
+		}
+		slices = []Slice{slice}
 	}
 	projectNamespace := "kubeslice-" + ApplicationConfiguration.Configuration.KubeSliceConfiguration.ProjectName
 	if len(namespace) != 0 {
 		projectNamespace = namespace
 	}
-	util.DumpFile(fmt.Sprintf(sliceTemplate, sliceConfigName, projectNamespace, clusterString), kubesliceDirectory+"/"+"slice-"+sliceConfigName+".yaml")
-	util.Printf("%s Generated %s", util.Tick, "slice-"+sliceConfigName+".yaml")
-	time.Sleep(200 * time.Millisecond)
+	return writeSliceManifests(slices, projectNamespace)
+}
 
-	util.Printf("Generated Slice Configuration")
+// GenerateDemoSliceConfiguration generates the SliceConfig of the demo slice
+// only, leaving the slices declared in the topology to the slice step.
+func GenerateDemoSliceConfiguration(ApplicationConfiguration *ConfigurationSpecs) error {
+	util.Printf("\nGenerating Slice Configuration to %s directory", kubesliceDirectory)
+	return writeSliceManifests([]Slice{DemoSlice(ApplicationConfiguration)}, "kubeslice-"+ApplicationConfiguration.Configuration.KubeSliceConfiguration.ProjectName)
 }
 
-func ApplySliceConfiguration(ApplicationConfiguration *ConfigurationSpecs) {
-	verifyNodeIPsInClusters(ApplicationConfiguration)
-	util.Printf("\nApplying Slice Manifest %s to %s cluster", sliceTemplateFileName, ApplicationConfiguration.Configuration.ClusterConfiguration.ControllerCluster.Name)
+func writeSliceManifests(slices []Slice, projectNamespace string) error {
+	for _, slice := range slices {
+		manifest, _ := renderSliceManifest(slice, projectNamespace)
+		if err := util.DumpFile(manifest, kubesliceDirectory+"/"+sliceFileName(slice.Name)); err != nil {
+			return err
+		}
+		util.Printf("%s Generated %s", util.Tick, sliceFileName(slice.Name))
+	}
+
+	util.Printf("Generated Slice Configuration")
+	return nil
+}
 
-	ApplyKubectlManifest(kubesliceDirectory+"/"+sliceTemplateFileName, "kubeslice-demo", &ApplicationConfiguration.Configuration.ClusterConfiguration.ControllerCluster)
+// ApplySliceConfiguration applies the generated SliceConfig of slices to the
+// controller cluster. Slices whose definition did not change since they were
+// last applied are left as they are.
+func ApplySliceConfiguration(ctx context.Context, ApplicationConfiguration *ConfigurationSpecs, slices []Slice) error {
+	if err := verifyNodeIPsInClusters(ctx, ApplicationConfiguration); err != nil {
+		return err
+	}
+	cc := &ApplicationConfiguration.Configuration.ClusterConfiguration.ControllerCluster
+	projectNamespace := "kubeslice-" + ApplicationConfiguration.Configuration.KubeSliceConfiguration.ProjectName
+	for _, slice := range slices {
+		_, hash := renderSliceManifest(slice, projectNamespace)
+		appliedHash := getSliceDefinitionHash(ctx, slice.Name, projectNamespace, cc)
+		if appliedHash == hash {
+			util.Printf("%s Slice %s is up to date", util.Tick, slice.Name)
+			continue
+		}
+		util.Printf("\nApplying Slice Manifest %s to %s cluster", sliceFileName(slice.Name), cc.Name)
+		if err := ApplyKubectlManifest(ctx, kubesliceDirectory+"/"+sliceFileName(slice.Name), projectNamespace, cc); err != nil {
+			return err
+		}
+		if appliedHash == "" {
+			util.Printf("%s Created slice %s", util.Tick, slice.Name)
+		} else {
+			util.Printf("%s Updated slice %s", util.Tick, slice.Name)
+		}
+	}
 
 	util.Printf("\nSuccessfully Applied Slice Configuration.")
+	return nil
+}
+
+// getSliceDefinitionHash returns the definition hash of a SliceConfig, or ""
+// if it does not exist or was not created by kubeslice-cli.
+func getSliceDefinitionHash(ctx context.Context, sliceName, namespace string, cc *Cluster) string {
+	client, err := NewKubeClient(cc)
+	if err != nil {
+		return ""
+	}
+	obj, err := client.Get(ctx, SliceConfigObject, namespace, sliceName)
+	if err != nil {
+		return ""
+	}
+	return obj.GetAnnotations()[sliceHashAnnotation]
 }
 
-func verifyNodeIPsInClusters(ApplicationConfiguration *ConfigurationSpecs) {
-	var outB, errB bytes.Buffer
-	cc := ApplicationConfiguration.Configuration.ClusterConfiguration.ControllerCluster
-	wc := ApplicationConfiguration.Configuration.ClusterConfiguration.WorkerClusters
+// verifyNodeIPsInClusters waits for the controller to populate the node IPs
+// of every worker in its Cluster object.
+func verifyNodeIPsInClusters(ctx context.Context, ApplicationConfiguration *ConfigurationSpecs) error {
+	cc := &ApplicationConfiguration.Configuration.ClusterConfiguration.ControllerCluster
 	projectNamespace := "kubeslice-" + ApplicationConfiguration.Configuration.KubeSliceConfiguration.ProjectName
-	for _, cluster := range wc {
-		util.Printf("%s Waiting for NodeIPs to be populated in %s...", util.Wait, cluster.Name)
-		var nodeIPs string
-		i := 1 // retry for 50 seconds
-		for nodeIPs == "" && i < 11 {
-			util.RunCommandCustomIO("kubectl", &outB, &errB, true, "--context="+cc.ContextName, "--kubeconfig="+cc.KubeConfigPath, "get", ClusterObject, cluster.Name, "-n", projectNamespace, "-o", "jsonpath='{.status.nodeIPs}'")
-			nodeIPs = outB.String()
-			if nodeIPs == "" {
-				time.Sleep(5 * time.Second)
-				util.Printf("%s Waiting for NodeIPs to be populated in %s... %d seconds elapsed", util.Wait, cluster.Name, i*5)
-				i++
-			} else {
-				util.Printf("%s NodeIPs populated in %s", util.Tick, cluster.Name)
+	for _, cluster := range ApplicationConfiguration.Configuration.ClusterConfiguration.WorkerClusters {
+		what := fmt.Sprintf("NodeIPs to be populated in %s", cluster.Name)
+		err := util.Poll(ctx, nil, what, WaitInterval, SliceTimeout, func(ctx context.Context) (bool, error) {
+			var object struct {
+				Status struct {
+					NodeIPs []string `json:"nodeIPs"`
+				} `json:"status"`
 			}
+			if ok, err := getObject(ctx, cc, ClusterObject, projectNamespace, cluster.Name, &object); !ok {
+				return false, err
+			}
+			return len(object.Status.NodeIPs) > 0, nil
+		})
+		if err != nil {
+			return err
+		}
+		if !util.DryRun {
+			util.Printf("%s NodeIPs populated in %s", util.Tick, cluster.Name)
 		}
 	}
-
+	return nil
 }
 
-func GetSliceConfig(sliceConfigName string, namespace string, controllerCluster *Cluster) {
+func GetSliceConfig(ctx context.Context, sliceConfigName string, namespace string, controllerCluster *Cluster) error {
 	util.Printf("\nFetching KubeSlice sliceConfig...")
-	GetKubectlResources(SliceConfigObject, sliceConfigName, namespace, controllerCluster, "")
-	time.Sleep(200 * time.Millisecond)
+	if err := GetKubectlResources(ctx, SliceConfigObject, sliceConfigName, namespace, controllerCluster, ""); err != nil {
+		return err
+	}
+	return nil
 }
 
-func DeleteSliceConfig(sliceConfigName string, namespace string, controllerCluster *Cluster) {
+func DeleteSliceConfig(ctx context.Context, sliceConfigName string, namespace string, controllerCluster *Cluster) error {
 	util.Printf("\nDeleting KubeSlice SliceConfig...")
-	DeleteKubectlResources(SliceConfigObject, sliceConfigName, namespace, controllerCluster)
-	time.Sleep(200 * time.Millisecond)
+	if err := DeleteKubectlResources(ctx, SliceConfigObject, sliceConfigName, namespace, controllerCluster); err != nil {
+		return err
+	}
+	return nil
 }
 
-func EditSliceConfig(sliceConfigName string, namespace string, controllerCluster *Cluster) {
+func EditSliceConfig(ctx context.Context, sliceConfigName string, namespace string, controllerCluster *Cluster) error {
 	util.Printf("\nEditing KubeSlice SliceConfig...")
-	EditKubectlResources(SliceConfigObject, sliceConfigName, namespace, controllerCluster)
-	time.Sleep(200 * time.Millisecond)
+	if err := EditKubectlResources(ctx, SliceConfigObject, sliceConfigName, namespace, controllerCluster); err != nil {
+		return err
+	}
+	return nil
 }
 
-func DescribeSliceConfig(sliceConfigName string, namespace string, controllerCluster *Cluster) {
+func DescribeSliceConfig(ctx context.Context, sliceConfigName string, namespace string, controllerCluster *Cluster) error {
 	util.Printf("\nDescribing KubeSlice SliceConfig...")
-	DescribeKubectlResources(SliceConfigObject, sliceConfigName, namespace, controllerCluster)
-	time.Sleep(200 * time.Millisecond)
+	if err := DescribeKubectlResources(ctx, SliceConfigObject, sliceConfigName, namespace, controllerCluster); err != nil {
+		return err
+	}
+	return nil
 }
 
-func CreateSliceConfig(namespace string, controllerCluster *Cluster, filename string) {
-	ApplyFile(filename, namespace, controllerCluster)
+func CreateSliceConfig(ctx context.Context, namespace string, controllerCluster *Cluster, filename string) error {
+	if err := ApplyFile(ctx, filename, namespace, controllerCluster); err != nil {
+		return err
+	}
 	util.Printf("\nSuccessfully Applied Slice Configuration.")
+	return nil
 }
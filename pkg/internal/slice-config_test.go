@@ -0,0 +1,68 @@
+package internal
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/kubeslice/kubeslice-cli/util"
+)
+
+func TestSliceQosPriority(t *testing.T) {
+	t.Parallel()
+
+	zero, three := 0, 3
+	tests := []struct {
+		name     string
+		priority *int
+		want     string
+	}{
+		{name: "unset", want: "priority: 1\n"},
+		{name: "zero", priority: &zero, want: "priority: 0\n"},
+		{name: "set", priority: &three, want: "priority: 3\n"},
+	}
+	for _, tc := range tests {
+		tc := tc // Capture range variable for parallel execution
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			slice := withSliceDefaults(Slice{Name: "red", QosProfile: QosProfile{Priority: tc.priority}})
+			manifest, _ := renderSliceManifest(slice, "kubeslice-demo")
+			if !strings.Contains(manifest, tc.want) {
+				t.Errorf("renderSliceManifest() = %s, want it to contain %q", manifest, tc.want)
+			}
+		})
+	}
+}
+
+// not parallel, as it sets the output and ExecutablePaths
+func TestPrintNamespaceIsolationSteps(t *testing.T) {
+	var b bytes.Buffer
+	util.SetOutput(&b)
+	util.ExecutablePaths = map[string]string{"kubectl": "kubectl"}
+	t.Cleanup(func() {
+		util.SetOutput(os.Stdout)
+		util.ExecutablePaths = nil
+	})
+
+	tests := []struct {
+		name   string
+		slices []Slice
+		want   string
+	}{
+		{name: "demo slice", want: "apply -f kubeslice/slice-demo.yaml"},
+		{name: "declared slices", slices: []Slice{{Name: "red"}, {Name: "blue"}}, want: "apply -f kubeslice/slice-demo.yaml"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			b.Reset()
+			config := &ConfigurationSpecs{}
+			config.Configuration.ClusterConfiguration.WorkerClusters = []Cluster{{Name: "worker-1"}, {Name: "worker-2"}}
+			config.Configuration.KubeSliceConfiguration.Slices = tc.slices
+			printNamespaceIsolationSteps(config)
+			if !strings.Contains(b.String(), tc.want) {
+				t.Errorf("printNamespaceIsolationSteps() printed %s, want the demo slice file %q", b.String(), tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kubeslice/kubeslice-cli/util"
+)
+
+// UpgradeKubeSliceController upgrades the controller to the chart version of
+// the topology and waits for its pods. With resetValues the values are
+// generated from the topology as install does, otherwise the values the
+// release was deployed with are kept.
+func UpgradeKubeSliceController(ctx context.Context, ApplicationConfiguration *ConfigurationSpecs, resetValues bool) error {
+	cc := ApplicationConfiguration.Configuration.ClusterConfiguration
+	hc := ApplicationConfiguration.Configuration.HelmChartConfiguration
+	util.Printf("\nUpgrading KubeSlice Controller to %s...", hc.ControllerChart.Version)
+	valuesFile := ""
+	if resetValues {
+		if err := generateControllerValuesFile(cc.ControllerCluster, hc); err != nil {
+			return err
+		}
+		util.Printf("%s Generated Helm Values file for Controller Upgrade %s", util.Tick, controllerValuesFileName)
+		valuesFile = controllerValuesFileName
+	}
+	if err := upgradeRelease(ctx, nil, cc.ControllerCluster, KUBESLICE_CONTROLLER_NAMESPACE, KUBESLICE_CONTROLLER_NAMESPACE, hc.RepoAlias, hc.ControllerChart, valuesFile); err != nil {
+		return err
+	}
+	util.Printf("%s Waiting for KubeSlice Controller Pods to be Healthy...", util.Wait)
+	if err := podVerification(ctx, nil, "Waiting for KubeSlice Controller Pods to be Healthy", cc.ControllerCluster, KUBESLICE_CONTROLLER_NAMESPACE); err != nil {
+		return err
+	}
+	util.Printf("%s Successfully upgraded KubeSlice Controller.", util.Tick)
+	return nil
+}
+
+// UpgradeKubeSliceWorker upgrades the worker of cluster to the chart version
+// of the topology and waits for its pods, see UpgradeKubeSliceController.
+func UpgradeKubeSliceWorker(ctx context.Context, out *util.Output, ApplicationConfiguration *ConfigurationSpecs, cluster Cluster, resetValues bool) error {
+	config := ApplicationConfiguration.Configuration
+	hc := config.HelmChartConfiguration
+	out.Printf("\nUpgrading KubeSlice Worker %s to %s...", cluster.Name, hc.WorkerChart.Version)
+	valuesFile := ""
+	if resetValues {
+		valuesFile = "helm-values-" + cluster.Name + ".yaml"
+		insecureMetrics := config.ClusterConfiguration.ClusterType == Kind_Component
+		if err := generateWorkerValuesFile(ctx, cluster, valuesFile, config, insecureMetrics); err != nil {
+			return err
+		}
+		out.Printf("%s Generated Helm Values file for Worker Upgrade %s", util.Tick, valuesFile)
+	}
+	if err := upgradeRelease(ctx, out, cluster, "kubeslice-worker", "kubeslice-system", hc.RepoAlias, hc.WorkerChart, valuesFile); err != nil {
+		return err
+	}
+	out.Printf("%s Waiting for KubeSlice Worker Pods to be Healthy...", util.Wait)
+	if err := podVerification(ctx, out, "Waiting for KubeSlice Worker Pods to be Healthy", cluster, "kubeslice-system"); err != nil {
+		return err
+	}
+	out.Printf("%s Successfully upgraded KubeSlice Worker %s.", util.Tick, cluster.Name)
+	return nil
+}
+
+// RollbackKubeSliceWorker rolls the worker of cluster back to revision and
+// waits for its pods.
+func RollbackKubeSliceWorker(ctx context.Context, out *util.Output, cluster Cluster, revision string) error {
+	out.Printf("%s Rolling KubeSlice Worker %s back to revision %s...", util.Wait, cluster.Name, revision)
+	client, err := NewHelmClient(out, &cluster)
+	if err != nil {
+		return err
+	}
+	if err := client.Rollback(ctx, "kubeslice-worker", "kubeslice-system", revision); err != nil {
+		return err
+	}
+	if err := podVerification(ctx, out, "Waiting for KubeSlice Worker Pods to be Healthy", cluster, "kubeslice-system"); err != nil {
+		return err
+	}
+	out.Printf("%s Rolled KubeSlice Worker %s back to revision %s", util.Tick, cluster.Name, revision)
+	return nil
+}
+
+// upgradeRelease upgrades a release to chart, keeping its values unless a
+// values file is given.
+func upgradeRelease(ctx context.Context, out *util.Output, cluster Cluster, release, namespace, repoAlias string, chart HelmChart, valuesFile string) error {
+	client, err := NewHelmClient(out, &cluster)
+	if err != nil {
+		return err
+	}
+	spec := HelmReleaseSpec{
+		Name:      release,
+		Namespace: namespace,
+		Chart:     fmt.Sprintf("%s/%s", repoAlias, chart.ChartName),
+		Version:   chart.Version,
+	}
+	if valuesFile != "" {
+		spec.ValuesFiles = []string{kubesliceDirectory + "/" + valuesFile}
+	} else {
+		spec.ReuseValues = true
+	}
+	if err := client.Upgrade(ctx, spec); err != nil {
+		return err
+	}
+	out.Printf("%s Successfully upgraded helm chart %s/%s on %s", util.Tick, repoAlias, chart.ChartName, cluster.Name)
+	return nil
+}
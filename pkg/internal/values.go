@@ -8,12 +8,35 @@ import (
 	"gopkg.in/yaml.v2"
 )
 
+// mergeMaps deep-merges src into dest, with values from src taking precedence.
+// Lists are replaced, as helm replaces them.
 func mergeMaps(dest, src map[interface{}]interface{}) map[interface{}]interface{} {
+	return deepMerge(dest, src, false)
+}
+
+// mergeLayers deep-merges the topology layer src into dest like mergeMaps,
+// except that lists of mappings which all have a name, such as workers, are
+// merged item by item on that name instead of being replaced.
+func mergeLayers(dest, src map[interface{}]interface{}) map[interface{}]interface{} {
+	return deepMerge(dest, src, true)
+}
+
+func deepMerge(dest, src map[interface{}]interface{}, byName bool) map[interface{}]interface{} {
 	for k, v := range src {
 		if d, ok := dest[k]; ok {
 			switch d.(type) {
 			case map[interface{}]interface{}:
-				dest[k] = mergeMaps(d.(map[interface{}]interface{}), v.(map[interface{}]interface{}))
+				if vm, ok := v.(map[interface{}]interface{}); ok {
+					dest[k] = deepMerge(d.(map[interface{}]interface{}), vm, byName)
+				} else {
+					dest[k] = v
+				}
+			case []interface{}:
+				if vl, ok := v.([]interface{}); ok && byName && namedList(d.([]interface{})) && namedList(vl) {
+					dest[k] = mergeNamedLists(d.([]interface{}), vl)
+				} else {
+					dest[k] = v
+				}
 			default:
 				dest[k] = v
 			}
@@ -24,14 +47,81 @@ func mergeMaps(dest, src map[interface{}]interface{}) map[interface{}]interface{
 	return dest
 }
 
-func generateValuesFile(filePath string, hc *HelmChart, defaults string) error {
+func namedList(list []interface{}) bool {
+	for _, item := range list {
+		m, ok := item.(map[interface{}]interface{})
+		if !ok {
+			return false
+		}
+		if _, ok := m["name"].(string); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func mergeNamedLists(dest, src []interface{}) []interface{} {
+	for _, item := range src {
+		srcItem := item.(map[interface{}]interface{})
+		merged := false
+		for i, destItem := range dest {
+			if destItem.(map[interface{}]interface{})["name"] == srcItem["name"] {
+				dest[i] = mergeLayers(destItem.(map[interface{}]interface{}), srcItem)
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			dest = append(dest, srcItem)
+		}
+	}
+	return dest
+}
+
+// generateValuesFile writes the values of hc deep-merged with defaults, and
+// then with each of the overrides in order, the last one taking precedence.
+func generateValuesFile(filePath string, hc *HelmChart, defaults string, overrides ...map[interface{}]interface{}) error {
+	mergedMap, err := renderValues(hc, defaults, overrides...)
+	if err != nil {
+		return err
+	}
+
+	finalData, err := yaml.Marshal(mergedMap)
+	if err != nil {
+		return fmt.Errorf("error encoding final data as YAML: %v", err)
+	}
+
+	if err := ioutil.WriteFile(filePath, finalData, 0644); err != nil {
+		return fmt.Errorf("error writing values file: %v", err)
+	}
+
+	return nil
+}
+
+// renderValues returns the values generateValuesFile writes.
+func renderValues(hc *HelmChart, defaults string, overrides ...map[interface{}]interface{}) (map[interface{}]interface{}, error) {
+	defaultsMap := make(map[interface{}]interface{})
+	if err := yaml.Unmarshal([]byte(defaults), &defaultsMap); err != nil {
+		return nil, fmt.Errorf("error parsing defaults: %v", err)
+	}
+
+	valuesMap := mergeMaps(expandValues(hc.Values), defaultsMap)
+	for _, override := range overrides {
+		valuesMap = mergeMaps(valuesMap, override)
+	}
+	return valuesMap, nil
+}
+
+// expandValues turns values keyed by dotted paths, as passed to helm --set,
+// into nested maps.
+func expandValues(values map[string]interface{}) map[interface{}]interface{} {
 	valuesMap := make(map[interface{}]interface{})
-	for k, v := range hc.Values {
+	for k, v := range values {
 		keys := strings.Split(k, ".")
 		currentMap := valuesMap
 		for i, key := range keys {
 			if i == len(keys)-1 {
-				currentMap[key] = v
+				currentMap[key] = copyValue(v)
 			} else {
 				if currentMap[key] == nil {
 					currentMap[key] = make(map[interface{}]interface{})
@@ -40,22 +130,55 @@ func generateValuesFile(filePath string, hc *HelmChart, defaults string) error {
 			}
 		}
 	}
+	return valuesMap
+}
 
-	defaultsMap := make(map[interface{}]interface{})
-	if err := yaml.Unmarshal([]byte(defaults), &defaultsMap); err != nil {
-		return fmt.Errorf("error parsing defaults: %v", err)
+// copyValue deep-copies maps and lists, so that merging into the result
+// leaves the configuration untouched.
+func copyValue(v interface{}) interface{} {
+	switch typed := v.(type) {
+	case map[interface{}]interface{}:
+		copied := make(map[interface{}]interface{}, len(typed))
+		for k, v := range typed {
+			copied[k] = copyValue(v)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(typed))
+		for i, v := range typed {
+			copied[i] = copyValue(v)
+		}
+		return copied
 	}
+	return v
+}
 
-	mergedMap := mergeMaps(valuesMap, defaultsMap)
-
-	finalData, err := yaml.Marshal(mergedMap)
+// readValuesFile reads a helm values file.
+func readValuesFile(fileName string) (map[interface{}]interface{}, error) {
+	data, err := ioutil.ReadFile(fileName)
 	if err != nil {
-		return fmt.Errorf("error encoding final data as YAML: %v", err)
+		return nil, err
 	}
-
-	if err := ioutil.WriteFile(filePath, finalData, 0644); err != nil {
-		return fmt.Errorf("error writing values file: %v", err)
+	values := make(map[interface{}]interface{})
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("error parsing values file %s: %v", fileName, err)
 	}
+	return values, nil
+}
 
-	return nil
+// ClusterValues returns the values overrides of a worker cluster: its values
+// files in order, then its helm_values.
+func ClusterValues(cluster Cluster) ([]map[interface{}]interface{}, error) {
+	overrides := make([]map[interface{}]interface{}, 0)
+	for _, fileName := range cluster.ValuesFiles {
+		values, err := readValuesFile(fileName)
+		if err != nil {
+			return nil, err
+		}
+		overrides = append(overrides, values)
+	}
+	if len(cluster.HelmValues) > 0 {
+		overrides = append(overrides, expandValues(cluster.HelmValues))
+	}
+	return overrides, nil
 }
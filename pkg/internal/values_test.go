@@ -0,0 +1,73 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func parseValues(t *testing.T, values string) map[interface{}]interface{} {
+	t.Helper()
+	parsed := make(map[interface{}]interface{})
+	if err := yaml.Unmarshal([]byte(values), &parsed); err != nil {
+		t.Fatalf("Failed to setup test: %v", err)
+	}
+	return parsed
+}
+
+func TestRenderValuesReplacesLists(t *testing.T) {
+	t.Parallel()
+
+	hc := &HelmChart{Values: map[string]interface{}{
+		"tolerations": []interface{}{
+			map[interface{}]interface{}{"name": "a", "effect": "NoSchedule"},
+			map[interface{}]interface{}{"name": "b", "effect": "NoSchedule"},
+		},
+	}}
+	override := parseValues(t, "tolerations:\n- name: a\n  effect: NoExecute\n")
+	got, err := renderValues(hc, "", override)
+	if err != nil {
+		t.Fatalf("renderValues() returned unexpected error %v", err)
+	}
+	if want := parseValues(t, "tolerations:\n- name: a\n  effect: NoExecute\n"); !reflect.DeepEqual(got, want) {
+		t.Errorf("renderValues() = %v, want the list of the override %v", got, want)
+	}
+}
+
+func TestMergeLayers(t *testing.T) {
+	t.Parallel()
+
+	base := parseValues(t, `
+workers:
+- name: w1
+  context_name: a
+  helm_values:
+    args: [x, y]
+- name: w2
+  context_name: b
+`)
+	overlay := parseValues(t, `
+workers:
+- name: w1
+  context_name: c
+  helm_values:
+    args: [z]
+- name: w3
+  context_name: d
+`)
+	want := parseValues(t, `
+workers:
+- name: w1
+  context_name: c
+  helm_values:
+    args: [z]
+- name: w2
+  context_name: b
+- name: w3
+  context_name: d
+`)
+	if got := mergeLayers(base, overlay); !reflect.DeepEqual(got, want) {
+		t.Errorf("mergeLayers() = %v, want %v", got, want)
+	}
+}
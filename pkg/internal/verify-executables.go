@@ -1,19 +1,18 @@
 package internal
 
 import (
+	"context"
 	"fmt"
 	"os"
-	"os/exec"
 	"runtime"
+	"sort"
 	"strings"
-	"time"
 
 	"github.com/kubeslice/kubeslice-cli/util"
 )
 
-func VerifyExecutables(ApplicationConfiguration *ConfigurationSpecs) {
+func VerifyExecutables(ctx context.Context, ApplicationConfiguration *ConfigurationSpecs) error {
 	util.Printf("Verifying Executables...")
-	time.Sleep(200 * time.Millisecond)
 	if ApplicationConfiguration.Configuration.ClusterConfiguration.Profile != "" || ApplicationConfiguration.Configuration.ClusterConfiguration.ClusterType == "kind" {
 		util.ExecutablePaths = map[string]string{
 			"kind":    "kind",
@@ -27,29 +26,43 @@ func VerifyExecutables(ApplicationConfiguration *ConfigurationSpecs) {
 			"helm":    "helm",
 		}
 	}
+	if util.DryRun {
+		util.Printf("%s Skipping executable verification in dry-run mode\n", util.Warn)
+		return nil
+	}
+	executables := make([]string, 0, len(util.ExecutablePaths))
 	for key := range util.ExecutablePaths {
-		time.Sleep(200 * time.Millisecond)
-		verificationResult(verifyBinary(key), key)
+		executables = append(executables, key)
+	}
+	sort.Strings(executables)
+	for _, key := range executables {
+		// the Helm SDK installs the charts unless the helm binary is asked for
+		if key == "helm" && HelmBackend != HelmBackendBinary {
+			continue
+		}
+		if err := verificationResult(verifyBinary(ctx, key), key); err != nil {
+			return err
+		}
 	}
 
-	time.Sleep(200 * time.Millisecond)
 	util.Printf("All required executables were found\n")
+	return nil
 }
 
-func verifyBinary(name string) int {
-	return _verifyBinary(name, strings.ToUpper(name)+"_PATH", util.ExecutableVerifyCommands[name])
+func verifyBinary(ctx context.Context, name string) int {
+	return _verifyBinary(ctx, name, strings.ToUpper(name)+"_PATH", util.ExecutableVerifyCommands[name])
 }
 
-func _verifyBinary(name, environmentVariable string, executable []string) int {
+func _verifyBinary(ctx context.Context, name, environmentVariable string, executable []string) int {
 	cli := name
 	if os.Getenv(environmentVariable) != "" {
 		cli = strings.Trim(os.Getenv(environmentVariable), "\"")
 	}
-	path, err := exec.LookPath(cli)
+	path, err := util.LookPath(cli)
 	if err != nil || path == "" {
 		return 1
 	}
-	if err = exec.Command(path, executable...).Run(); err != nil {
+	if err = util.RunExecutable(ctx, path, executable...); err != nil {
 		return 2
 	}
 	util.ExecutablePaths[name] = path
@@ -70,14 +83,14 @@ func executableDownloadMessage(executable string) string {
 	return ""
 }
 
-func verificationResult(num int, cli string) {
+func verificationResult(num int, cli string) error {
 	switch num {
 	case 0:
 		util.Printf("%s %s found", util.Tick, cli)
 	case 1:
-		util.Printf("%s %s not found on path", util.Cross, cli)
-		util.Fatalf(executableDownloadMessage(cli))
+		return util.NotFoundErrorf("%s not found on path\n%s", cli, executableDownloadMessage(cli))
 	case 2:
-		util.Fatalf("%s %s is not executable", util.Cross, cli)
+		return fmt.Errorf("%s is not executable", cli)
 	}
+	return nil
 }
@@ -0,0 +1,223 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/kubeslice/kubeslice-cli/util"
+)
+
+const (
+	WorkerSliceConfigObject  = "workersliceconfigs.worker.kubeslice.io"
+	WorkerSliceGatewayObject = "workerslicegateways.worker.kubeslice.io"
+	SliceObject              = "slices.networking.kubeslice.io"
+	ServiceImportObject      = "serviceimports.networking.kubeslice.io"
+
+	// originalSliceLabel is set by the controller on the objects it creates for a slice
+	originalSliceLabel = "original-slice-name"
+)
+
+var (
+	// WaitInterval is how often the waits check the clusters.
+	WaitInterval = 5 * time.Second
+	// SliceTimeout is how long to wait for a slice to be set up.
+	SliceTimeout = 2 * time.Minute
+	// RolloutTimeout is how long to wait for a Deployment to roll out.
+	RolloutTimeout = 5 * time.Minute
+)
+
+// getObject reads the object name of resource into v, and returns false if
+// it does not exist yet, or its resource type does not. Other errors, such as
+// a forbidden get, are returned as they will not go away by waiting.
+func getObject(ctx context.Context, cluster *Cluster, resource, namespace, name string, v interface{}) (bool, error) {
+	client, err := NewKubeClient(cluster)
+	if err != nil {
+		return false, err
+	}
+	obj, err := client.Get(ctx, resource, namespace, name)
+	if err != nil {
+		return false, notYet(err)
+	}
+	return true, fromJSON(obj, v)
+}
+
+// notYet returns nil for a NotFound error, which waiting may solve, and err
+// otherwise.
+func notYet(err error) error {
+	if util.ExitCode(err) == util.ExitNotFound {
+		return nil
+	}
+	return err
+}
+
+// listObjectsInto reads the objects of resource matching selector into the
+// slice pointed to by v, and returns false if there are none.
+func listObjectsInto(ctx context.Context, cluster *Cluster, resource, namespace, selector string, v interface{}) (bool, error) {
+	client, err := NewKubeClient(cluster)
+	if err != nil {
+		return false, err
+	}
+	items, err := client.List(ctx, resource, namespace, selector)
+	if err != nil {
+		return false, notYet(err)
+	}
+	if len(items) == 0 {
+		return false, nil
+	}
+	data, err := json.Marshal(items)
+	if err != nil {
+		return false, err
+	}
+	return true, json.Unmarshal(data, v)
+}
+
+// waitForNamespace waits for the controller to create namespace on cluster.
+func waitForNamespace(ctx context.Context, cluster *Cluster, namespace string) error {
+	what := fmt.Sprintf("namespace %s on %s", namespace, cluster.Name)
+	return util.Poll(ctx, nil, what, WaitInterval, SliceTimeout, func(ctx context.Context) (bool, error) {
+		var ns json.RawMessage
+		return getObject(ctx, cluster, "namespace", "", namespace, &ns)
+	})
+}
+
+// WaitForSlicePropagation waits for the controller to set up slices for their
+// workers, and for the workers to pick them up.
+func WaitForSlicePropagation(ctx context.Context, ApplicationConfiguration *ConfigurationSpecs, slices []Slice) error {
+	cc := &ApplicationConfiguration.Configuration.ClusterConfiguration.ControllerCluster
+	projectNamespace := "kubeslice-" + ApplicationConfiguration.Configuration.KubeSliceConfiguration.ProjectName
+	for _, slice := range slices {
+		if err := waitForWorkerSliceConfigs(ctx, cc, projectNamespace, slice); err != nil {
+			return err
+		}
+		if err := waitForWorkerSliceGateways(ctx, cc, projectNamespace, slice); err != nil {
+			return err
+		}
+		workers := make([]*Cluster, 0, len(slice.Clusters))
+		for _, worker := range getWorkerClusters(&ApplicationConfiguration.Configuration.ClusterConfiguration) {
+			if containsString(slice.Clusters, worker.Name) {
+				workers = append(workers, worker)
+			}
+		}
+		err := forEachCluster(ctx, workers, func(ctx context.Context, out *util.Output, worker *Cluster) error {
+			return waitForSlice(ctx, out, slice.Name, worker)
+		})
+		if err := clusterErrors("Waiting for slice "+slice.Name, err); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// waitForWorkerSliceConfigs waits for the controller to create the
+// WorkerSliceConfig of every worker of slice, and for the workers to report a
+// healthy slice if they report its health.
+func waitForWorkerSliceConfigs(ctx context.Context, controller *Cluster, projectNamespace string, slice Slice) error {
+	what := fmt.Sprintf("the WorkerSliceConfigs of slice %s", slice.Name)
+	err := util.Poll(ctx, nil, what, WaitInterval, SliceTimeout, func(ctx context.Context) (bool, error) {
+		var items []struct {
+			Status struct {
+				SliceHealth *struct {
+					SliceHealthStatus string `json:"sliceHealthStatus"`
+				} `json:"sliceHealth"`
+			} `json:"status"`
+		}
+		if ok, err := listObjectsInto(ctx, controller, WorkerSliceConfigObject, projectNamespace, originalSliceLabel+"="+slice.Name, &items); !ok {
+			return false, err
+		}
+		if len(items) < len(slice.Clusters) {
+			return false, nil
+		}
+		for _, item := range items {
+			if health := item.Status.SliceHealth; health != nil && health.SliceHealthStatus != "Normal" {
+				return false, nil
+			}
+		}
+		return true, nil
+	})
+	if err != nil || util.DryRun {
+		return err
+	}
+	util.Printf("%s WorkerSliceConfigs of slice %s created for %d workers", util.Tick, slice.Name, len(slice.Clusters))
+	return nil
+}
+
+// waitForWorkerSliceGateways waits for the controller to create the gateways
+// connecting every pair of workers of slice, a server and a client each.
+func waitForWorkerSliceGateways(ctx context.Context, controller *Cluster, projectNamespace string, slice Slice) error {
+	gateways := len(slice.Clusters) * (len(slice.Clusters) - 1)
+	if gateways == 0 {
+		return nil
+	}
+	what := fmt.Sprintf("the WorkerSliceGateways of slice %s", slice.Name)
+	err := util.Poll(ctx, nil, what, WaitInterval, SliceTimeout, func(ctx context.Context) (bool, error) {
+		var items []json.RawMessage
+		if ok, err := listObjectsInto(ctx, controller, WorkerSliceGatewayObject, projectNamespace, originalSliceLabel+"="+slice.Name, &items); !ok {
+			return false, err
+		}
+		return len(items) >= gateways, nil
+	})
+	if err != nil || util.DryRun {
+		return err
+	}
+	util.Printf("%s %d WorkerSliceGateways of slice %s created", util.Tick, gateways, slice.Name)
+	return nil
+}
+
+// waitForSlice waits for the worker operator to set up the slice on cluster,
+// printing its progress to out.
+func waitForSlice(ctx context.Context, out *util.Output, sliceName string, cluster *Cluster) error {
+	what := fmt.Sprintf("slice %s on %s", sliceName, cluster.Name)
+	err := util.Poll(ctx, out, what, WaitInterval, SliceTimeout, func(ctx context.Context) (bool, error) {
+		var slice struct {
+			Status struct {
+				SliceConfig *struct {
+					SliceSubnet string `json:"sliceSubnet"`
+				} `json:"sliceConfig"`
+			} `json:"status"`
+		}
+		if ok, err := getObject(ctx, cluster, SliceObject, "kubeslice-system", sliceName, &slice); !ok {
+			return false, err
+		}
+		return slice.Status.SliceConfig != nil && slice.Status.SliceConfig.SliceSubnet != "", nil
+	})
+	if err != nil || util.DryRun {
+		return err
+	}
+	out.Printf("%s Slice %s is ready on %s", util.Tick, sliceName, cluster.Name)
+	return nil
+}
+
+// WaitForServiceImport waits for the service exported as name from namespace
+// of worker to be imported on the other workers of slice.
+func WaitForServiceImport(ctx context.Context, ApplicationConfiguration *ConfigurationSpecs, slice Slice, name, namespace, worker string) error {
+	importers := make([]*Cluster, 0)
+	for _, cluster := range getWorkerClusters(&ApplicationConfiguration.Configuration.ClusterConfiguration) {
+		if cluster.Name != worker && containsString(slice.Clusters, cluster.Name) {
+			importers = append(importers, cluster)
+		}
+	}
+	err := forEachCluster(ctx, importers, func(ctx context.Context, out *util.Output, cluster *Cluster) error {
+		what := fmt.Sprintf("ServiceImport %s/%s on %s", namespace, name, cluster.Name)
+		return util.Poll(ctx, out, what, WaitInterval, SliceTimeout, func(ctx context.Context) (bool, error) {
+			var serviceImport json.RawMessage
+			return getObject(ctx, cluster, ServiceImportObject, namespace, name, &serviceImport)
+		})
+	})
+	return clusterErrors("Waiting for ServiceImport "+namespace+"/"+name, err)
+}
+
+// waitForRollout waits for every replica of deployment to be updated and
+// available, as kubectl rollout status does.
+func waitForRollout(ctx context.Context, out *util.Output, cluster *Cluster, namespace, deployment string) error {
+	what := fmt.Sprintf("deployment %s/%s to roll out on %s", namespace, deployment, cluster.Name)
+	return util.Poll(ctx, out, what, WaitInterval, RolloutTimeout, func(ctx context.Context) (bool, error) {
+		var d workloadObject
+		if ok, err := getObject(ctx, cluster, "deployment", namespace, deployment, &d); !ok {
+			return false, err
+		}
+		status, _ := d.readiness()
+		return status == PodVerificationStatusSuccess, nil
+	})
+}
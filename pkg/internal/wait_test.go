@@ -0,0 +1,56 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kubeslice/kubeslice-cli/util"
+)
+
+// not parallel, as it sets the executor, KubeBackend, ExecutablePaths and the
+// wait options
+func TestWaitErrors(t *testing.T) {
+	backend, interval, timeout := KubeBackend, WaitInterval, SliceTimeout
+	KubeBackend, util.ExecutablePaths = KubeBackendKubectl, map[string]string{"kubectl": "kubectl"}
+	WaitInterval, SliceTimeout = time.Millisecond, 20*time.Millisecond
+	t.Cleanup(func() {
+		KubeBackend, WaitInterval, SliceTimeout, util.ExecutablePaths = backend, interval, timeout, nil
+	})
+	specs := &ConfigurationSpecs{}
+	specs.Configuration.KubeSliceConfiguration.ProjectName = "demo"
+	specs.Configuration.ClusterConfiguration.ControllerCluster = Cluster{Name: "ctrl", ContextName: "ctrl"}
+	specs.Configuration.ClusterConfiguration.WorkerClusters = []Cluster{{Name: "w1", ContextName: "w1"}}
+
+	tests := []struct {
+		name         string
+		response     util.FakeResponse
+		wantExitCode int
+		wantPolls    bool
+	}{
+		{name: "node IPs populated", response: util.FakeResponse{Stdout: `{"status":{"nodeIPs":["10.0.0.2"]}}`}, wantExitCode: 0},
+		{name: "node IPs never populated", response: util.FakeResponse{Stdout: `{"status":{}}`}, wantExitCode: util.ExitTimeout, wantPolls: true},
+		{name: "cluster not found", response: util.FakeResponse{Stderr: "Error from server (NotFound): clusters.controller.kubeslice.io \"w1\" not found", Err: errors.New("exit status 1")},
+			wantExitCode: util.ExitTimeout, wantPolls: true},
+		{name: "forbidden", response: util.FakeResponse{Stderr: "Error from server (Forbidden): clusters.controller.kubeslice.io \"w1\" is forbidden", Err: errors.New("exit status 1")},
+			wantExitCode: util.ExitCommand},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fake := &util.FakeExecutor{}
+			fake.Respond(" get clusters.controller.kubeslice.io w1 ", tc.response)
+			defer util.SetExecutor(util.SetExecutor(fake))
+
+			err := verifyNodeIPsInClusters(context.Background(), specs)
+			if tc.wantExitCode == 0 && err != nil {
+				t.Errorf("verifyNodeIPsInClusters() returned unexpected error %v", err)
+			} else if got := util.ExitCode(err); tc.wantExitCode != 0 && got != tc.wantExitCode {
+				t.Errorf("verifyNodeIPsInClusters() = %v, exit code %d, want %d", err, got, tc.wantExitCode)
+			}
+			if polls := len(fake.Commands()) > 1; polls != tc.wantPolls {
+				t.Errorf("verifyNodeIPsInClusters() ran %q, want polling %t", fake.Commands(), tc.wantPolls)
+			}
+		})
+	}
+}
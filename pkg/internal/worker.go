@@ -1,14 +1,12 @@
 package internal
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
-	"log"
-	"strings"
 	"time"
 
 	"github.com/kubeslice/kubeslice-cli/util"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
 
 const workerValuesTemplate = `
@@ -26,53 +24,62 @@ cluster:
 
 `
 
-func InstallKubeSliceWorker(ApplicationConfiguration *ConfigurationSpecs) {
+func InstallKubeSliceWorker(ctx context.Context, ApplicationConfiguration *ConfigurationSpecs) error {
 	util.Printf("\nInstalling KubeSlice Worker...")
 
-	cc := ApplicationConfiguration.Configuration.ClusterConfiguration
-	for _, cluster := range cc.WorkerClusters {
+	workers := getWorkerClusters(&ApplicationConfiguration.Configuration.ClusterConfiguration)
+	err := forEachCluster(ctx, workers, func(ctx context.Context, out *util.Output, cluster *Cluster) error {
 		filename := "helm-values-" + cluster.Name + ".yaml"
 		insecureMetrics := ApplicationConfiguration.Configuration.ClusterConfiguration.ClusterType == Kind_Component
-		generateWorkerValuesFile(cluster,
+		err := generateWorkerValuesFile(ctx, *cluster,
 			filename,
 			ApplicationConfiguration.Configuration,
 			insecureMetrics,
 		)
+		if err != nil {
+			return err
+		}
 
-		util.Printf("%s Generated Helm Values file for Worker Installation %s", util.Tick, filename)
-		time.Sleep(200 * time.Millisecond)
+		out.Printf("%s Generated Helm Values file for Worker Installation %s", util.Tick, filename)
 
-		installWorker(cluster, filename, ApplicationConfiguration.Configuration.HelmChartConfiguration)
+		return installWorker(ctx, out, *cluster, filename, ApplicationConfiguration.Configuration.HelmChartConfiguration)
+	})
+	if err := clusterErrors("Installing KubeSlice Worker", err); err != nil {
+		return err
 	}
 
 	util.Printf("%s Successfully Installed Kubeslice Worker", util.Tick)
-	time.Sleep(200 * time.Millisecond)
+	return nil
 }
 
-func UninstallKubeSliceWorker(ApplicationConfiguration *ConfigurationSpecs, workersToUninstall map[string]string) {
+// UninstallKubeSliceWorker uninstalls the worker from the workers named in
+// workersToUninstall, or from all of them for "*". A worker failing to
+// uninstall does not stop the others.
+func UninstallKubeSliceWorker(ctx context.Context, ApplicationConfiguration *ConfigurationSpecs, workersToUninstall map[string]string) error {
 	util.Printf("\nUninstalling KubeSlice Worker...")
 
 	_, uninstallAllWorker := workersToUninstall["*"]
 
-	cc := ApplicationConfiguration.Configuration.ClusterConfiguration
-	for _, cluster := range cc.WorkerClusters {
-		_, found := workersToUninstall[cluster.Name]
-		if found || uninstallAllWorker {
-			uninstallKubeSliceWorkerHelm(cluster)
-			time.Sleep(200 * time.Millisecond)
+	workers := make([]*Cluster, 0)
+	for _, cluster := range getWorkerClusters(&ApplicationConfiguration.Configuration.ClusterConfiguration) {
+		if _, found := workersToUninstall[cluster.Name]; found || uninstallAllWorker {
+			workers = append(workers, cluster)
 		}
 	}
-
-	// util.Printf("%s Successfully Installed Kubeslice Worker", util.Tick)
-	time.Sleep(200 * time.Millisecond)
+	err := forEachCluster(ctx, workers, func(ctx context.Context, out *util.Output, cluster *Cluster) error {
+		return uninstallKubeSliceWorkerHelm(ctx, out, *cluster)
+	})
+	return clusterErrors("Uninstalling KubeSlice Worker", err)
 }
 
 // Retry tries to execute the funtion, If failed reattempts till backoffLimit
-func Retry(backoffLimit int, sleep time.Duration, f func() error) (err error) {
+func Retry(ctx context.Context, backoffLimit int, sleep time.Duration, f func() error) (err error) {
 	start := time.Now()
 	for i := 0; i < backoffLimit; i++ {
 		if i > 0 {
-			time.Sleep(sleep)
+			if err := util.Sleep(ctx, sleep); err != nil {
+				return err
+			}
 			sleep *= 2
 		}
 		err = f()
@@ -84,92 +91,117 @@ func Retry(backoffLimit int, sleep time.Duration, f func() error) (err error) {
 	return fmt.Errorf("retry failed after %d attempts (took %d seconds), last error: %s", backoffLimit, int(elapsed.Seconds()), err)
 }
 
-func generateWorkerValuesFile(cluster Cluster, valuesFile string, config Configuration, insecureMetrics bool) {
+func generateWorkerValuesFile(ctx context.Context, cluster Cluster, valuesFile string, config Configuration, insecureMetrics bool) error {
 	var secrets map[string]string
-	err := Retry(3, 1*time.Second, func() (err error) {
-		secrets = fetchSecret(cluster.Name, config.ClusterConfiguration.ControllerCluster, config.KubeSliceConfiguration.ProjectName)
+	err := Retry(ctx, 3, 1*time.Second, func() (err error) {
+		secrets, err = fetchSecret(ctx, cluster.Name, config.ClusterConfiguration.ControllerCluster, config.KubeSliceConfiguration.ProjectName)
+		if err != nil {
+			return err
+		}
 		if secrets["namespace"] == "" || secrets["controllerEndpoint"] == "" || secrets["ca.crt"] == "" || secrets["token"] == "" {
 			return fmt.Errorf("secret is empty")
 		}
 		return nil
 	})
 	if err != nil {
-		log.Fatalf("Unable to fetch secrets\n%s", err)
+		return fmt.Errorf("Unable to fetch secrets\n%s", err)
 	}
-	err = generateValuesFile(kubesliceDirectory+"/"+valuesFile, &config.HelmChartConfiguration.WorkerChart, fmt.Sprintf(workerValuesTemplate+generateImagePullSecretsValue(config.HelmChartConfiguration.ImagePullSecret), secrets["namespace"], secrets["controllerEndpoint"], secrets["ca.crt"], secrets["token"], insecureMetrics, cluster.Name, cluster.ControlPlaneAddress))
+	overrides, err := ClusterValues(cluster)
 	if err != nil {
-		log.Fatalf("%s %s", util.Cross, err)
+		return fmt.Errorf("Unable to read values files of %s: %s", cluster.Name, err)
 	}
+	return generateValuesFile(kubesliceDirectory+"/"+valuesFile, &config.HelmChartConfiguration.WorkerChart, workerValuesDefaults(cluster, config, secrets, insecureMetrics), overrides...)
 }
 
-func installWorker(cluster Cluster, valuesName string, helmChartConfig HelmChartConfiguration) {
+func workerValuesDefaults(cluster Cluster, config Configuration, secrets map[string]string, insecureMetrics bool) string {
+	return fmt.Sprintf(workerValuesTemplate+generateImagePullSecretsValue(config.HelmChartConfiguration.ImagePullSecret), secrets["namespace"], secrets["controllerEndpoint"], secrets["ca.crt"], secrets["token"], insecureMetrics, cluster.Name, cluster.ControlPlaneAddress)
+}
+
+func installWorker(ctx context.Context, out *util.Output, cluster Cluster, valuesName string, helmChartConfig HelmChartConfiguration) error {
 	hc := helmChartConfig
-	installKubeSliceWorkerHelm(cluster, valuesName, hc)
-	util.Printf("%s Successfully installed helm chart %s/%s on %s", util.Tick, hc.RepoAlias, hc.WorkerChart.ChartName, cluster.Name)
-	time.Sleep(200 * time.Millisecond)
+	if err := installKubeSliceWorkerHelm(ctx, out, cluster, valuesName, hc); err != nil {
+		return err
+	}
+	out.Printf("%s Successfully installed helm chart %s/%s on %s", util.Tick, hc.RepoAlias, hc.WorkerChart.ChartName, cluster.Name)
 
-	util.Printf("%s Waiting for KubeSlice Worker Pods to be Healthy...", util.Wait)
-	PodVerification("Waiting for KubeSlice Worker Pods to be Healthy", cluster, "kubeslice-system")
+	out.Printf("%s Waiting for KubeSlice Worker Pods to be Healthy...", util.Wait)
+	if err := podVerification(ctx, out, "Waiting for KubeSlice Worker Pods to be Healthy", cluster, "kubeslice-system"); err != nil {
+		return err
+	}
 
-	util.Printf("%s Successfully installed KubeSlice Worker %s.", util.Tick, cluster.Name)
+	out.Printf("%s Successfully installed KubeSlice Worker %s.", util.Tick, cluster.Name)
+	return nil
 }
 
-func installKubeSliceWorkerHelm(cluster Cluster, valuesFile string, hc HelmChartConfiguration) {
-	args := make([]string, 0)
-	args = append(args, "--kube-context", cluster.ContextName, "--kubeconfig", cluster.KubeConfigPath, "upgrade", "-i", "kubeslice-worker", fmt.Sprintf("%s/%s", hc.RepoAlias, hc.WorkerChart.ChartName), "--namespace", "kubeslice-system", "--create-namespace", "-f", kubesliceDirectory+"/"+valuesFile)
-	if hc.WorkerChart.Version != "" {
-		args = append(args, "--version", hc.WorkerChart.Version)
-	}
-	err := util.RunCommand("helm", args...)
+func installKubeSliceWorkerHelm(ctx context.Context, out *util.Output, cluster Cluster, valuesFile string, hc HelmChartConfiguration) error {
+	recordRelease(ctx, cluster, "kubeslice-worker", "kubeslice-system")
+	client, err := NewHelmClient(out, &cluster)
 	if err != nil {
-		log.Fatalf("Process failed %v", err)
+		return err
 	}
+	return client.Upgrade(ctx, HelmReleaseSpec{
+		Name:            "kubeslice-worker",
+		Namespace:       "kubeslice-system",
+		Chart:           fmt.Sprintf("%s/%s", hc.RepoAlias, hc.WorkerChart.ChartName),
+		Version:         hc.WorkerChart.Version,
+		ValuesFiles:     []string{kubesliceDirectory + "/" + valuesFile},
+		Install:         true,
+		CreateNamespace: true,
+	})
 }
 
-func fetchSecret(clusterName string, cc Cluster, projectName string) map[string]string {
+func fetchSecret(ctx context.Context, clusterName string, cc Cluster, projectName string) (map[string]string, error) {
 	//kubectl get secrets -n kubeslice-demo -o name
-	secret := findSecret(clusterName, projectName, cc)
-	//kubectl get secret/kubeslice-rbac-worker-kubeslice-worker-1-token-h99pc -n kubeslice-demo -o jsonpath={.data}
-	var outB, errB bytes.Buffer
-	err := util.RunCommandCustomIO("kubectl", &outB, &errB, true, "--context="+cc.ContextName, "--kubeconfig="+cc.KubeConfigPath, "get", secret, "-n", "kubeslice-"+projectName, "-o", "jsonpath={.data}")
+	secret, err := findSecret(ctx, clusterName, projectName, cc)
+	if err != nil {
+		return nil, err
+	}
+	client, err := NewKubeClient(&cc)
+	if err != nil {
+		return nil, err
+	}
+	obj, err := client.Get(ctx, SecretObject, "kubeslice-"+projectName, secret)
 	if err != nil {
-		log.Fatalf("Process failed %v", err)
+		return nil, err
 	}
-	x := map[string]string{}
-	err = json.Unmarshal(outB.Bytes(), &x)
+	if util.DryRun {
+		return map[string]string{
+			"namespace":          dryRunValue("namespace", clusterName),
+			"controllerEndpoint": dryRunValue("controllerEndpoint", clusterName),
+			"ca.crt":             dryRunValue("ca.crt", clusterName),
+			"token":              dryRunValue("token", clusterName),
+		}, nil
+	}
+	// the values stay base64 encoded
+	data, _, err := unstructured.NestedStringMap(obj.Object, "data")
 	if err != nil {
-		log.Fatalf("failed to read secret %s", secret)
+		return nil, fmt.Errorf("failed to read secret %s", secret)
 	}
-	return x
+	return data, nil
 }
 
-func findSecret(workerName string, projectName string, cc Cluster) string {
-	var outB, errB bytes.Buffer
-	err := util.RunCommandCustomIO("kubectl", &outB, &errB, true, "--context="+cc.ContextName, "--kubeconfig="+cc.KubeConfigPath, "get", "sa", "-n", "kubeslice-"+projectName, "-o", "name")
+func findSecret(ctx context.Context, workerName string, projectName string, cc Cluster) (string, error) {
+	secret, err := findServiceAccountSecret(ctx, &cc, "kubeslice-"+projectName, "rbac-worker-"+workerName)
 	if err != nil {
-		log.Fatalf("Process failed %v", err)
+		return "", err
 	}
-
-	var secret string
-	for _, line := range strings.Split(outB.String(), "\n") {
-		if strings.Contains(line, "rbac-worker-"+workerName) {
-			secret = fmt.Sprintf("secrets/%s", strings.TrimPrefix(line, "serviceaccount/"))
-			break
-		}
+	if util.DryRun {
+		return dryRunValue("worker-secret", workerName), nil
 	}
 	if secret == "" {
-		log.Fatalf("failed to find secret for %s", workerName)
+		return "", fmt.Errorf("failed to find secret for %s", workerName)
 	}
-	return secret
+	return secret, nil
 }
 
-func uninstallKubeSliceWorkerHelm(cluster Cluster) {
-	args := make([]string, 0)
-	args = append(args, "--kube-context", cluster.ContextName, "--kubeconfig", cluster.KubeConfigPath, "uninstall", "kubeslice-worker", "--namespace", "kubeslice-system")
-
-	err := util.RunCommand("helm", args...)
+func uninstallKubeSliceWorkerHelm(ctx context.Context, out *util.Output, cluster Cluster) error {
+	client, err := NewHelmClient(out, &cluster)
 	if err != nil {
-		util.Printf("%s Uninstall failed. %v", util.Cross, err)
+		return err
+	}
+	if err := client.Uninstall(ctx, "kubeslice-worker", "kubeslice-system"); err != nil {
+		return err
 	}
-	util.Printf("%s Successfully uninstalled KubeSlice Worker %s.", util.Tick, cluster.Name)
+	out.Printf("%s Successfully uninstalled KubeSlice Worker %s.", util.Tick, cluster.Name)
+	return nil
 }
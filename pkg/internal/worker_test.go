@@ -0,0 +1,80 @@
+package internal
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/kubeslice/kubeslice-cli/util"
+)
+
+// not parallel, as it sets the executor, KubeBackend and ExecutablePaths, and
+// changes the working directory
+func TestGenerateWorkerValuesFile(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to setup test: %v", err)
+	}
+	dir := t.TempDir()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("Failed to setup test: %v", err)
+	}
+	backend := KubeBackend
+	KubeBackend, util.ExecutablePaths = KubeBackendKubectl, map[string]string{"kubectl": "kubectl"}
+	t.Cleanup(func() {
+		os.Chdir(wd)
+		KubeBackend, util.ExecutablePaths = backend, nil
+	})
+	fake := &util.FakeExecutor{}
+	fake.Respond(" get serviceaccounts ", util.FakeResponse{Stdout: `{"items":[{"metadata":{"name":"kubeslice-rbac-worker-worker-1"}}]}`}).
+		Respond(" get secrets ", util.FakeResponse{Stdout: `{"metadata":{"name":"kubeslice-rbac-worker-worker-1"},
+			"data":{"namespace":"a3ViZXNsaWNlLWRlbW8=","controllerEndpoint":"aHR0cHM6Ly8xMC4wLjAuMTo2NDQz","ca.crt":"Y2E=","token":"dG9rZW4="}}`})
+	defer util.SetExecutor(util.SetExecutor(fake))
+
+	if err := os.Mkdir(kubesliceDirectory, 0755); err != nil {
+		t.Fatalf("Failed to setup test: %v", err)
+	}
+	valuesFile := filepath.Join(dir, "worker-1.yaml")
+	if err := os.WriteFile(valuesFile, []byte("cluster:\n  endpoint: https://worker-1.example.com\n"), 0644); err != nil {
+		t.Fatalf("Failed to setup test: %v", err)
+	}
+	config := Configuration{}
+	config.KubeSliceConfiguration.ProjectName = "demo"
+	config.HelmChartConfiguration.WorkerChart.Values = map[string]interface{}{"cluster.name": "chart", "image.tag": "1.0"}
+	cluster := Cluster{
+		Name:                "worker-1",
+		ControlPlaneAddress: "https://172.18.0.3:6443",
+		ValuesFiles:         []string{valuesFile},
+		HelmValues:          map[string]interface{}{"metrics.insecure": false, "controllerSecret": map[interface{}]interface{}{"namespace": "custom"}},
+	}
+
+	if err := generateWorkerValuesFile(context.Background(), cluster, "helm-values-worker-1.yaml", config, true); err != nil {
+		t.Fatalf("generateWorkerValuesFile() returned unexpected error %v", err)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(kubesliceDirectory, "helm-values-worker-1.yaml"))
+	if err != nil {
+		t.Fatalf("generateWorkerValuesFile() did not write the values file: %v", err)
+	}
+	// the generated values override the chart values, and the values files
+	// and helm_values of the worker override the generated values
+	want := parseValues(t, `
+controllerSecret:
+  namespace: custom
+  endpoint: aHR0cHM6Ly8xMC4wLjAuMTo2NDQz
+  ca.crt: Y2E=
+  token: dG9rZW4=
+metrics:
+  insecure: false
+cluster:
+  name: worker-1
+  endpoint: https://worker-1.example.com
+image:
+  tag: "1.0"
+`)
+	if got := parseValues(t, string(data)); !reflect.DeepEqual(got, want) {
+		t.Errorf("generateWorkerValuesFile() wrote %v, want %v", got, want)
+	}
+}
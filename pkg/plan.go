@@ -0,0 +1,126 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/kubeslice/kubeslice-cli/pkg/internal"
+	"github.com/kubeslice/kubeslice-cli/util"
+)
+
+// PlanParams selects what the plan compares and how it is printed.
+type PlanParams struct {
+	SkipSteps    map[string]string // install steps not to compare
+	OutputFormat string            // text or json
+}
+
+// Plan prints what install would change on the clusters of the topology.
+func Plan(ctx context.Context, params PlanParams) error {
+	if profile := ApplicationConfiguration.InstallProfile; profile != nil {
+		for _, step := range profile.SkipSteps {
+			params.SkipSteps[step] = ""
+		}
+	}
+	if err := internal.VerifyExecutables(ctx, ApplicationConfiguration); err != nil {
+		return err
+	}
+	if ApplicationConfiguration.Configuration.ClusterConfiguration.Profile != "" {
+		internal.SetKubeConfigPath()
+	}
+	// the network information recorded by the last install is used when there
+	// is some, as it is what install would use on resume
+	state, err := internal.LoadInstallState()
+	if err != nil || !state.RestoreNetworkInformation(&ApplicationConfiguration.Configuration.ClusterConfiguration) {
+		if err := internal.GatherNetworkInformation(ctx, ApplicationConfiguration); err != nil {
+			return err
+		}
+	}
+
+	util.Printf("\nComparing the topology with the clusters...")
+	plan, err := internal.MakePlan(ctx, ApplicationConfiguration, planComponents(installSteps(), params.SkipSteps))
+	if err != nil {
+		return fmt.Errorf("Failed to compare the topology with the clusters\n%w", err)
+	}
+	if params.OutputFormat == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(plan); err != nil {
+			return fmt.Errorf("Failed to print the plan %w", err)
+		}
+		return nil
+	}
+	printPlan(os.Stdout, plan)
+	return nil
+}
+
+// planComponents returns the install steps whose result the plan compares.
+func planComponents(steps []*installStep, skipSteps map[string]string) []string {
+	components := make([]string, 0)
+	skipped := skippedSteps(steps, skipSteps)
+	for _, step := range steps {
+		if skipped[step.name] || step.implicit || !step.applicable() {
+			continue
+		}
+		components = append(components, step.name)
+		// a demo profile can apply the demo slice
+		if step.name == internal.Demo_Component && ApplicationConfiguration.InstallProfile.Demo.ApplySlice {
+			components = append(components, internal.Slice_Component)
+		}
+	}
+	return components
+}
+
+var planSymbols = map[string]string{
+	internal.ActionAdd:    "+",
+	internal.ActionChange: "~",
+	internal.ActionRemove: "-",
+}
+
+func printPlan(w io.Writer, plan *internal.Plan) {
+	for _, cluster := range plan.Clusters {
+		if len(cluster.Changes) == 0 {
+			fmt.Fprintf(w, "\n%s: no changes\n", cluster.Cluster)
+			continue
+		}
+		fmt.Fprintf(w, "\n%s:\n", cluster.Cluster)
+		for _, change := range cluster.Changes {
+			line := fmt.Sprintf("  %s %s", planSymbols[change.Action], change.Resource)
+			if change.Field != "" {
+				line += " " + change.Field
+			}
+			switch {
+			case change.Action == internal.ActionChange:
+				line += fmt.Sprintf(": %s -> %s", planValue(change.Running), planValue(change.Desired))
+			case change.Field != "" && change.Action == internal.ActionAdd:
+				line += ": " + planValue(change.Desired)
+			case change.Field != "" && change.Action == internal.ActionRemove:
+				line += ": " + planValue(change.Running)
+			case change.Field == "" && change.Action == internal.ActionAdd:
+				if chart, ok := change.Desired.(string); ok {
+					line += " (" + chart + ")"
+				}
+			case change.Field == "" && change.Action == internal.ActionRemove:
+				if chart, ok := change.Running.(string); ok {
+					line += " (" + chart + ")"
+				}
+			}
+			fmt.Fprintln(w, line)
+		}
+	}
+	add, change, remove := plan.Summary()
+	fmt.Fprintf(w, "\nPlan: %d to add, %d to change, %d to remove.\n", add, change, remove)
+}
+
+func planValue(value interface{}) string {
+	if value == nil {
+		return "<none>"
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
@@ -0,0 +1,133 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/kubeslice/kubeslice-cli/pkg/internal"
+	"github.com/kubeslice/kubeslice-cli/util"
+)
+
+// running state of the clusters, ks-w-2 having no worker installed yet
+const fakeHelm = `#!/bin/sh
+case "$*" in
+*"--kube-context ks-ctrl "*" list "*)
+	echo '[{"name":"kubeslice-controller","namespace":"kubeslice-controller","chart":"kubeslice-controller-0.5.0","status":"deployed"},
+		{"name":"kubeslice-ui","namespace":"kubeslice-controller","chart":"kubeslice-ui-1.0.0","status":"deployed"}]' ;;
+*"--kube-context ks-ctrl "*" get values kubeslice-controller "*)
+	echo '{"kubeslice":{"controller":{"loglevel":"debug","rbacResourcePrefix":"kubeslice-rbac","projectnsPrefix":"kubeslice","endpoint":"https://10.0.0.1:6443"}}}' ;;
+*"--kube-context ks-w-1 "*" list "*)
+	echo '[{"name":"kubeslice-worker","namespace":"kubeslice-system","chart":"kubeslice-worker-0.6.0","status":"deployed"}]' ;;
+*"--kube-context ks-w-1 "*" get values kubeslice-worker "*)
+	echo '{"controllerSecret":{"token":"dG9rZW4="},"metrics":{"insecure":false},"cluster":{"name":"ks-w-1","endpoint":"https://10.0.0.2:6443"},"logLevel":"debug"}' ;;
+*" list "*)
+	echo '[]' ;;
+*)
+	exit 1 ;;
+esac
+`
+
+const fakeKubectl = `#!/bin/sh
+case "$*" in
+*" get projects.controller.kubeslice.io "*)
+	echo '{"items":[{"metadata":{"name":"demo"},"spec":{"serviceAccount":{"readWrite":["admin"]}}}]}' ;;
+*" get clusters.controller.kubeslice.io "*)
+	echo '{"items":[{"metadata":{"name":"ks-w-1"},"spec":{"clusterProperty":{},"networkInterface":"eth0"}},
+		{"metadata":{"name":"ks-w-3"},"spec":{"clusterProperty":{}}}]}' ;;
+*)
+	exit 1 ;;
+esac
+`
+
+// not parallel, as it sets the shared application configuration and executables
+func TestPlan(t *testing.T) {
+	dir := t.TempDir()
+	for name, script := range map[string]string{"helm": fakeHelm, "kubectl": fakeKubectl} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(script), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	util.ExecutablePaths = map[string]string{"helm": filepath.Join(dir, "helm"), "kubectl": filepath.Join(dir, "kubectl")}
+	ApplicationConfiguration = &internal.ConfigurationSpecs{}
+	t.Cleanup(func() { ApplicationConfiguration, util.ExecutablePaths = nil, nil })
+	config := &ApplicationConfiguration.Configuration
+	config.ClusterConfiguration.ControllerCluster = internal.Cluster{Name: "ks-ctrl", ContextName: "ks-ctrl", ControlPlaneAddress: "https://10.0.0.1:6443"}
+	config.ClusterConfiguration.WorkerClusters = []internal.Cluster{
+		{Name: "ks-w-1", ContextName: "ks-w-1", ControlPlaneAddress: "https://10.0.0.2:6443"},
+		{Name: "ks-w-2", ContextName: "ks-w-2", ControlPlaneAddress: "https://10.0.0.3:6443"},
+	}
+	config.KubeSliceConfiguration.ProjectName = "demo"
+	config.HelmChartConfiguration.ControllerChart = internal.HelmChart{ChartName: "kubeslice-controller", Version: "0.6.0"}
+	config.HelmChartConfiguration.WorkerChart = internal.HelmChart{ChartName: "kubeslice-worker"}
+
+	plan, err := internal.MakePlan(context.Background(), ApplicationConfiguration, planComponents(installSteps(), map[string]string{"cert-manager": ""}))
+	if err != nil {
+		t.Fatalf("MakePlan() returned unexpected error %v", err)
+	}
+	var b bytes.Buffer
+	printPlan(&b, plan)
+
+	want := `
+ks-ctrl:
+  ~ releases/kubeslice-controller version: "0.5.0" -> "0.6.0"
+  ~ releases/kubeslice-controller values.kubeslice.controller.loglevel: "debug" -> "info"
+  - releases/kubeslice-ui (kubeslice-ui-1.0.0)
+  + clusters/ks-w-2
+  - clusters/ks-w-3
+
+ks-w-1:
+  - releases/kubeslice-worker values.logLevel: "debug"
+
+ks-w-2:
+  + releases/kubeslice-worker (kubeslice-worker latest)
+
+Plan: 2 to add, 2 to change, 3 to remove.
+`
+	if b.String() != want {
+		t.Errorf("plan printed\n%s\nwant\n%s", b.String(), want)
+	}
+
+	// --skip controller skips the steps which are part of it, as install does
+	skipSteps := map[string]string{"controller": ""}
+	steps, _, err := selectInstallSteps(installSteps(), InstallParams{SkipSteps: skipSteps})
+	if err != nil {
+		t.Fatalf("selectInstallSteps() returned unexpected error %v", err)
+	}
+	installed := make([]string, 0)
+	for _, step := range steps {
+		if !step.implicit {
+			installed = append(installed, step.name)
+		}
+	}
+	if components := planComponents(installSteps(), skipSteps); !reflect.DeepEqual(components, installed) {
+		t.Errorf("planComponents() with --skip controller = %v, want the steps install runs %v", components, installed)
+	}
+	plan, err = internal.MakePlan(context.Background(), ApplicationConfiguration, planComponents(installSteps(), skipSteps))
+	if err != nil {
+		t.Fatalf("MakePlan() returned unexpected error %v", err)
+	}
+	b.Reset()
+	printPlan(&b, plan)
+	// the controller release is neither compared nor removed
+	want = `
+ks-ctrl:
+  - releases/kubeslice-ui (kubeslice-ui-1.0.0)
+  + clusters/ks-w-2
+  - clusters/ks-w-3
+
+ks-w-1:
+  - releases/kubeslice-worker values.logLevel: "debug"
+
+ks-w-2:
+  + releases/kubeslice-worker (kubeslice-worker latest)
+
+Plan: 2 to add, 0 to change, 3 to remove.
+`
+	if b.String() != want {
+		t.Errorf("plan with --skip controller printed\n%s\nwant\n%s", b.String(), want)
+	}
+}
@@ -0,0 +1,56 @@
+package pkg
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/kubeslice/kubeslice-cli/pkg/internal"
+	"github.com/kubeslice/kubeslice-cli/util"
+)
+
+const (
+	// ProfileMinimalDemo is the name of the built-in minimal demo profile.
+	//
+	// Deprecated: profiles are looked up by name among the built-in ones and
+	// the profile files, see ListProfiles.
+	ProfileMinimalDemo = "minimal-demo"
+	// ProfileEntDemo is the name of the built-in enterprise demo profile.
+	//
+	// Deprecated: profiles are looked up by name among the built-in ones and
+	// the profile files, see ListProfiles.
+	ProfileEntDemo = "enterprise-demo"
+)
+
+// ListProfiles prints the built-in profiles and the ones found in the
+// profiles directory. Broken profile files are reported but do not hide the
+// others.
+func ListProfiles() {
+	profiles, errors := internal.ListProfiles(profilesDirectory())
+	for _, e := range errors {
+		util.Printf("%s %s", util.Cross, e)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSOURCE\tDESCRIPTION")
+	for _, profile := range profiles {
+		source := profile.Source
+		if source == "" {
+			source = "built-in"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", profile.Name, source, profile.Description)
+	}
+	w.Flush()
+}
+
+// ShowProfile prints the profile file of the profile called name.
+func ShowProfile(name string) error {
+	profile, errors := internal.LoadProfile(profilesDirectory(), name)
+	if profile == nil {
+		for _, e := range errors {
+			util.Printf("%s %s", util.Cross, e)
+		}
+		return util.ValidationErrorf("Failed to load profile %s", name)
+	}
+	fmt.Print(string(profile.Data))
+	return nil
+}
@@ -1,26 +1,27 @@
 package pkg
 
 import (
+	"context"
 	"github.com/kubeslice/kubeslice-cli/pkg/internal"
 )
 
-func CreateProject() {
+func CreateProject(ctx context.Context) error {
 	ApplicationConfiguration.Configuration.KubeSliceConfiguration.ProjectName = CliOptions.ObjectName
-	internal.CreateKubeSliceProject(ApplicationConfiguration, CliOptions)
+	return internal.CreateKubeSliceProject(ctx, ApplicationConfiguration, CliOptions)
 }
 
-func GetProject() {
-	internal.GetKubeSliceProject(CliOptions.ObjectName, CliOptions.Namespace, CliOptions.Cluster)
+func GetProject(ctx context.Context) error {
+	return internal.GetKubeSliceProject(ctx, CliOptions.ObjectName, CliOptions.Namespace, CliOptions.Cluster)
 }
 
-func DeleteProject() {
-	internal.DeleteKubeSliceProject(CliOptions.ObjectName, CliOptions.Namespace, CliOptions.Cluster)
+func DeleteProject(ctx context.Context) error {
+	return internal.DeleteKubeSliceProject(ctx, CliOptions.ObjectName, CliOptions.Namespace, CliOptions.Cluster)
 }
 
-func EditProject() {
-	internal.EditKubeSliceProject(CliOptions.ObjectName, CliOptions.Namespace, CliOptions.Cluster)
+func EditProject(ctx context.Context) error {
+	return internal.EditKubeSliceProject(ctx, CliOptions.ObjectName, CliOptions.Namespace, CliOptions.Cluster)
 }
 
-func DescribeProject() {
-	internal.DescribeKubeSliceProject(CliOptions.ObjectName, CliOptions.Namespace, CliOptions.Cluster)
+func DescribeProject(ctx context.Context) error {
+	return internal.DescribeKubeSliceProject(ctx, CliOptions.ObjectName, CliOptions.Namespace, CliOptions.Cluster)
 }
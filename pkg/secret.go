@@ -1,9 +1,10 @@
 package pkg
 
 import (
+	"context"
 	"github.com/kubeslice/kubeslice-cli/pkg/internal"
 )
 
-func GetSecrets(worker string) {
-	internal.GetSecrets(worker, CliOptions.Namespace, CliOptions.Cluster, CliOptions.OutputFormat)
+func GetSecrets(ctx context.Context, worker string) error {
+	return internal.GetSecrets(ctx, worker, CliOptions.Namespace, CliOptions.Cluster, CliOptions.OutputFormat)
 }
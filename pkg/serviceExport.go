@@ -1,25 +1,26 @@
 package pkg
 
 import (
+	"context"
 	"github.com/kubeslice/kubeslice-cli/pkg/internal"
 )
 
-func CreateServiceExportConfig(filename string) {
-	internal.CreateServiceExportConfig(CliOptions.Namespace, CliOptions.Cluster, filename)
+func CreateServiceExportConfig(ctx context.Context, filename string) error {
+	return internal.CreateServiceExportConfig(ctx, CliOptions.Namespace, CliOptions.Cluster, filename)
 }
 
-func GetServiceExportConfig() {
-	internal.GetServiceExportConfig(CliOptions.ObjectName, CliOptions.Namespace, CliOptions.Cluster)
+func GetServiceExportConfig(ctx context.Context) error {
+	return internal.GetServiceExportConfig(ctx, CliOptions.ObjectName, CliOptions.Namespace, CliOptions.Cluster)
 }
 
-func DeleteServiceExportConfig() {
-	internal.DeleteServiceExportConfig(CliOptions.ObjectName, CliOptions.Namespace, CliOptions.Cluster)
+func DeleteServiceExportConfig(ctx context.Context) error {
+	return internal.DeleteServiceExportConfig(ctx, CliOptions.ObjectName, CliOptions.Namespace, CliOptions.Cluster)
 }
 
-func EditServiceExportConfig() {
-	internal.EditServiceExportConfig(CliOptions.ObjectName, CliOptions.Namespace, CliOptions.Cluster)
+func EditServiceExportConfig(ctx context.Context) error {
+	return internal.EditServiceExportConfig(ctx, CliOptions.ObjectName, CliOptions.Namespace, CliOptions.Cluster)
 }
 
-func DescribeServiceExportConfig() {
-	internal.DescribeServiceExportConfig(CliOptions.ObjectName, CliOptions.Namespace, CliOptions.Cluster)
+func DescribeServiceExportConfig(ctx context.Context) error {
+	return internal.DescribeServiceExportConfig(ctx, CliOptions.ObjectName, CliOptions.Namespace, CliOptions.Cluster)
 }
@@ -1,30 +1,38 @@
 package pkg
 
 import (
+	"context"
 	"github.com/kubeslice/kubeslice-cli/pkg/internal"
 )
 
-func CreateSliceConfig(worker []string) {
+func CreateSliceConfig(ctx context.Context, worker []string) error {
 	if len(CliOptions.FileName) != 0 {
-		internal.CreateSliceConfig(CliOptions.Namespace, CliOptions.Cluster, CliOptions.FileName)
+		if err := internal.CreateSliceConfig(ctx, CliOptions.Namespace, CliOptions.Cluster, CliOptions.FileName); err != nil {
+			return err
+		}
 	} else if len(worker) != 0 {
-		internal.GenerateSliceConfiguration(ApplicationConfiguration, worker, CliOptions.ObjectName, CliOptions.Namespace)
-		internal.ApplyFile("kubeslice/slice-"+CliOptions.ObjectName+".yaml", CliOptions.Namespace, CliOptions.Cluster)
+		if err := internal.GenerateSliceConfiguration(ApplicationConfiguration, worker, CliOptions.ObjectName, CliOptions.Namespace); err != nil {
+			return err
+		}
+		if err := internal.ApplyFile(ctx, "kubeslice/slice-"+CliOptions.ObjectName+".yaml", CliOptions.Namespace, CliOptions.Cluster); err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
-func GetSliceConfig() {
-	internal.GetSliceConfig(CliOptions.ObjectName, CliOptions.Namespace, CliOptions.Cluster)
+func GetSliceConfig(ctx context.Context) error {
+	return internal.GetSliceConfig(ctx, CliOptions.ObjectName, CliOptions.Namespace, CliOptions.Cluster)
 }
 
-func DeleteSliceConfig() {
-	internal.DeleteSliceConfig(CliOptions.ObjectName, CliOptions.Namespace, CliOptions.Cluster)
+func DeleteSliceConfig(ctx context.Context) error {
+	return internal.DeleteSliceConfig(ctx, CliOptions.ObjectName, CliOptions.Namespace, CliOptions.Cluster)
 }
 
-func EditSliceConfig() {
-	internal.EditSliceConfig(CliOptions.ObjectName, CliOptions.Namespace, CliOptions.Cluster)
+func EditSliceConfig(ctx context.Context) error {
+	return internal.EditSliceConfig(ctx, CliOptions.ObjectName, CliOptions.Namespace, CliOptions.Cluster)
 }
 
-func DescribeSliceConfig() {
-	internal.DescribeSliceConfig(CliOptions.ObjectName, CliOptions.Namespace, CliOptions.Cluster)
+func DescribeSliceConfig(ctx context.Context) error {
+	return internal.DescribeSliceConfig(ctx, CliOptions.ObjectName, CliOptions.Namespace, CliOptions.Cluster)
 }
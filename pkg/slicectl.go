@@ -1,121 +1,157 @@
 package pkg
 
 import (
+	"context"
 	"time"
 
 	"github.com/kubeslice/kubeslice-cli/pkg/internal"
 	"github.com/kubeslice/kubeslice-cli/util"
 )
 
-func Install(skipSteps map[string]string) {
-	basicInstall(skipSteps)
-	if _, skipDemo := skipSteps[internal.Demo_Component]; !skipDemo {
-		switch ApplicationConfiguration.Configuration.ClusterConfiguration.Profile {
-		case ProfileFullDemo:
-			fullDemo()
-		case ProfileMinimalDemo:
-			minimalDemo()
-		case ProfileEntDemo:
-			entDemo()
-		}
-	}
-}
-
-func fullDemo() {
-	internal.GenerateSliceConfiguration(ApplicationConfiguration, nil, "", "")
-	internal.ApplySliceConfiguration(ApplicationConfiguration)
-	util.Printf("%s Waiting for configuration propagation", util.Wait)
-	time.Sleep(20 * time.Second)
-	internal.GenerateIPerfManifests()
-	internal.GenerateIPerfServiceExportManifest(ApplicationConfiguration)
-	internal.InstallIPerf(ApplicationConfiguration)
-	internal.ApplyIPerfServiceExportManifest(ApplicationConfiguration)
-	util.Printf("%s Waiting for configuration propagation", util.Wait)
-	time.Sleep(20 * time.Second)
-	internal.RolloutRestartIPerf(ApplicationConfiguration)
-	internal.PrintNextSteps(true, ApplicationConfiguration)
-}
-
-func minimalDemo() {
-	internal.GenerateSliceConfiguration(ApplicationConfiguration, nil, "", "")
-	internal.GenerateIPerfManifests()
-	internal.InstallIPerf(ApplicationConfiguration)
-	internal.GenerateIPerfServiceExportManifest(ApplicationConfiguration)
-	internal.PrintNextSteps(false, ApplicationConfiguration)
+// InstallParams selects the install steps to run.
+type InstallParams struct {
+	SkipSteps    map[string]string        // steps not to run
+	Only         []string                 // run these steps only
+	From         string                   // first step to run
+	Until        string                   // last step to run
+	Resume       bool                     // skip the steps a previous install finished
+	Parallelism  int                      // clusters worked on at the same time
+	Atomic       bool                     // undo what the install changed when it fails
+	StepTimeouts map[string]time.Duration // time limits of single steps, by step name
+	PollInterval time.Duration            // how often the waits check the clusters
+	BackoffLimit int                      // how many times pods may be found failing
 }
 
-func entDemo() {
-	//  TODO: Add enterprise demo applications like bookinfo etc.
-	internal.GenerateSliceConfiguration(ApplicationConfiguration, nil, "", "")
-	internal.ApplySliceConfiguration(ApplicationConfiguration)
-	util.Printf("%s Waiting for configuration propagation", util.Wait)
-	time.Sleep(20 * time.Second)
-	internal.GenerateIPerfManifests()
-	internal.GenerateIPerfServiceExportManifest(ApplicationConfiguration)
-	internal.InstallIPerf(ApplicationConfiguration)
-	internal.ApplyIPerfServiceExportManifest(ApplicationConfiguration)
-	util.Printf("%s Waiting for configuration propagation", util.Wait)
-	time.Sleep(20 * time.Second)
-	internal.RolloutRestartIPerf(ApplicationConfiguration)
-	internal.PrintNextSteps(true, ApplicationConfiguration)
-}
-
-func basicInstall(skipSteps map[string]string) {
-	internal.VerifyExecutables(ApplicationConfiguration)
-
-	_, skipKind := skipSteps[internal.Kind_Component]
-	_, skipCalico := skipSteps[internal.Calico_Component]
-	_, skipController := skipSteps[internal.Controller_Component]
-	_, skipWorker := skipSteps[internal.Worker_Component]
-	_, skipWorker_registration := skipSteps[internal.Worker_registration_Component]
-	_, skipUI := skipSteps[internal.UI_install_Component]
-	_, skipCertManager := skipSteps[internal.CertManager_Component]
-	_, skipPrometheus := skipSteps[internal.Prometheus_Component]
-
-	if ApplicationConfiguration.Configuration.HelmChartConfiguration.PrometheusChart.ChartName == "" {
-		skipPrometheus = true
+func Install(ctx context.Context, params InstallParams) error {
+	if profile := ApplicationConfiguration.InstallProfile; profile != nil {
+		for _, step := range profile.SkipSteps {
+			params.SkipSteps[step] = ""
+		}
+	}
+	steps, warnings, err := selectInstallSteps(installSteps(), params)
+	if err != nil {
+		return util.ValidationErrorf("%w", err)
+	}
+	for _, warning := range warnings {
+		util.Printf("%s %s", util.Warn, warning)
+	}
+	for _, step := range steps {
+		step.timeout = params.StepTimeouts[step.name]
 	}
 
-	internal.GenerateKubeSliceDirectory()
+	if params.Parallelism > 0 {
+		internal.Parallelism = params.Parallelism
+	}
+	setWaitOptions(params.PollInterval, params.BackoffLimit)
+	if err := internal.VerifyExecutables(ctx, ApplicationConfiguration); err != nil {
+		return err
+	}
+	if err := internal.GenerateKubeSliceDirectory(); err != nil {
+		return err
+	}
 	if ApplicationConfiguration.Configuration.ClusterConfiguration.Profile != "" {
-		if !skipKind {
-			internal.GenerateKindConfiguration(ApplicationConfiguration)
+		if err := internal.CreateKubeConfig(); err != nil {
+			return err
 		}
-		internal.CreateKubeConfig()
 		internal.SetKubeConfigPath()
-		if !skipKind {
-			internal.CreateKindClusters(ApplicationConfiguration)
-		}
 	}
-	if (ApplicationConfiguration.Configuration.ClusterConfiguration.Profile != "" || ApplicationConfiguration.Configuration.ClusterConfiguration.ClusterType == "kind") && !skipCalico {
-		internal.InstallCalico(&ApplicationConfiguration.Configuration.ClusterConfiguration)
+	if !params.Atomic {
+		return runInstallSteps(ctx, steps, params.Resume, nil)
+	}
+	journal := internal.StartInstallJournal()
+	defer internal.StopInstallJournal()
+	if err := runInstallSteps(ctx, steps, params.Resume, journal); err != nil {
+		// undo even when the install was interrupted or timed out
+		undoInstall(context.Background(), journal)
+		return err
+	}
+	return nil
+}
+
+// setWaitOptions sets how often the waits check the clusters and how many
+// times pods may be found failing, zero values keeping the defaults.
+func setWaitOptions(pollInterval time.Duration, backoffLimit int) {
+	if pollInterval > 0 {
+		internal.WaitInterval = pollInterval
+	}
+	if backoffLimit > 0 {
+		internal.PodBackoffLimit = backoffLimit
 	}
-	internal.GatherNetworkInformation(ApplicationConfiguration)
-	internal.AddHelmCharts(ApplicationConfiguration)
-	if !skipController {
-		if !skipCertManager {
-			internal.InstallCertManager(ApplicationConfiguration)
+}
+
+// undoInstall undoes what a failed atomic install changed, and forgets the
+// steps it ran so that the next install runs them again.
+func undoInstall(ctx context.Context, journal *internal.InstallJournal) {
+	util.Printf("\n%s Install failed, undoing its changes...", util.Wait)
+	undone, failed := journal.Undo(ctx)
+	internal.ForgetInstallSteps(journal.Steps()...)
+	if len(undone) > 0 {
+		util.Printf("\nUndone:")
+		for _, change := range undone {
+			util.Printf("  %s %s", util.Tick, change)
 		}
-		internal.InstallKubeSliceController(ApplicationConfiguration)
-		internal.CreateKubeSliceProject(ApplicationConfiguration, nil)
 	}
-	if !skipUI {
-		internal.InstallKubeSliceUI(ApplicationConfiguration)
+	if len(failed) > 0 {
+		util.Printf("\nCould not undo, please clean up manually:")
+		for _, change := range failed {
+			util.Printf("  %s %s", util.Cross, change)
+		}
 	}
-	if !skipWorker_registration {
-		internal.RegisterWorkerClusters(ApplicationConfiguration, nil)
+	util.Printf("\n%s Undid %d changes, %d could not be undone", util.Warn, len(undone), len(failed))
+}
+
+// demo sets up the demo slice and applications of a profile. Unless the
+// profile applies them, the manifests are only generated and the next steps
+// walk the user through applying them. The slices declared in the topology
+// are left to the slice step.
+func demo(ctx context.Context, profile *internal.InstallProfile) error {
+	//  TODO: Add enterprise demo applications like bookinfo etc.
+	slice := internal.DemoSlice(ApplicationConfiguration)
+	if err := internal.GenerateDemoSliceConfiguration(ApplicationConfiguration); err != nil {
+		return err
 	}
-	if !skipWorker {
-		internal.InstallKubeSliceWorker(ApplicationConfiguration)
+	if profile.Demo.ApplySlice {
+		if err := internal.ApplySliceConfiguration(ctx, ApplicationConfiguration, []internal.Slice{slice}); err != nil {
+			return err
+		}
+		if err := internal.WaitForSlicePropagation(ctx, ApplicationConfiguration, []internal.Slice{slice}); err != nil {
+			return err
+		}
 	}
-	if !skipPrometheus {
-		internal.InstallPrometheus(ApplicationConfiguration)
+	if profile.HasDemoApp(internal.DemoAppIPerf) {
+		if err := internal.GenerateIPerfManifests(); err != nil {
+			return err
+		}
+		if err := internal.GenerateIPerfServiceExportManifest(ApplicationConfiguration); err != nil {
+			return err
+		}
+		if err := internal.InstallIPerf(ctx, ApplicationConfiguration); err != nil {
+			return err
+		}
+		if profile.Demo.ApplySlice {
+			if err := internal.ApplyIPerfServiceExportManifest(ctx, ApplicationConfiguration); err != nil {
+				return err
+			}
+			wc := ApplicationConfiguration.Configuration.ClusterConfiguration.WorkerClusters
+			if err := internal.WaitForServiceImport(ctx, ApplicationConfiguration, slice, "iperf-server", "iperf", wc[0].Name); err != nil {
+				return err
+			}
+			if err := internal.RolloutRestartIPerf(ctx, ApplicationConfiguration); err != nil {
+				return err
+			}
+		}
+	} else if profile.NextSteps == "" {
+		// the built-in next steps are about iPerf
+		return nil
 	}
+	return internal.PrintNextSteps(ctx, profile.Demo.ApplySlice, ApplicationConfiguration)
 }
 
-func Uninstall(componentsToUninstall, workersToUninstall map[string]string) {
+func Uninstall(ctx context.Context, componentsToUninstall, workersToUninstall map[string]string) error {
 
-	internal.VerifyExecutables(ApplicationConfiguration)
+	if err := internal.VerifyExecutables(ctx, ApplicationConfiguration); err != nil {
+		return err
+	}
 
 	// Custom topology passed
 	if ApplicationConfiguration.Configuration.ClusterConfiguration.Profile == "" {
@@ -125,20 +161,50 @@ func Uninstall(componentsToUninstall, workersToUninstall map[string]string) {
 		_, uninstallUI := componentsToUninstall[internal.UI_install_Component]
 
 		if uninstallUI {
-			internal.UninstallKubeSliceUI(ApplicationConfiguration)
+			if err := util.RunStep(ctx, "uninstall "+internal.UI_install_Component, func(ctx context.Context) error {
+				return internal.UninstallKubeSliceUI(ctx, ApplicationConfiguration)
+			}); err != nil {
+				return err
+			}
+			internal.ForgetInstallSteps(internal.UI_install_Component)
 		}
+		// best effort, the controller is uninstalled even if a worker fails
+		// to, and the worker error is returned after
+		var workerErr error
 		if uninstallWorker {
-			internal.UninstallKubeSliceWorker(ApplicationConfiguration, workersToUninstall)
+			workerErr = util.RunStep(ctx, "uninstall "+internal.Worker_Component, func(ctx context.Context) error {
+				return internal.UninstallKubeSliceWorker(ctx, ApplicationConfiguration, workersToUninstall)
+			})
+			if workerErr == nil {
+				internal.ForgetInstallSteps(internal.Worker_Component)
+			}
 		}
 		if uninstallController {
-			internal.UninstallKubeSliceController(ApplicationConfiguration)
+			if err := util.RunStep(ctx, "uninstall "+internal.Controller_Component, func(ctx context.Context) error {
+				return internal.UninstallKubeSliceController(ctx, ApplicationConfiguration)
+			}); err != nil {
+				return err
+			}
+			// the steps depending on the controller run again with it
+			internal.ForgetInstallSteps(internal.Controller_Component)
 			if uninstallCertManager {
-				internal.UninstallCertManager(ApplicationConfiguration)
+				if err := util.RunStep(ctx, "uninstall "+internal.CertManager_Component, func(ctx context.Context) error {
+					return internal.UninstallCertManager(ctx, ApplicationConfiguration)
+				}); err != nil {
+					return err
+				}
+				internal.ForgetInstallSteps(internal.CertManager_Component)
 			}
 		}
-		return
+		return workerErr
 	}
 	// Cleanup setup of Minimal/Full Demo.
 	internal.SetKubeConfigPath()
-	internal.DeleteKindClusters(ApplicationConfiguration)
+	if err := util.RunStep(ctx, "delete kind clusters", func(ctx context.Context) error {
+		return internal.DeleteKindClusters(ctx, ApplicationConfiguration)
+	}); err != nil {
+		return err
+	}
+	internal.RemoveInstallState()
+	return nil
 }
@@ -0,0 +1,119 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kubeslice/kubeslice-cli/pkg/internal"
+	"github.com/kubeslice/kubeslice-cli/util"
+)
+
+// a project existing before the install, and one the install applies
+const fakeAtomicKubectl = `#!/bin/sh
+echo "$*" >> %[1]s/kubectl.log
+case "$*" in
+*" get project.controller.kubeslice.io existing "*)
+	echo '{"metadata":{"name":"existing"}}' ;;
+*" get project.controller.kubeslice.io demo "*)
+	if [ -f %[1]s/applied ]; then echo '{"metadata":{"name":"demo"}}'; else echo 'Error from server (NotFound): not found' >&2; exit 1; fi ;;
+*" apply -f "*)
+	touch %[1]s/applied ;;
+esac
+`
+
+const atomicProjects = `apiVersion: controller.kubeslice.io/v1alpha1
+kind: Project
+metadata:
+  name: existing
+---
+apiVersion: controller.kubeslice.io/v1alpha1
+kind: Project
+metadata:
+  name: demo
+`
+
+// not parallel, as it sets the shared executables, backend and output
+func TestUndoInstall(t *testing.T) {
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "kubectl"), []byte(fmt.Sprintf(fakeAtomicKubectl, dir)), 0755); err != nil {
+		t.Fatal(err)
+	}
+	manifest := filepath.Join(dir, "project.yaml")
+	if err := ioutil.WriteFile(manifest, []byte(atomicProjects), 0644); err != nil {
+		t.Fatal(err)
+	}
+	kubeBackend := internal.KubeBackend
+	internal.KubeBackend = internal.KubeBackendKubectl
+	util.ExecutablePaths = map[string]string{"kubectl": filepath.Join(dir, "kubectl")}
+	var b bytes.Buffer
+	util.SetOutput(&b)
+	t.Cleanup(func() {
+		internal.KubeBackend = kubeBackend
+		util.ExecutablePaths = nil
+		util.SetOutput(os.Stdout)
+		internal.StopInstallJournal()
+	})
+	cluster := &internal.Cluster{Name: "ks-ctrl", ContextName: "ks-ctrl"}
+
+	journal := internal.StartInstallJournal()
+	journal.BeginStep("project")
+	internal.ApplyKubectlManifest(context.Background(), manifest, "kubeslice-controller", cluster)
+	undoInstall(context.Background(), journal)
+
+	log, err := ioutil.ReadFile(filepath.Join(dir, "kubectl.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "--context=ks-ctrl --kubeconfig= delete project.controller.kubeslice.io demo -n kubeslice-controller\n"
+	if !strings.HasSuffix(string(log), want) {
+		t.Errorf("kubectl ran\n%s\nwant it to end with\n%s", log, want)
+	}
+	if !strings.Contains(b.String(), "Undid 1 changes, 0 could not be undone") {
+		t.Errorf("undoInstall() printed\n%s\nwant the undone changes counted", b.String())
+	}
+}
+
+// not parallel, as it sets the shared application configuration, executor,
+// backends and output
+func TestUninstallErrors(t *testing.T) {
+	kubeBackend, helmBackend := internal.KubeBackend, internal.HelmBackend
+	internal.KubeBackend, internal.HelmBackend = internal.KubeBackendKubectl, internal.HelmBackendBinary
+	var b bytes.Buffer
+	util.SetOutput(&b)
+	t.Cleanup(func() {
+		internal.KubeBackend, internal.HelmBackend = kubeBackend, helmBackend
+		ApplicationConfiguration, util.ExecutablePaths = nil, nil
+		util.SetOutput(os.Stdout)
+	})
+	if _, err := ReadAndValidateConfiguration([]string{"../samples/custom-topology.yaml"}, ""); err != nil {
+		t.Fatalf("ReadAndValidateConfiguration() returned unexpected error %v", err)
+	}
+	fake := &util.FakeExecutor{}
+	fake.Respond("--kube-context eks-preprod-2 ", util.FakeResponse{Stderr: "Error: uninstall: Release not loaded: kubeslice-worker: release: not found", Err: errors.New("exit status 1")})
+	defer util.SetExecutor(util.SetExecutor(fake))
+
+	components := map[string]string{internal.Controller_Component: "", internal.Worker_Component: ""}
+	err := Uninstall(context.Background(), components, map[string]string{"*": ""})
+	if got := util.ExitCode(err); got != util.ExitCommand {
+		t.Errorf("Uninstall() = %v, exit code %d, want %d", err, got, util.ExitCommand)
+	}
+	uninstalls := make([]string, 0)
+	for _, command := range fake.Commands() {
+		if strings.Contains(command, " uninstall ") {
+			uninstalls = append(uninstalls, command)
+		}
+	}
+	if len(uninstalls) != 6 || !strings.Contains(uninstalls[5], "uninstall kubeslice-controller") {
+		t.Errorf("Uninstall() ran %q, want every worker and then the controller uninstalled", uninstalls)
+	}
+	if strings.Contains(b.String(), "Successfully uninstalled KubeSlice Worker gke-worker-2") {
+		t.Errorf("Uninstall() printed\n%s\nwant no success for the failed worker", b.String())
+	}
+}
@@ -1,7 +1,10 @@
 package pkg
 
-import "github.com/kubeslice/kubeslice-cli/pkg/internal"
+import (
+	"context"
+	"github.com/kubeslice/kubeslice-cli/pkg/internal"
+)
 
-func GetUIEndpoint() {
-	internal.GetUIEndpoint(CliOptions.Cluster, ApplicationConfiguration.Configuration.ClusterConfiguration.Profile)
+func GetUIEndpoint(ctx context.Context) {
+	internal.GetUIEndpoint(ctx, CliOptions.Cluster, ApplicationConfiguration.Enterprise())
 }
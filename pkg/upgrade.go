@@ -0,0 +1,216 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kubeslice/kubeslice-cli/pkg/internal"
+	"github.com/kubeslice/kubeslice-cli/util"
+)
+
+// maxWorkerSkew is how many minor versions a worker can be behind the
+// controller. Workers ahead of the controller are not supported.
+const maxWorkerSkew = 1
+
+// UpgradeParams controls how the charts are upgraded.
+type UpgradeParams struct {
+	ResetValues  bool          // generate the values from the topology instead of keeping the deployed ones
+	Rollback     bool          // roll a worker back when its pods fail verification
+	PollInterval time.Duration // how often the waits check the clusters
+	BackoffLimit int           // how many times pods may be found failing
+}
+
+// Upgrade upgrades the controller and then the workers, one at a time, to the
+// chart versions of the topology.
+func Upgrade(ctx context.Context, params UpgradeParams) error {
+	cc := &ApplicationConfiguration.Configuration.ClusterConfiguration
+	hc := ApplicationConfiguration.Configuration.HelmChartConfiguration
+	if hc.ControllerChart.Version == "" || hc.WorkerChart.Version == "" {
+		return util.ValidationErrorf("Please set the chart versions to upgrade to in configuration.helm_chart_configuration.controller_chart.version and worker_chart.version")
+	}
+	setWaitOptions(params.PollInterval, params.BackoffLimit)
+	if err := internal.VerifyExecutables(ctx, ApplicationConfiguration); err != nil {
+		return err
+	}
+	if cc.Profile != "" {
+		internal.SetKubeConfigPath()
+	}
+
+	util.Printf("Fetching the deployed versions...")
+	controllerRelease, err := deployedRelease(ctx, &cc.ControllerCluster, internal.KUBESLICE_CONTROLLER_NAMESPACE, internal.KUBESLICE_CONTROLLER_NAMESPACE)
+	if err != nil {
+		return err
+	}
+	workerReleases := make(map[string]*internal.HelmRelease)
+	workerVersions := make(map[string]string)
+	for i := range cc.WorkerClusters {
+		cluster := &cc.WorkerClusters[i]
+		release, err := deployedRelease(ctx, cluster, "kubeslice-worker", "kubeslice-system")
+		if err != nil {
+			return err
+		}
+		workerReleases[cluster.Name] = release
+		workerVersions[cluster.Name] = release.ChartVersion(hc.WorkerChart.ChartName)
+	}
+	controllerVersion := controllerRelease.ChartVersion(hc.ControllerChart.ChartName)
+	if err := checkUpgrade(controllerVersion, hc.ControllerChart.Version, workerVersions, hc.WorkerChart.Version); err != nil {
+		return util.ValidationErrorf("%w", err)
+	}
+
+	if err := internal.AddHelmCharts(ctx, ApplicationConfiguration); err != nil {
+		return err
+	}
+	if params.ResetValues {
+		if err := internal.GenerateKubeSliceDirectory(); err != nil {
+			return err
+		}
+		state, err := internal.LoadInstallState()
+		if err != nil || !state.RestoreNetworkInformation(cc) {
+			if err := internal.GatherNetworkInformation(ctx, ApplicationConfiguration); err != nil {
+				return err
+			}
+		}
+	}
+
+	if sameVersion(controllerVersion, hc.ControllerChart.Version) {
+		util.Printf("%s KubeSlice Controller is already at %s", util.Tick, controllerVersion)
+	} else if err := internal.UpgradeKubeSliceController(ctx, ApplicationConfiguration, params.ResetValues); err != nil {
+		return fmt.Errorf("Upgrading KubeSlice Controller failed, the workers were not upgraded\n%w", err)
+	}
+	// one worker at a time, so that a failing upgrade stops before the others
+	for _, cluster := range cc.WorkerClusters {
+		if sameVersion(workerVersions[cluster.Name], hc.WorkerChart.Version) {
+			util.Printf("%s KubeSlice Worker %s is already at %s", util.Tick, cluster.Name, hc.WorkerChart.Version)
+			continue
+		}
+		err := internal.UpgradeKubeSliceWorker(ctx, nil, ApplicationConfiguration, cluster, params.ResetValues)
+		if err == nil {
+			continue
+		}
+		util.Printf("%s Upgrading KubeSlice Worker %s failed\n%v", util.Cross, cluster.Name, err)
+		if revision := workerReleases[cluster.Name].Revision; params.Rollback && revision != "" {
+			if err := internal.RollbackKubeSliceWorker(ctx, nil, cluster, revision); err != nil {
+				return fmt.Errorf("Rolling KubeSlice Worker %s back failed\n%w", cluster.Name, err)
+			}
+		}
+		return fmt.Errorf("Stopped the upgrade at worker %s, the workers after it were not upgraded\n%w", cluster.Name, err)
+	}
+	util.Printf("\n%s Successfully upgraded KubeSlice to controller %s and worker %s", util.Tick, hc.ControllerChart.Version, hc.WorkerChart.Version)
+	return nil
+}
+
+// deployedRelease returns the release deployed on cluster. A dry run cannot
+// tell, and assumes a release of unknown version.
+func deployedRelease(ctx context.Context, cluster *internal.Cluster, name, namespace string) (*internal.HelmRelease, error) {
+	releases, err := internal.ListHelmReleases(ctx, cluster)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch the releases of %s %w", cluster.Name, err)
+	}
+	release, ok := releases[namespace+"/"+name]
+	if !ok {
+		if util.DryRun {
+			return &internal.HelmRelease{Name: name, Namespace: namespace}, nil
+		}
+		return nil, util.NotFoundErrorf("%s is not installed on %s, please run install first", name, cluster.Name)
+	}
+	return &release, nil
+}
+
+// checkUpgrade refuses downgrades and version skews the workers do not
+// support, including while they still run their current version after the
+// controller is upgraded. Unknown deployed versions, as in a dry run, are not
+// checked.
+func checkUpgrade(controllerFrom, controllerTo string, workersFrom map[string]string, workerTo string) error {
+	to, err := parseSemanticVersion(controllerTo)
+	if err != nil {
+		return err
+	}
+	workerToVersion, err := parseSemanticVersion(workerTo)
+	if err != nil {
+		return err
+	}
+	if controllerFrom != "" {
+		from, err := parseSemanticVersion(controllerFrom)
+		if err != nil {
+			return err
+		}
+		if from.newerThan(to) {
+			return fmt.Errorf("the controller runs %s, downgrading it to %s is not supported", controllerFrom, controllerTo)
+		}
+	}
+	if err := checkVersionSkew(to, workerToVersion); err != nil {
+		return fmt.Errorf("controller %s and worker %s cannot run together: %v", controllerTo, workerTo, err)
+	}
+	names := make([]string, 0, len(workersFrom))
+	for name := range workersFrom {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if workersFrom[name] == "" {
+			continue
+		}
+		from, err := parseSemanticVersion(workersFrom[name])
+		if err != nil {
+			return err
+		}
+		if from.newerThan(workerToVersion) {
+			return fmt.Errorf("worker %s runs %s, downgrading it to %s is not supported", name, workersFrom[name], workerTo)
+		}
+		if err := checkVersionSkew(to, from); err != nil {
+			return fmt.Errorf("worker %s runs %s, which controller %s does not support: %v. Please upgrade in smaller steps", name, workersFrom[name], controllerTo, err)
+		}
+	}
+	return nil
+}
+
+func checkVersionSkew(controller, worker semanticVersion) error {
+	switch {
+	case controller[0] != worker[0]:
+		return fmt.Errorf("the major versions differ")
+	case worker[1] > controller[1]:
+		return fmt.Errorf("the worker is newer than the controller")
+	case controller[1]-worker[1] > maxWorkerSkew:
+		return fmt.Errorf("the worker is more than %d minor version behind the controller", maxWorkerSkew)
+	}
+	return nil
+}
+
+// semanticVersion is the major, minor and patch version of a chart.
+type semanticVersion [3]int
+
+// parseSemanticVersion reads versions such as 0.6.0, v1.2.3 or 1.2.3-rc.1, the
+// pre-release being ignored.
+func parseSemanticVersion(version string) (semanticVersion, error) {
+	var v semanticVersion
+	core := strings.SplitN(strings.TrimPrefix(version, "v"), "-", 2)[0]
+	parts := strings.Split(core, ".")
+	if len(parts) != 3 {
+		return v, fmt.Errorf("unsupported chart version %q, expected major.minor.patch", version)
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return v, fmt.Errorf("unsupported chart version %q, expected major.minor.patch", version)
+		}
+		v[i] = n
+	}
+	return v, nil
+}
+
+func (v semanticVersion) newerThan(other semanticVersion) bool {
+	for i := range v {
+		if v[i] != other[i] {
+			return v[i] > other[i]
+		}
+	}
+	return false
+}
+
+func sameVersion(deployed, version string) bool {
+	return deployed != "" && strings.TrimPrefix(deployed, "v") == strings.TrimPrefix(version, "v")
+}
@@ -0,0 +1,94 @@
+package pkg
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckUpgrade(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name           string
+		controllerFrom string
+		controllerTo   string
+		workersFrom    map[string]string
+		workerTo       string
+		err            string
+	}{
+		{
+			name:           "Minor upgrade",
+			controllerFrom: "0.5.0",
+			controllerTo:   "0.6.0",
+			workersFrom:    map[string]string{"ks-w-1": "0.5.0", "ks-w-2": "0.5.1"},
+			workerTo:       "0.6.0",
+		},
+		{
+			name:           "Workers one minor version behind",
+			controllerFrom: "v1.1.0",
+			controllerTo:   "v1.2.0",
+			workersFrom:    map[string]string{"ks-w-1": "1.1.0"},
+			workerTo:       "1.1.2",
+		},
+		{
+			name:         "Unknown deployed versions are not checked",
+			controllerTo: "0.6.0",
+			workersFrom:  map[string]string{"ks-w-1": ""},
+			workerTo:     "0.6.0-rc.1",
+		},
+		{
+			name:           "Controller downgrade",
+			controllerFrom: "0.6.0",
+			controllerTo:   "0.5.0",
+			workersFrom:    map[string]string{"ks-w-1": "0.5.0"},
+			workerTo:       "0.5.0",
+			err:            "the controller runs 0.6.0, downgrading it to 0.5.0 is not supported",
+		},
+		{
+			name:           "Worker newer than the controller",
+			controllerFrom: "0.5.0",
+			controllerTo:   "0.5.0",
+			workersFrom:    map[string]string{"ks-w-1": "0.5.0"},
+			workerTo:       "0.6.0",
+			err:            "controller 0.5.0 and worker 0.6.0 cannot run together: the worker is newer than the controller",
+		},
+		{
+			name:           "Controller two minor versions ahead of a deployed worker",
+			controllerFrom: "0.5.0",
+			controllerTo:   "0.7.0",
+			workersFrom:    map[string]string{"ks-w-1": "0.6.0", "ks-w-2": "0.5.0"},
+			workerTo:       "0.7.0",
+			err:            "worker ks-w-2 runs 0.5.0, which controller 0.7.0 does not support",
+		},
+		{
+			name:         "Major version skew",
+			controllerTo: "1.0.0",
+			workersFrom:  map[string]string{},
+			workerTo:     "0.9.0",
+			err:          "controller 1.0.0 and worker 0.9.0 cannot run together: the major versions differ",
+		},
+		{
+			name:         "Invalid version",
+			controllerTo: "latest",
+			workerTo:     "0.6.0",
+			err:          `unsupported chart version "latest"`,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc // Capture range variable for parallel execution
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			err := checkUpgrade(tc.controllerFrom, tc.controllerTo, tc.workersFrom, tc.workerTo)
+			if tc.err == "" {
+				if err != nil {
+					t.Errorf("checkUpgrade() returned unexpected error %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.HasPrefix(err.Error(), tc.err) {
+				t.Errorf("checkUpgrade() error = %v, want %s", err, tc.err)
+			}
+		})
+	}
+}
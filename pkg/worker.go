@@ -1,29 +1,30 @@
 package pkg
 
 import (
+	"context"
 	"github.com/kubeslice/kubeslice-cli/pkg/internal"
 )
 
-func RegisterWorker() {
+func RegisterWorker(ctx context.Context) error {
 	ApplicationConfiguration.Configuration.ClusterConfiguration.WorkerClusters = nil
 	ApplicationConfiguration.Configuration.ClusterConfiguration.WorkerClusters = append(ApplicationConfiguration.Configuration.ClusterConfiguration.WorkerClusters, internal.Cluster{
 		Name: CliOptions.ObjectName,
 	})
-	internal.RegisterWorkerClusters(ApplicationConfiguration, CliOptions)
+	return internal.RegisterWorkerClusters(ctx, ApplicationConfiguration, CliOptions)
 }
 
-func GetWorker() {
-	internal.GetKubeSliceCluster(CliOptions.ObjectName, CliOptions.Namespace, CliOptions.Cluster, CliOptions.OutputFormat)
+func GetWorker(ctx context.Context) error {
+	return internal.GetKubeSliceCluster(ctx, CliOptions.ObjectName, CliOptions.Namespace, CliOptions.Cluster, CliOptions.OutputFormat)
 }
 
-func RemoveWorker() {
-	internal.DeleteKubeSliceCluster(CliOptions.ObjectName, CliOptions.Namespace, CliOptions.Cluster)
+func RemoveWorker(ctx context.Context) error {
+	return internal.DeleteKubeSliceCluster(ctx, CliOptions.ObjectName, CliOptions.Namespace, CliOptions.Cluster)
 }
 
-func EditWorker() {
-	internal.EditKubeSliceCluster(CliOptions.ObjectName, CliOptions.Namespace, CliOptions.Cluster)
+func EditWorker(ctx context.Context) error {
+	return internal.EditKubeSliceCluster(ctx, CliOptions.ObjectName, CliOptions.Namespace, CliOptions.Cluster)
 }
 
-func DescribeWorker() {
-	internal.DescribeKubeSliceCluster(CliOptions.ObjectName, CliOptions.Namespace, CliOptions.Cluster)
+func DescribeWorker(ctx context.Context) error {
+	return internal.DescribeKubeSliceCluster(ctx, CliOptions.ObjectName, CliOptions.Namespace, CliOptions.Cluster)
 }
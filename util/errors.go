@@ -0,0 +1,137 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Exit codes of the commands, by class of error, so that scripts can react to
+// the failure.
+const (
+	ExitError       = 1   // errors of no other class
+	ExitValidation  = 2   // invalid topology, profile or option
+	ExitCommand     = 3   // a helm, kubectl, kind or docker command failed
+	ExitTimeout     = 4   // something did not get ready in time
+	ExitNotFound    = 5   // a cluster, release, secret or file does not exist
+	ExitInterrupted = 130 // interrupted by SIGINT or SIGTERM, as shells report it
+)
+
+// CommandError is a helm, kubectl, kind or docker command that failed.
+type CommandError struct {
+	Cli     string
+	Command string // the command line that ran
+	Err     error
+}
+
+func (e *CommandError) Error() string {
+	return fmt.Sprintf("%s failed: %v", e.Cli, e.Err)
+}
+
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError is a topology, profile or option that is not valid.
+type ValidationError struct {
+	Err error
+}
+
+func (e *ValidationError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationErrorf formats a ValidationError, wrapping the error of a %w verb.
+func ValidationErrorf(format string, a ...interface{}) error {
+	return &ValidationError{Err: fmt.Errorf(format, a...)}
+}
+
+// TimeoutError is something that did not get ready in time.
+type TimeoutError struct {
+	Err error
+}
+
+func (e *TimeoutError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// TimeoutErrorf formats a TimeoutError, wrapping the error of a %w verb.
+func TimeoutErrorf(format string, a ...interface{}) error {
+	return &TimeoutError{Err: fmt.Errorf(format, a...)}
+}
+
+// NotFoundError is a cluster, release, secret or file that does not exist.
+type NotFoundError struct {
+	Err error
+}
+
+func (e *NotFoundError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *NotFoundError) Unwrap() error {
+	return e.Err
+}
+
+// NotFoundErrorf formats a NotFoundError, wrapping the error of a %w verb.
+func NotFoundErrorf(format string, a ...interface{}) error {
+	return &NotFoundError{Err: fmt.Errorf(format, a...)}
+}
+
+// exitCoder is an error choosing its exit code, as errors joining the errors
+// of several clusters do.
+type exitCoder interface {
+	ExitCode() int
+}
+
+// ErrorClass returns the name of the class of err, as in the JSON progress.
+func ErrorClass(err error) string {
+	switch ExitCode(err) {
+	case ExitValidation:
+		return "validation"
+	case ExitCommand:
+		return "command"
+	case ExitTimeout:
+		return "timeout"
+	case ExitNotFound:
+		return "not-found"
+	case ExitInterrupted:
+		return "interrupted"
+	}
+	return "error"
+}
+
+// ExitCode returns the exit code of the class of err. The outermost class
+// wins, e.g. a timeout waiting for a command to succeed is a timeout. Runs
+// stopped by their context are interrupted, or timed out past their deadline.
+func ExitCode(err error) int {
+	for ; err != nil; err = errors.Unwrap(err) {
+		switch err {
+		case context.Canceled:
+			return ExitInterrupted
+		case context.DeadlineExceeded:
+			return ExitTimeout
+		}
+		switch e := err.(type) {
+		case exitCoder:
+			return e.ExitCode()
+		case *ValidationError:
+			return ExitValidation
+		case *CommandError:
+			return ExitCommand
+		case *TimeoutError:
+			return ExitTimeout
+		case *NotFoundError:
+			return ExitNotFound
+		}
+	}
+	return ExitError
+}
@@ -0,0 +1,87 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// sameCode is an error choosing its exit code, as the errors of several
+// clusters do.
+type sameCode int
+
+func (c sameCode) Error() string {
+	return "failed on every cluster"
+}
+
+func (c sameCode) ExitCode() int {
+	return int(c)
+}
+
+func TestExitCode(t *testing.T) {
+	t.Parallel()
+
+	commandError := &CommandError{Cli: "helm", Command: "helm upgrade -i", Err: errors.New("exit status 1")}
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{
+			name: "Error of no class",
+			err:  errors.New("failed"),
+			want: ExitError,
+		},
+		{
+			name: "Command failure",
+			err:  commandError,
+			want: ExitCommand,
+		},
+		{
+			name: "Wrapped command failure",
+			err:  fmt.Errorf("Process failed %w", commandError),
+			want: ExitCommand,
+		},
+		{
+			name: "Validation",
+			err:  ValidationErrorf("Namespace is required"),
+			want: ExitValidation,
+		},
+		{
+			name: "Not found",
+			err:  NotFoundErrorf("license not found"),
+			want: ExitNotFound,
+		},
+		{
+			name: "Timeout waiting for a failing command",
+			err:  TimeoutErrorf("Unable to fetch License\n%w", commandError),
+			want: ExitTimeout,
+		},
+		{
+			name: "Interrupted",
+			err:  fmt.Errorf("kind stopped: %w", context.Canceled),
+			want: ExitInterrupted,
+		},
+		{
+			name: "Past the deadline of the run",
+			err:  fmt.Errorf("Process failed %w", fmt.Errorf("helm stopped: %w", context.DeadlineExceeded)),
+			want: ExitTimeout,
+		},
+		{
+			name: "Error choosing its exit code",
+			err:  fmt.Errorf("Installing KubeSlice Worker failed: %w", sameCode(ExitTimeout)),
+			want: ExitTimeout,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc // Capture range variable for parallel execution
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if got := ExitCode(tc.err); got != tc.want {
+				t.Errorf("ExitCode(%v) = %d, want %d", tc.err, got, tc.want)
+			}
+		})
+	}
+}
@@ -2,13 +2,20 @@ package util
 
 import (
 	"bytes"
+	"context"
+	"fmt"
 	"io"
 	"os"
-	"os/exec"
+	"strings"
+	"time"
 )
 
 var ExecutablePaths map[string]string
 
+// DryRun prints the commands instead of running them. Commands reading from the
+// clusters are not run either, so that a plan can be made without cluster access.
+var DryRun bool
+
 var ExecutableVerifyCommands = map[string][]string{
 	"kind":    {"version"},
 	"kubectl": {"version", "--client=true"},
@@ -16,34 +23,101 @@ var ExecutableVerifyCommands = map[string][]string{
 	"helm":    {"version"},
 }
 
-func RunCommand(cli string, arg ...string) error {
+func RunCommand(ctx context.Context, cli string, arg ...string) error {
 	var outB, errB bytes.Buffer
-	err := RunCommandCustomIO(cli, &outB, &errB, false, arg...)
+	err := RunCommandCustomIO(ctx, cli, &outB, &errB, false, arg...)
 	if err != nil {
 		Printf("%s Failed to run command\nOutput: %s\nError: %s %v", Cross, outB.String(), errB.String(), err)
 	}
 	return err
 }
 
-func RunCommandWithoutPrint(cli string, arg ...string) error {
+func RunCommandWithoutPrint(ctx context.Context, cli string, arg ...string) error {
 	var outB, errB bytes.Buffer
-	err := RunCommandCustomIO(cli, &outB, &errB, true, arg...)
+	err := RunCommandCustomIO(ctx, cli, &outB, &errB, true, arg...)
 	// if err != nil {
 	// 	Printf("%s Failed to run command\nOutput: %s\nError: %s %v", Cross, outB.String(), errB.String(), err)
 	// }
 	return err
 }
 
-func RunCommandOnStdIO(cli string, arg ...string) error {
-	return RunCommandCustomIO(cli, os.Stdout, os.Stderr, false, arg...)
+// CommandOutput is where output meant for the user, rather than progress,
+// goes.
+func CommandOutput() io.Writer {
+	return commandOutput
+}
+
+func RunCommandOnStdIO(ctx context.Context, cli string, arg ...string) error {
+	return RunCommandCustomIO(ctx, cli, commandOutput, os.Stderr, false, arg...)
+}
+
+// RunCommandCustomIO runs cli, it can be called from parallel goroutines as
+// long as they do not share the writers. A failing command returns a
+// CommandError. The command is killed when ctx is done, and none is started
+// after.
+func RunCommandCustomIO(ctx context.Context, cli string, stdout, stderr io.Writer, suppressPrint bool, arg ...string) error {
+	var o *Output
+	return o.RunCommandCustomIO(ctx, cli, stdout, stderr, suppressPrint, arg...)
+}
+
+// RunCommand runs cli for the cluster of the output, printing the output of
+// the command if it fails.
+func (o *Output) RunCommand(ctx context.Context, cli string, arg ...string) error {
+	var outB, errB bytes.Buffer
+	err := o.RunCommandCustomIO(ctx, cli, &outB, &errB, false, arg...)
+	if err != nil {
+		o.Printf("%s Failed to run command\nOutput: %s\nError: %s %v", Cross, outB.String(), errB.String(), err)
+	}
+	return err
+}
+
+// RunCommandOnStdIO runs cli for the cluster of the output, streaming the
+// output of the command with the prefix of the cluster.
+func (o *Output) RunCommandOnStdIO(ctx context.Context, cli string, arg ...string) error {
+	if o == nil {
+		return RunCommandOnStdIO(ctx, cli, arg...)
+	}
+	w := &prefixWriter{output: o}
+	defer w.Flush()
+	return o.RunCommandCustomIO(ctx, cli, w, w, false, arg...)
+}
+
+// RunCommandWithInput runs cli for the cluster of the output like RunCommand,
+// writing input to its stdin. Secrets passed this way are neither printed nor
+// visible in the process list.
+func (o *Output) RunCommandWithInput(ctx context.Context, cli string, input string, arg ...string) error {
+	var outB, errB bytes.Buffer
+	err := o.runCommand(ctx, cli, strings.NewReader(input), &outB, &errB, false, arg...)
+	if err != nil {
+		o.Printf("%s Failed to run command\nOutput: %s\nError: %s %v", Cross, outB.String(), errB.String(), err)
+	}
+	return err
 }
 
-func RunCommandCustomIO(cli string, stdout, stderr io.Writer, suppressPrint bool, arg ...string) error {
-	cmd := exec.Command(ExecutablePaths[cli], arg...)
+func (o *Output) RunCommandCustomIO(ctx context.Context, cli string, stdout, stderr io.Writer, suppressPrint bool, arg ...string) error {
+	return o.runCommand(ctx, cli, nil, stdout, stderr, suppressPrint, arg...)
+}
+
+func (o *Output) runCommand(ctx context.Context, cli string, stdin io.Reader, stdout, stderr io.Writer, suppressPrint bool, arg ...string) error {
+	command := commandLine(ExecutablePaths[cli], arg)
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%s not run: %w", cli, err)
+	}
+	if DryRun {
+		o.Printf("%s Would run: %s", Run, command)
+		commandRan(ctx, o.clusterName(), command, 0, nil)
+		return nil
+	}
 	if !suppressPrint {
-		Printf("%s Running command: %s", Run, cmd.String())
+		o.Printf("%s Running command: %s", Run, command)
+	}
+	start := time.Now()
+	err := executor.Run(ctx, ExecutablePaths[cli], arg, stdin, stdout, stderr)
+	if err != nil && ctx.Err() != nil {
+		err = fmt.Errorf("%s stopped: %w", cli, ctx.Err())
+	} else if err != nil {
+		err = &CommandError{Cli: cli, Command: command, Err: err}
 	}
-	cmd.Stdout = stdout
-	cmd.Stderr = stderr
-	return cmd.Run()
+	commandRan(ctx, o.clusterName(), command, time.Since(start), err)
+	return err
 }
@@ -0,0 +1,119 @@
+package util
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// not parallel, as it sets DryRun and ExecutablePaths
+func TestDryRunPrintsCommands(t *testing.T) {
+	DryRun, ExecutablePaths = true, map[string]string{"touch": "touch"}
+	defer func() { DryRun, ExecutablePaths = false, nil }()
+
+	var b, outB, errB bytes.Buffer
+	o := &Output{prefix: "[ks-w-1] ", w: &b}
+	file := filepath.Join(t.TempDir(), "created")
+	if err := o.RunCommandCustomIO(context.Background(), "touch", &outB, &errB, true, file); err != nil {
+		t.Fatalf("RunCommandCustomIO returned %v", err)
+	}
+
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Errorf("the command was run in dry-run mode")
+	}
+	if got := b.String(); !strings.HasPrefix(got, "[ks-w-1] "+Run+" Would run: ") || !strings.HasSuffix(got, "touch "+file+"\n") {
+		t.Errorf("Output printed %q, want the command line", got)
+	}
+}
+
+// not parallel, as it sets ExecutablePaths
+func TestCommandStoppedByContext(t *testing.T) {
+	ExecutablePaths = map[string]string{"sleep": "sleep"}
+	defer func() { ExecutablePaths = nil }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	var outB, errB bytes.Buffer
+	start := time.Now()
+	err := RunCommandCustomIO(ctx, "sleep", &outB, &errB, true, "10")
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("the command ran for %s after its context was done", elapsed)
+	}
+	if got := ExitCode(err); got != ExitTimeout {
+		t.Errorf("RunCommandCustomIO returned %v, exit code %d, want %d", err, got, ExitTimeout)
+	}
+}
+
+// not parallel, as it sets the executor and ExecutablePaths
+func TestFakeExecutor(t *testing.T) {
+	ExecutablePaths = map[string]string{"kubectl": "/usr/bin/kubectl"}
+	defer func() { ExecutablePaths = nil }()
+	fake := &FakeExecutor{}
+	fake.Respond("get nodes", FakeResponse{Stdout: "node-1"}).
+		Respond("get pods", FakeResponse{Stderr: "Error from server (Forbidden)", Err: errors.New("exit status 1")})
+	defer SetExecutor(SetExecutor(fake))
+
+	var outB, errB bytes.Buffer
+	if err := RunCommandCustomIO(context.Background(), "kubectl", &outB, &errB, true, "get", "nodes"); err != nil {
+		t.Fatalf("RunCommandCustomIO returned %v", err)
+	}
+	if got := outB.String(); got != "node-1" {
+		t.Errorf("RunCommandCustomIO printed %q, want the scripted output", got)
+	}
+	err := RunCommandCustomIO(context.Background(), "kubectl", &outB, &errB, true, "get", "pods")
+	if got := ExitCode(err); got != ExitCommand {
+		t.Errorf("RunCommandCustomIO returned %v, exit code %d, want %d", err, got, ExitCommand)
+	}
+	if err := RunCommandCustomIO(context.Background(), "kubectl", &outB, &errB, true, "apply", "-f", "slice.yaml"); err != nil {
+		t.Errorf("RunCommandCustomIO returned %v for a command with no scripted response", err)
+	}
+
+	want := []string{"kubectl get nodes", "kubectl get pods", "kubectl apply -f slice.yaml"}
+	if got := fake.Commands(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("FakeExecutor recorded %q, want %q", got, want)
+	}
+}
+
+// not parallel, as it sets the executor, ExecutablePaths and where the
+// progress goes
+func TestCommandCredentialsMasked(t *testing.T) {
+	ExecutablePaths = map[string]string{"helm": "helm"}
+	defer func() { ExecutablePaths = nil }()
+	fake := &FakeExecutor{}
+	fake.Respond("repo add", FakeResponse{Err: errors.New("exit status 1")})
+	defer SetExecutor(SetExecutor(fake))
+	var progress bytes.Buffer
+	setProgress(&progress)
+	defer setProgress(nil)
+
+	var b, outB, errB bytes.Buffer
+	o := &Output{w: &b}
+	err := o.RunCommandCustomIO(context.Background(), "helm", &outB, &errB, false,
+		"repo", "add", "kubeslice", "https://charts", "--username", "robot", "--password", "s3cret", "--token=t0ken")
+	var commandError *CommandError
+	if !errors.As(err, &commandError) {
+		t.Fatalf("RunCommandCustomIO returned %v, want a CommandError", err)
+	}
+	want := "helm repo add kubeslice https://charts --username *** --password *** --token=***"
+	if commandError.Command != want {
+		t.Errorf("CommandError.Command = %q, want %q", commandError.Command, want)
+	}
+	if !strings.Contains(progress.String(), `"command":"`+want+`"`) {
+		t.Errorf("the progress reported %s, want the masked command", progress.String())
+	}
+	for name, got := range map[string]string{"output": b.String(), "progress": progress.String()} {
+		for _, secret := range []string{"robot", "s3cret", "t0ken"} {
+			if strings.Contains(got, secret) {
+				t.Errorf("the %s contains the credential %s: %s", name, secret, got)
+			}
+		}
+	}
+	if got := fake.Commands(); len(got) != 1 || !strings.Contains(got[0], "--password s3cret") {
+		t.Errorf("FakeExecutor ran %q, want the command with the credentials", got)
+	}
+}
@@ -0,0 +1,84 @@
+package util
+
+import (
+	"context"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// Executor runs the helm, kubectl, kind and docker commands of the CLI.
+type Executor interface {
+	// LookPath finds the executable file, as exec.LookPath does.
+	LookPath(file string) (string, error)
+	// Run runs the executable at path with args, reading its input from stdin,
+	// if not nil, and writing its output to stdout and stderr. It is killed
+	// when ctx is done.
+	Run(ctx context.Context, path string, args []string, stdin io.Reader, stdout, stderr io.Writer) error
+}
+
+// executor runs every command, see SetExecutor.
+var executor Executor = osExecutor{}
+
+// SetExecutor makes every command run through e, and returns the Executor
+// they ran through until then.
+func SetExecutor(e Executor) Executor {
+	previous := executor
+	executor = e
+	return previous
+}
+
+// LookPath finds the executable file through the Executor.
+func LookPath(file string) (string, error) {
+	return executor.LookPath(file)
+}
+
+// RunExecutable runs the executable at path through the Executor, without
+// printing it or its output.
+func RunExecutable(ctx context.Context, path string, args ...string) error {
+	return executor.Run(ctx, path, args, nil, io.Discard, io.Discard)
+}
+
+// commandLine is how a command is printed, reported and embedded in errors,
+// as exec.Cmd prints it but with the credentials masked.
+func commandLine(path string, args []string) string {
+	if found, err := executor.LookPath(path); err == nil {
+		path = found
+	}
+	return strings.Join(append([]string{path}, maskCredentials(args)...), " ")
+}
+
+// credentialFlags are the flags whose values commandLine masks.
+var credentialFlags = []string{"--username", "--password", "--docker-username", "--docker-password", "--token"}
+
+// maskCredentials returns args with the values of the credential flags
+// replaced by ***, passed as --flag value or --flag=value.
+func maskCredentials(args []string) []string {
+	masked := make([]string, len(args))
+	for i, arg := range args {
+		masked[i] = arg
+		for _, flag := range credentialFlags {
+			if strings.HasPrefix(arg, flag+"=") {
+				masked[i] = flag + "=***"
+			} else if i > 0 && args[i-1] == flag {
+				masked[i] = "***"
+			}
+		}
+	}
+	return masked
+}
+
+// osExecutor runs the commands as processes.
+type osExecutor struct{}
+
+func (osExecutor) LookPath(file string) (string, error) {
+	return exec.LookPath(file)
+}
+
+func (osExecutor) Run(ctx context.Context, path string, args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	return cmd.Run()
+}
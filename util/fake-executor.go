@@ -0,0 +1,89 @@
+package util
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// FakeExecutor is an Executor for tests. It records the commands instead of
+// running them, and answers them with the responses scripted for them.
+type FakeExecutor struct {
+	lock      sync.Mutex
+	responses []fakeResponse
+	commands  []string
+	inputs    []string
+}
+
+// FakeResponse is what a command run through a FakeExecutor prints and
+// returns.
+type FakeResponse struct {
+	Stdout string
+	Stderr string
+	Err    error
+}
+
+type fakeResponse struct {
+	match    string
+	response FakeResponse
+}
+
+// Respond scripts the response to the commands containing match. A command
+// is answered by the first response it matches, commands matching none
+// succeed printing nothing.
+func (f *FakeExecutor) Respond(match string, response FakeResponse) *FakeExecutor {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.responses = append(f.responses, fakeResponse{match: match, response: response})
+	return f
+}
+
+// Commands returns the commands run so far, as the name of the executable
+// followed by the arguments. The input of a command is not part of it, see
+// Inputs.
+func (f *FakeExecutor) Commands() []string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return append([]string{}, f.commands...)
+}
+
+// Inputs returns what the commands run so far read from their stdin, "" for
+// the commands which were given none.
+func (f *FakeExecutor) Inputs() []string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return append([]string{}, f.inputs...)
+}
+
+// LookPath finds every file, at the path it is given.
+func (f *FakeExecutor) LookPath(file string) (string, error) {
+	return file, nil
+}
+
+func (f *FakeExecutor) Run(ctx context.Context, path string, args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	command := strings.Join(append([]string{filepath.Base(path)}, args...), " ")
+	var input []byte
+	if stdin != nil {
+		var err error
+		if input, err = io.ReadAll(stdin); err != nil {
+			return err
+		}
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.commands = append(f.commands, command)
+	f.inputs = append(f.inputs, string(input))
+	for _, r := range f.responses {
+		if strings.Contains(command, r.match) {
+			io.WriteString(stdout, r.response.Stdout)
+			io.WriteString(stderr, r.response.Stderr)
+			return r.response.Err
+		}
+	}
+	return nil
+}
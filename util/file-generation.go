@@ -2,27 +2,30 @@ package util
 
 import (
 	"errors"
+	"fmt"
 	"os"
 )
 
-func CreateDirectoryPath(path string) {
+func CreateDirectoryPath(path string) error {
 	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
 		err := os.MkdirAll(path, os.ModePerm)
 		if err != nil {
-			Fatalf("%s Failed to create kubeslice directory to generate configuration files.", Cross)
+			return fmt.Errorf("failed to create directory %s to generate configuration files: %w", path, err)
 		}
 	}
+	return nil
 }
 
-func DumpFile(template, filename string) {
+func DumpFile(template, filename string) error {
 	f, err := os.Create(filename)
 	if err != nil {
-		Fatalf("%s Failed to create %s", Cross, filename)
+		return fmt.Errorf("failed to create %s: %w", filename, err)
 	}
 	defer f.Close()
 	data := []byte(template)
 	_, err2 := f.Write(data)
 	if err2 != nil {
-		Fatalf("%s Failed to write %s", Cross, filename)
+		return fmt.Errorf("failed to write %s: %w", filename, err2)
 	}
+	return nil
 }
\ No newline at end of file
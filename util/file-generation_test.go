@@ -49,7 +49,9 @@ func TestCreateDirectoryPath(t *testing.T) {
 				}
 			}
 
-			CreateDirectoryPath(targetPath)
+			if err := CreateDirectoryPath(targetPath); err != nil {
+				t.Fatalf("CreateDirectoryPath() returned unexpected error %v", err)
+			}
 
 			info, err := os.Stat(targetPath)
 			if err != nil {
@@ -155,7 +157,9 @@ data:
 				}
 			}
 
-			DumpFile(tc.content, targetFile)
+			if err := DumpFile(tc.content, targetFile); err != nil {
+				t.Fatalf("DumpFile() returned unexpected error %v", err)
+			}
 
 			actualContent, err := os.ReadFile(targetFile)
 			if err != nil {
@@ -187,7 +191,9 @@ func TestDumpFile_CreatesFileWithCorrectPermissions(t *testing.T) {
 
 	targetFile := filepath.Join(testDir, "test-permissions.txt")
 
-	DumpFile("test content", targetFile)
+	if err := DumpFile("test content", targetFile); err != nil {
+		t.Fatalf("DumpFile() returned unexpected error %v", err)
+	}
 
 	info, err := os.Stat(targetFile)
 	if err != nil {
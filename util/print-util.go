@@ -1,8 +1,11 @@
 package util
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"os"
+	"sync"
 )
 
 const (
@@ -15,19 +18,98 @@ const (
 	Globe = string(rune(0x1F310))
 )
 
+// outputLock keeps the lines printed from parallel goroutines whole
+var outputLock sync.Mutex
+
+// output is where progress is printed
+var output io.Writer = os.Stdout
+
+// SetOutput sends the progress to w, e.g. to stderr when stdout is kept for
+// machine-readable output.
+func SetOutput(w io.Writer) {
+	outputLock.Lock()
+	defer outputLock.Unlock()
+	output = w
+}
+
 func Printf(format string, a ...interface{}) {
+	outputLock.Lock()
+	defer outputLock.Unlock()
 	if len(a) > 0 {
-		fmt.Printf(format+"\n", a...)
+		fmt.Fprintf(output, format+"\n", a...)
 	} else {
-		fmt.Println(format)
+		fmt.Fprintln(output, format)
 	}
 }
 
-func Fatalf(format string, a ...interface{}) {
+// Output prints the lines of a single cluster prefixed with its name, so that
+// the output of clusters worked on in parallel can be told apart. A nil
+// Output prints without prefix.
+type Output struct {
+	cluster string
+	prefix  string
+	// w is where the lines go, the output of Printf when nil
+	w io.Writer
+}
+
+func ClusterOutput(clusterName string) *Output {
+	return &Output{cluster: clusterName, prefix: "[" + clusterName + "] "}
+}
+
+// clusterName returns the cluster of the output, empty for a nil Output.
+func (o *Output) clusterName() string {
+	if o == nil {
+		return ""
+	}
+	return o.cluster
+}
+
+func (o *Output) Printf(format string, a ...interface{}) {
+	if o == nil {
+		Printf(format, a...)
+		return
+	}
 	if len(a) > 0 {
-		fmt.Printf(format+"\n", a...)
-	} else {
-		fmt.Println(format + "\n")
+		format = fmt.Sprintf(format, a...)
+	}
+	o.writeLines([]byte(format + "\n"))
+}
+
+func (o *Output) writeLines(data []byte) {
+	outputLock.Lock()
+	defer outputLock.Unlock()
+	w := output
+	if o.w != nil {
+		w = o.w
+	}
+	for _, line := range bytes.SplitAfter(data, []byte("\n")) {
+		if len(line) > 0 {
+			io.WriteString(w, o.prefix)
+			w.Write(line)
+		}
+	}
+}
+
+// prefixWriter buffers partial lines, so that the prefix is only printed at
+// the start of a line.
+type prefixWriter struct {
+	output  *Output
+	pending []byte
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.pending = append(w.pending, p...)
+	if end := bytes.LastIndexByte(w.pending, '\n'); end >= 0 {
+		w.output.writeLines(w.pending[:end+1])
+		w.pending = append([]byte{}, w.pending[end+1:]...)
+	}
+	return len(p), nil
+}
+
+// Flush prints the last line if it did not end with a newline.
+func (w *prefixWriter) Flush() {
+	if len(w.pending) > 0 {
+		w.output.writeLines(append(w.pending, '\n'))
+		w.pending = nil
 	}
-	os.Exit(1)
 }
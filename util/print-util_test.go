@@ -0,0 +1,58 @@
+package util
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestOutputPrefixesLines(t *testing.T) {
+	t.Parallel()
+
+	var b bytes.Buffer
+	o := &Output{prefix: "[ks-w-1] ", w: &b}
+	w := &prefixWriter{output: o}
+	o.Printf("%s Installing", Wait)
+	w.Write([]byte("Creating cluster \"ks-w-1\" ...\n • Ensuring node"))
+	w.Write([]byte(" image\n • Preparing"))
+	w.Flush()
+
+	want := "[ks-w-1] " + Wait + " Installing\n" +
+		"[ks-w-1] Creating cluster \"ks-w-1\" ...\n" +
+		"[ks-w-1]  • Ensuring node image\n" +
+		"[ks-w-1]  • Preparing\n"
+	if b.String() != want {
+		t.Errorf("Output printed\n%s\nwant\n%s", b.String(), want)
+	}
+}
+
+func TestOutputKeepsParallelLinesWhole(t *testing.T) {
+	t.Parallel()
+
+	var b bytes.Buffer
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			o := &Output{prefix: fmt.Sprintf("[ks-w-%d] ", i), w: &b}
+			for j := 0; j < 100; j++ {
+				o.Printf("line %d", j)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	if len(lines) != 400 {
+		t.Fatalf("Output printed %d lines, want 400", len(lines))
+	}
+	for _, line := range lines {
+		var worker, n int
+		if _, err := fmt.Sscanf(line, "[ks-w-%d] line %d", &worker, &n); err != nil {
+			t.Fatalf("Output printed a mixed up line %q", line)
+		}
+	}
+}
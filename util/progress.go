@@ -0,0 +1,193 @@
+package util
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// Progress formats of --progress-format
+const (
+	ProgressText = "text"
+	ProgressJSON = "json"
+)
+
+// ProgressEvent is a line of the JSON progress. Steps and the clusters a step
+// works on have a start event, and a finish or fail event once done. Every
+// command run has a command event once it ran.
+type ProgressEvent struct {
+	Time     time.Time      `json:"time"`
+	Event    string         `json:"event"` // start, finish, fail or command
+	Step     string         `json:"step,omitempty"`
+	Cluster  string         `json:"cluster,omitempty"`
+	Command  string         `json:"command,omitempty"`
+	DryRun   bool           `json:"dry_run,omitempty"`  // the command was printed, not run
+	Duration *float64       `json:"duration,omitempty"` // seconds, once done
+	Error    *ProgressError `json:"error,omitempty"`
+}
+
+// ProgressError details the error an event failed with.
+type ProgressError struct {
+	Message  string `json:"message"`
+	Class    string `json:"class"` // validation, command, timeout, not-found, interrupted or error
+	ExitCode int    `json:"exit_code"`
+	Command  string `json:"command,omitempty"` // the command line that failed, if any
+}
+
+// ProgressSummary is the last line of the JSON progress.
+type ProgressSummary struct {
+	Time          time.Time      `json:"time"`
+	Event         string         `json:"event"`  // summary
+	Status        string         `json:"status"` // succeeded or failed
+	ExitCode      int            `json:"exit_code"`
+	Duration      float64        `json:"duration"` // seconds
+	FinishedSteps []string       `json:"finished_steps"`
+	FailedSteps   []string       `json:"failed_steps"`
+	Error         *ProgressError `json:"error,omitempty"`
+}
+
+var (
+	// progress is where the JSON progress goes, nil for text progress
+	progress      io.Writer
+	progressStart = time.Now()
+	finishedSteps = make([]string, 0)
+	failedSteps   = make([]string, 0)
+	// commandOutput is where the output of commands streamed to stdout goes
+	commandOutput io.Writer = os.Stdout
+)
+
+// SetProgressFormat sets how the progress is printed. The JSON progress keeps
+// stdout for one event per line, the text is printed to stderr instead.
+func SetProgressFormat(format string) error {
+	switch format {
+	case ProgressText:
+		return nil
+	case ProgressJSON:
+		SetOutput(os.Stderr)
+		outputLock.Lock()
+		defer outputLock.Unlock()
+		progress, commandOutput = os.Stdout, os.Stderr
+		return nil
+	}
+	return ValidationErrorf("Unsupported progress format %s. Supported values %s, %s", format, ProgressText, ProgressJSON)
+}
+
+// setProgress sends the JSON progress to w, nil for text progress.
+func setProgress(w io.Writer) {
+	outputLock.Lock()
+	defer outputLock.Unlock()
+	progress = w
+	progressStart = time.Now()
+	finishedSteps, failedSteps = make([]string, 0), make([]string, 0)
+}
+
+func emit(v interface{}) {
+	outputLock.Lock()
+	defer outputLock.Unlock()
+	if progress == nil {
+		return
+	}
+	line, err := json.Marshal(v)
+	if err != nil {
+		fmt.Fprintf(output, "%s Failed to print the progress %v\n", Cross, err)
+		return
+	}
+	progress.Write(append(line, '\n'))
+}
+
+func progressError(err error) *ProgressError {
+	if err == nil {
+		return nil
+	}
+	e := &ProgressError{Message: err.Error(), ExitCode: ExitCode(err), Class: ErrorClass(err)}
+	var commandError *CommandError
+	if errors.As(err, &commandError) {
+		e.Command = commandError.Command
+	}
+	return e
+}
+
+func seconds(d time.Duration) *float64 {
+	s := d.Seconds()
+	return &s
+}
+
+type stepKey struct{}
+
+// stepOf returns the step ctx runs in.
+func stepOf(ctx context.Context) string {
+	step, _ := ctx.Value(stepKey{}).(string)
+	return step
+}
+
+// RunStep runs f as step name, its events and the events of the clusters and
+// commands of f carry the name.
+func RunStep(ctx context.Context, name string, f func(ctx context.Context) error) error {
+	emit(ProgressEvent{Time: time.Now(), Event: "start", Step: name})
+	start := time.Now()
+	err := f(context.WithValue(ctx, stepKey{}, name))
+	event := ProgressEvent{Time: time.Now(), Event: "finish", Step: name, Duration: seconds(time.Since(start))}
+	if err != nil {
+		event.Event, event.Error = "fail", progressError(err)
+	}
+	emit(event)
+	outputLock.Lock()
+	defer outputLock.Unlock()
+	if err != nil {
+		failedSteps = append(failedSteps, name)
+	} else {
+		finishedSteps = append(finishedSteps, name)
+	}
+	return err
+}
+
+// RunClusterStep runs the part of the step of ctx working on cluster.
+func RunClusterStep(ctx context.Context, cluster string, f func() error) error {
+	step := stepOf(ctx)
+	emit(ProgressEvent{Time: time.Now(), Event: "start", Step: step, Cluster: cluster})
+	start := time.Now()
+	err := f()
+	event := ProgressEvent{Time: time.Now(), Event: "finish", Step: step, Cluster: cluster, Duration: seconds(time.Since(start))}
+	if err != nil {
+		event.Event, event.Error = "fail", progressError(err)
+	}
+	emit(event)
+	return err
+}
+
+// commandRan reports a command, run or printed in dry-run mode.
+func commandRan(ctx context.Context, cluster, command string, duration time.Duration, err error) {
+	emit(ProgressEvent{
+		Time:     time.Now(),
+		Event:    "command",
+		Step:     stepOf(ctx),
+		Cluster:  cluster,
+		Command:  command,
+		DryRun:   DryRun,
+		Duration: seconds(duration),
+		Error:    progressError(err),
+	})
+}
+
+// PrintProgressSummary prints the summary of the run ending with err.
+func PrintProgressSummary(err error) {
+	outputLock.Lock()
+	summary := ProgressSummary{
+		Time:          time.Now(),
+		Event:         "summary",
+		Status:        "succeeded",
+		Duration:      time.Since(progressStart).Seconds(),
+		FinishedSteps: finishedSteps,
+		FailedSteps:   failedSteps,
+		Error:         progressError(err),
+	}
+	outputLock.Unlock()
+	if err != nil {
+		summary.Status, summary.ExitCode = "failed", ExitCode(err)
+	}
+	emit(summary)
+}
@@ -0,0 +1,82 @@
+package util
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// not parallel, as it sets where the progress of every test goes
+func TestProgressEvents(t *testing.T) {
+	var buf bytes.Buffer
+	setProgress(&buf)
+	defer setProgress(nil)
+
+	ctx := context.Background()
+	if err := RunStep(ctx, "kind", func(ctx context.Context) error {
+		return RunClusterStep(ctx, "ks-ctrl", func() error { return nil })
+	}); err != nil {
+		t.Fatalf("RunStep(kind) = %v, want nil", err)
+	}
+	failure := &CommandError{Cli: "helm", Command: "helm upgrade -i kubeslice-controller", Err: errors.New("exit status 1")}
+	if err := RunStep(ctx, "controller", func(ctx context.Context) error {
+		commandRan(ctx, "ks-ctrl", failure.Command, 0, failure)
+		return failure
+	}); err != failure {
+		t.Fatalf("RunStep(controller) = %v, want %v", err, failure)
+	}
+	PrintProgressSummary(failure)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	want := []struct{ event, step, cluster string }{
+		{"start", "kind", ""},
+		{"start", "kind", "ks-ctrl"},
+		{"finish", "kind", "ks-ctrl"},
+		{"finish", "kind", ""},
+		{"start", "controller", ""},
+		{"command", "controller", "ks-ctrl"},
+		{"fail", "controller", ""},
+	}
+	if len(lines) != len(want)+1 {
+		t.Fatalf("got %d events, want %d:\n%s", len(lines), len(want)+1, buf.String())
+	}
+	for i, w := range want {
+		var event ProgressEvent
+		if err := json.Unmarshal([]byte(lines[i]), &event); err != nil {
+			t.Fatalf("event %d %q is not JSON: %v", i, lines[i], err)
+		}
+		if event.Event != w.event || event.Step != w.step || event.Cluster != w.cluster {
+			t.Errorf("event %d = %s %s %s, want %s %s %s", i, event.Event, event.Step, event.Cluster, w.event, w.step, w.cluster)
+		}
+		if w.event != "start" && event.Duration == nil {
+			t.Errorf("event %d has no duration", i)
+		}
+		if w.event == "fail" && (event.Error == nil || event.Error.Class != "command" || event.Error.Command != failure.Command) {
+			t.Errorf("event %d error = %+v, want the command error", i, event.Error)
+		}
+	}
+
+	var summary ProgressSummary
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &summary); err != nil {
+		t.Fatalf("summary %q is not JSON: %v", lines[len(lines)-1], err)
+	}
+	if summary.Event != "summary" || summary.Status != "failed" || summary.ExitCode != ExitCommand {
+		t.Errorf("summary = %s %s %d, want summary failed %d", summary.Event, summary.Status, summary.ExitCode, ExitCommand)
+	}
+	if len(summary.FinishedSteps) != 1 || summary.FinishedSteps[0] != "kind" ||
+		len(summary.FailedSteps) != 1 || summary.FailedSteps[0] != "controller" {
+		t.Errorf("summary steps = %v finished, %v failed, want [kind] finished, [controller] failed", summary.FinishedSteps, summary.FailedSteps)
+	}
+}
+
+func TestSetProgressFormat(t *testing.T) {
+	t.Parallel()
+
+	err := SetProgressFormat("yaml")
+	if ExitCode(err) != ExitValidation {
+		t.Errorf("SetProgressFormat(yaml) = %v, want a validation error", err)
+	}
+}
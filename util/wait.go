@@ -0,0 +1,49 @@
+package util
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Sleep waits for d, or until ctx is done, returning the error of ctx then.
+func Sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Poll checks condition every interval until it holds, printing to out how
+// long it has been waiting for what. It returns a TimeoutError if the
+// condition does not hold within timeout, the error of condition if it fails
+// and the error of ctx once ctx is done. In dry-run mode it only prints what
+// it would wait for.
+func Poll(ctx context.Context, out *Output, what string, interval, timeout time.Duration, condition func(ctx context.Context) (bool, error)) error {
+	if DryRun {
+		out.Printf("%s Would wait for %s", Wait, what)
+		return nil
+	}
+	out.Printf("%s Waiting for %s...", Wait, what)
+	start := time.Now()
+	for {
+		done, err := condition(ctx)
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+		if time.Since(start)+interval > timeout {
+			return TimeoutErrorf("Timed out waiting for %s after %d seconds", what, int(time.Since(start).Seconds()))
+		}
+		if err := Sleep(ctx, interval); err != nil {
+			return fmt.Errorf("Waiting for %s stopped: %w", what, err)
+		}
+		out.Printf("%s Waiting for %s... %d seconds elapsed", Wait, what, int(time.Since(start).Seconds()))
+	}
+}
@@ -0,0 +1,82 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPoll(t *testing.T) {
+	t.Parallel()
+
+	failure := errors.New("failed to read slices.networking.kubeslice.io")
+	tests := []struct {
+		name      string
+		readyAt   int // the check the condition holds at, 0 for never
+		timeout   time.Duration
+		err       error
+		wantCode  int
+		wantCalls int
+	}{
+		{
+			name:      "Ready at once",
+			readyAt:   1,
+			timeout:   time.Second,
+			wantCalls: 1,
+		},
+		{
+			name:      "Ready after polling",
+			readyAt:   3,
+			timeout:   time.Second,
+			wantCalls: 3,
+		},
+		{
+			name:      "Never ready",
+			timeout:   5 * time.Millisecond,
+			wantCode:  ExitTimeout,
+			wantCalls: 1,
+		},
+		{
+			name:      "Failing condition",
+			err:       failure,
+			timeout:   time.Second,
+			wantCode:  ExitError,
+			wantCalls: 1,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc // Capture range variable for parallel execution
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			calls := 0
+			err := Poll(context.Background(), nil, "slice demo", 10*time.Millisecond, tc.timeout, func(ctx context.Context) (bool, error) {
+				calls++
+				return calls == tc.readyAt, tc.err
+			})
+			if tc.wantCode == 0 && err != nil {
+				t.Errorf("Poll() = %v, want nil", err)
+			}
+			if tc.wantCode != 0 && ExitCode(err) != tc.wantCode {
+				t.Errorf("Poll() = %v, want exit code %d", err, tc.wantCode)
+			}
+			if calls != tc.wantCalls {
+				t.Errorf("Poll() checked %d times, want %d", calls, tc.wantCalls)
+			}
+		})
+	}
+}
+
+func TestPollStoppedByContext(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := Poll(ctx, nil, "slice demo", time.Minute, time.Hour, func(ctx context.Context) (bool, error) {
+		return false, nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Poll() = %v, want %v", err, context.Canceled)
+	}
+}